@@ -0,0 +1,128 @@
+// Package apikeystest provides a conformance test suite for the implementations of services.APIKeyStore.
+// Every implementation should pass it, e.g.
+//
+//	func TestMemoryStore_conformance(t *testing.T) {
+//		apikeystest.Run(t, func() services.APIKeyStore { return apikeys.NewMemoryStore() })
+//	}
+package apikeystest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+// Run checks that the APIKeyStores returned by newStore honour the contract of services.APIKeyStore.
+// newStore is called once per subtest, and must return an empty APIKeyStore.
+func Run(t *testing.T, newStore func() services.APIKeyStore) {
+	t.Run("api keys", func(t *testing.T) {
+		testAPIKeys(t, newStore())
+	})
+	t.Run("plaintext", func(t *testing.T) {
+		testPlaintext(t, newStore())
+	})
+	t.Run("context cancellation", func(t *testing.T) {
+		testContextCancellation(t, newStore())
+	})
+}
+
+func testAPIKeys(t *testing.T, store services.APIKeyStore) {
+	ctx := context.TODO()
+	first, second := newAPIKey("first"), newAPIKey("second")
+	for _, apiKey := range []models.APIKey{first, second} {
+		if err := store.CreateAPIKey(ctx, apiKey); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	if err := store.CreateAPIKey(ctx, first); !errors.Is(err, models.ErrAlreadyExists) {
+		t.Errorf("CreateAPIKey() of an API key twice error = %v, want %v", err, models.ErrAlreadyExists)
+	}
+	if err := store.CreateAPIKey(ctx, newAPIKey(first.Hash)); !errors.Is(err, models.ErrAlreadyExists) {
+		t.Errorf("CreateAPIKey() of an API key with the same hash error = %v, want %v", err, models.ErrAlreadyExists)
+	}
+
+	got, err := store.APIKeyByID(ctx, first.ID)
+	if err != nil || !reflect.DeepEqual(*got, first) {
+		t.Errorf("APIKeyByID() got = %v, %v, want %v", got, err, first)
+	}
+	got.Scopes[0] = models.ScopeAdmin
+	if got, _ = store.APIKeyByID(ctx, first.ID); !reflect.DeepEqual(*got, first) {
+		t.Errorf("the stored API key has been modified through a returned one: %v", got)
+	}
+	if got, err = store.APIKeyByHash(ctx, second.Hash); err != nil || !reflect.DeepEqual(*got, second) {
+		t.Errorf("APIKeyByHash() got = %v, %v, want %v", got, err, second)
+	}
+	if _, err = store.APIKeyByHash(ctx, "unknown"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("APIKeyByHash() of an unknown hash error = %v, want %v", err, models.ErrNotFound)
+	}
+
+	revokedAt := first.CreatedAt.Add(time.Hour)
+	first.RevokedAt = &revokedAt
+	if err = store.UpdateAPIKey(ctx, first); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	apiKeys, err := store.ListAPIKeys(ctx)
+	if err != nil || !reflect.DeepEqual(apiKeys, []models.APIKey{first, second}) {
+		t.Errorf("ListAPIKeys() got = %v, %v, want the API keys in order of creation", apiKeys, err)
+	}
+	if got, err = store.APIKeyByHash(ctx, first.Hash); err != nil || !reflect.DeepEqual(*got, first) {
+		t.Errorf("APIKeyByHash() of a revoked API key got = %v, %v, want %v", got, err, first)
+	}
+
+	unknown := newAPIKey("unknown")
+	wantErr := models.NewAPIKeyNotFoundErr(unknown.ID)
+	if _, err = store.APIKeyByID(ctx, unknown.ID); !errors.Is(err, wantErr) {
+		t.Errorf("APIKeyByID() of an unknown API key error = %v, want %v", err, wantErr)
+	}
+	if err = store.UpdateAPIKey(ctx, unknown); !errors.Is(err, wantErr) {
+		t.Errorf("UpdateAPIKey() of an unknown API key error = %v, want %v", err, wantErr)
+	}
+}
+
+// testPlaintext checks that the plaintext of the API keys is never stored.
+func testPlaintext(t *testing.T, store services.APIKeyStore) {
+	ctx := context.TODO()
+	apiKey := newAPIKey("hash")
+	apiKey.Key = "cm_abcdefgh_plaintext"
+	if err := store.CreateAPIKey(ctx, apiKey); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err := store.APIKeyByID(ctx, apiKey.ID)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Key != "" {
+		t.Errorf("APIKeyByID() got the plaintext %q, want none", got.Key)
+	}
+}
+
+func testContextCancellation(t *testing.T, store services.APIKeyStore) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	if err := store.CreateAPIKey(ctx, newAPIKey("hash")); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateAPIKey() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := store.ListAPIKeys(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListAPIKeys() error = %v, want %v", err, context.Canceled)
+	}
+	if apiKeys, _ := store.ListAPIKeys(context.TODO()); len(apiKeys) != 0 {
+		t.Errorf("ListAPIKeys() got = %v, want none", apiKeys)
+	}
+}
+
+// newAPIKey returns an API key with the given hash which has not been created yet.
+func newAPIKey(hash string) models.APIKey {
+	return models.APIKey{
+		APIKeyMeta: models.APIKeyMeta{Name: "LMS sync", Scopes: []models.Scope{models.ScopeEnrollmentWrite}},
+		ID:         uuid.New(),
+		Prefix:     "cm_abcdefgh",
+		Hash:       hash,
+		CreatedAt:  time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC),
+	}
+}
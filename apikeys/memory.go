@@ -0,0 +1,122 @@
+// Package apikeys provides implementations of services.APIKeyStore persisting the API keys.
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// MemoryStore is a services.APIKeyStore keeping the API keys in memory, e.g. for development and testing.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	apiKeys []models.APIKey
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// CreateAPIKey stores the given API key without its plaintext.
+// It returns a *models.AlreadyExistsError if a key with the same ID or hash exists.
+func (s *MemoryStore) CreateAPIKey(ctx context.Context, apiKey models.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stored := range s.apiKeys {
+		if stored.ID == apiKey.ID || stored.Hash == apiKey.Hash {
+			return models.NewAlreadyExistsErr(models.ResourceAPIKey, apiKey.ID)
+		}
+	}
+	s.apiKeys = append(s.apiKeys, copyAPIKey(apiKey))
+	return nil
+}
+
+// APIKeyByID returns the API key with the given ID, or a *models.NotFoundError if it does not exist.
+func (s *MemoryStore) APIKeyByID(ctx context.Context, apiKeyID uuid.UUID) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.apiKeyIndex(apiKeyID)
+	if i < 0 {
+		return nil, models.NewAPIKeyNotFoundErr(apiKeyID)
+	}
+	apiKey := copyAPIKey(s.apiKeys[i])
+	return &apiKey, nil
+}
+
+// APIKeyByHash returns the API key with the given hash, or an error matching models.ErrNotFound if it does not exist.
+func (s *MemoryStore) APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, stored := range s.apiKeys {
+		if stored.Hash == hash {
+			apiKey := copyAPIKey(stored)
+			return &apiKey, nil
+		}
+	}
+	return nil, fmt.Errorf("no API key has the given hash: %w", models.ErrNotFound)
+}
+
+// ListAPIKeys returns the API keys in the order they have been created.
+func (s *MemoryStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	apiKeys := make([]models.APIKey, len(s.apiKeys))
+	for i, apiKey := range s.apiKeys {
+		apiKeys[i] = copyAPIKey(apiKey)
+	}
+	return apiKeys, nil
+}
+
+// UpdateAPIKey stores the given API key. It returns a *models.NotFoundError if the key does not exist.
+func (s *MemoryStore) UpdateAPIKey(ctx context.Context, apiKey models.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.apiKeyIndex(apiKey.ID)
+	if i < 0 {
+		return models.NewAPIKeyNotFoundErr(apiKey.ID)
+	}
+	s.apiKeys[i] = copyAPIKey(apiKey)
+	return nil
+}
+
+// apiKeyIndex returns the index of the API key with the given ID, or -1. It must be called with s.mu held.
+func (s *MemoryStore) apiKeyIndex(apiKeyID uuid.UUID) int {
+	for i, apiKey := range s.apiKeys {
+		if apiKey.ID == apiKeyID {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyAPIKey returns a copy of the given API key which does not share memory with it.
+// The plaintext key is never stored.
+func copyAPIKey(apiKey models.APIKey) models.APIKey {
+	apiKey.Key = ""
+	apiKey.Scopes = append([]models.Scope(nil), apiKey.Scopes...)
+	if apiKey.RevokedAt != nil {
+		revokedAt := *apiKey.RevokedAt
+		apiKey.RevokedAt = &revokedAt
+	}
+	return apiKey
+}
@@ -0,0 +1,17 @@
+package apikeys_test
+
+import (
+	"testing"
+
+	"github.com/tomasdembelli/course-manager/apikeys"
+	"github.com/tomasdembelli/course-manager/apikeys/apikeystest"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+var _ services.APIKeyStore = (*apikeys.MemoryStore)(nil)
+
+func TestMemoryStore_conformance(t *testing.T) {
+	apikeystest.Run(t, func() services.APIKeyStore {
+		return apikeys.NewMemoryStore()
+	})
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+func TestSinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		newSink func(t *testing.T) services.AuditSink
+	}{
+		{
+			name: "memory",
+			newSink: func(t *testing.T) services.AuditSink {
+				return NewMemorySink()
+			},
+		},
+		{
+			name: "file",
+			newSink: func(t *testing.T) services.AuditSink {
+				sink, err := NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				t.Cleanup(func() { _ = sink.Close() })
+				return sink
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSink(t, tt.newSink(t))
+		})
+	}
+}
+
+func testSink(t *testing.T, sink services.AuditSink) {
+	ctx := context.TODO()
+	courseUUID, studentUUID := uuid.New(), uuid.New()
+	now := time.Date(2022, 3, 1, 10, 30, 0, 123000, time.UTC)
+	course := models.Course{
+		CourseMeta: models.CourseMeta{Uuid: courseUUID, Name: "Golang", TutorUUID: uuid.New()},
+		Students:   map[uuid.UUID]models.Enrollment{},
+		Version:    1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	registered := course
+	registered.Students = map[uuid.UUID]models.Enrollment{studentUUID: {StudentUUID: studentUUID, EnrolledAt: now}}
+	registered.Version = 2
+	events := []models.AuditEvent{
+		{Time: now, Actor: "alice", Action: models.CourseCreated, CourseUUID: courseUUID, After: &course},
+		{Time: now, Action: models.CourseCreated, CourseUUID: uuid.New()},
+		{
+			Time:        now.Add(time.Minute),
+			Actor:       "bob",
+			Action:      models.StudentRegistered,
+			CourseUUID:  courseUUID,
+			StudentUUID: &studentUUID,
+			Before:      &course,
+			After:       &registered,
+		},
+	}
+	for _, event := range events {
+		if err := sink.Record(ctx, event); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	got, err := sink.History(ctx, courseUUID)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := []models.AuditEvent{events[0], events[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("History() got = %v, want %v", got, want)
+	}
+	if got, err = sink.History(ctx, uuid.New()); err != nil || len(got) != 0 {
+		t.Errorf("History() of an unknown course got = %v, %v, want no events", got, err)
+	}
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err = sink.Record(cancelledCtx, events[0]); !errors.Is(err, context.Canceled) {
+		t.Errorf("Record() error = %v, want %v", err, context.Canceled)
+	}
+	if got, err = sink.History(ctx, courseUUID); err != nil || len(got) != 2 {
+		t.Errorf("History() after a cancelled Record() got = %v, %v, want 2 events", got, err)
+	}
+}
+
+func TestFileSink_reopen(t *testing.T) {
+	ctx := context.TODO()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	event := models.AuditEvent{Time: time.Now().UTC(), Action: models.CourseDeleted, CourseUUID: uuid.New()}
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if err = sink.Record(ctx, event); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if err = sink.Close(); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	defer sink.Close()
+	got, err := sink.History(ctx, event.CourseUUID)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := []models.AuditEvent{event, event}; !reflect.DeepEqual(got, want) {
+		t.Errorf("History() got = %v, want the events appended before reopening %v", got, want)
+	}
+}
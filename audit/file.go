@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// FileSink is a services.AuditSink appending the audit events to a JSON lines file, one event per line.
+// Events are never modified or removed from the file, so the services.CourseManager records them
+// once the modifications they tell about have committed. It is safe for concurrent use.
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewFileSink returns a FileSink appending to the file at the given path, which is created if it does not exist.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the audit log: %w", err)
+	}
+	return &FileSink{path: path, file: file}, nil
+}
+
+// Record appends the given event to the audit log.
+func (s *FileSink) Record(ctx context.Context, event models.AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("unable to encode the audit event: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write the audit event: %w", err)
+	}
+	return nil
+}
+
+// History returns the events of the given course in the order they have been recorded.
+// It reads the whole audit log.
+func (s *FileSink) History(ctx context.Context, courseUUID uuid.UUID) ([]models.AuditEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the audit log: %w", err)
+	}
+	defer file.Close()
+
+	var events []models.AuditEvent
+	decoder := json.NewDecoder(file)
+	for {
+		var event models.AuditEvent
+		err = decoder.Decode(&event)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the audit log: %w", err)
+		}
+		if event.CourseUUID == courseUUID {
+			events = append(events, event)
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close closes the audit log file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,49 @@
+// Package audit provides implementations of services.AuditSink keeping the audit log of the courses.
+package audit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// MemorySink is a services.AuditSink keeping the audit events in memory, e.g. for development and testing.
+// It is safe for concurrent use.
+type MemorySink struct {
+	mu     sync.RWMutex
+	events []models.AuditEvent
+}
+
+// NewMemorySink returns an empty MemorySink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// Record appends the given event to the audit log.
+func (s *MemorySink) Record(ctx context.Context, event models.AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// History returns the events of the given course in the order they have been recorded.
+func (s *MemorySink) History(ctx context.Context, courseUUID uuid.UUID) ([]models.AuditEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var events []models.AuditEvent
+	for _, event := range s.events {
+		if event.CourseUUID == courseUUID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -1,28 +1,232 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/apikeys"
+	"github.com/tomasdembelli/course-manager/audit"
+	"github.com/tomasdembelli/course-manager/config"
+	db_memory "github.com/tomasdembelli/course-manager/db-memory"
 	db_mock "github.com/tomasdembelli/course-manager/db-mock"
+	db_sql "github.com/tomasdembelli/course-manager/db-sql"
 	server "github.com/tomasdembelli/course-manager/echo-server"
+	"github.com/tomasdembelli/course-manager/publishers"
+	"github.com/tomasdembelli/course-manager/ratelimit"
 	"github.com/tomasdembelli/course-manager/services"
-	"log"
-	"os"
+	"github.com/tomasdembelli/course-manager/webhooks"
 )
 
-const devEnvironment = "development"
-
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
 	var repo services.Repo
-	if os.Getenv("ENVIRONMENT") == devEnvironment {
-		repo = db_mock.NewMockRepo(&db_mock.Config{
-			CourseByUUID: db_mock.CourseByUUID,
-		})
+	var webhookStore services.WebhookStore
+	var apiKeyStore services.APIKeyStore
+	if cfg.Repo.Backend == config.BackendMemory {
+		repo = db_memory.NewRepo(db_mock.CourseByUUID, db_mock.TutorByUUID, db_mock.StudentByUUID)
+		webhookStore = webhooks.NewMemoryStore()
+		apiKeyStore = apikeys.NewMemoryStore()
+	} else {
+		sqlRepo, err := openSQLRepo(context.Background(), cfg.Repo)
+		if err != nil {
+			log.Fatalf("unable to open the database %v", err)
+		}
+		defer sqlRepo.Close()
+		if err = checkMigrations(context.Background(), sqlRepo); err != nil {
+			log.Fatal(err)
+		}
+		repo = sqlRepo
+		webhookStore = sqlRepo
+		apiKeyStore = sqlRepo
+	}
+	auditSink, err := openAuditSink(cfg.Audit.LogPath, repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if closer, ok := auditSink.(io.Closer); ok {
+		defer closer.Close()
+	}
+	apiKeyManager, err := services.NewAPIKeyManager(apiKeyStore, log.Default())
+	if err != nil {
+		log.Fatalf("unable to start API key manager service %v", err)
+	}
+	authenticator, err := newAuthenticator(cfg, &apiKeyManager)
+	if err != nil {
+		log.Fatal(err)
+	}
+	courseOpts := []services.Option{services.WithPolicy(cfg.Enrollment.Policy()), services.WithAuditSink(auditSink)}
+	if authenticator != nil {
+		courseOpts = append(courseOpts, services.WithAuthorizer(services.RoleBasedAuthorizer{}))
 	}
-	courseManager, err := services.NewCourseManager(repo, log.Default())
+	courseManager, err := services.NewCourseManager(repo, log.Default(), courseOpts...)
 	if err != nil {
 		log.Fatalf("unable to start course manager service %v", err)
 	}
+	tutorManager, err := services.NewTutorManager(repo, log.Default())
+	if err != nil {
+		log.Fatalf("unable to start tutor manager service %v", err)
+	}
+	studentManager, err := services.NewStudentManager(repo, log.Default())
+	if err != nil {
+		log.Fatalf("unable to start student manager service %v", err)
+	}
+	// Tenants may only subscribe webhooks on the local host or private networks in development.
+	webhookClient := publishers.NewPublicClient(cfg.Timeouts.Webhook)
+	var webhookOpts []services.WebhookOption
+	if cfg.Environment == config.DevEnvironment {
+		webhookClient = &http.Client{Timeout: cfg.Timeouts.Webhook}
+		webhookOpts = append(webhookOpts, services.WithPrivateWebhookTargets())
+	}
+	webhookManager, err := services.NewWebhookManager(webhookStore, func(url, secret string) services.Publisher {
+		return publishers.NewSignedWebhook(url, secret, webhookClient)
+	}, log.Default(), webhookOpts...)
+	if err != nil {
+		log.Fatalf("unable to start webhook manager service %v", err)
+	}
+	relay, err := services.NewRelay(repo, map[string]services.Publisher{
+		"webhooks": webhookManager,
+		"events":   newPublisher(cfg.Events.WebhookURL, cfg.Timeouts.Webhook),
+	}, log.Default(), 0)
+	if err != nil {
+		log.Fatalf("unable to start the event relay %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Run(ctx)
+	go webhookManager.Run(ctx, 0)
+	rateLimiter, err := newRateLimiter(cfg.RateLimits)
+	if err != nil {
+		log.Fatalf("unable to start the rate limiter %v", err)
+	}
 	server.StartServer(&server.Config{
-		Port:             8000,
-		CourseManagerSvc: &courseManager,
+		Port:              cfg.Port,
+		CourseManagerSvc:  &courseManager,
+		TutorManagerSvc:   &tutorManager,
+		StudentManagerSvc: &studentManager,
+		WebhookManagerSvc: &webhookManager,
+		APIKeyManagerSvc:  &apiKeyManager,
+		Authenticator:     authenticator,
+		RateLimiter:       rateLimiter,
+		TrustedProxies:    cfg.TrustedProxyNetworks(),
+		LogLevel:          cfg.LogLevel,
+		CORSAllowOrigins:  cfg.CORS.AllowOrigins,
+		ReadTimeout:       cfg.Timeouts.Read,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
 	})
 }
+
+// openSQLRepo opens the SQL repo of the given configuration.
+// For SQLite, the DSN is the path of the database file.
+func openSQLRepo(ctx context.Context, repo config.Repo) (*db_sql.Repo, error) {
+	switch repo.Backend {
+	case config.BackendPostgres:
+		return db_sql.OpenPostgres(ctx, repo.DSN)
+	case config.BackendSQLite:
+		return db_sql.OpenSQLite(ctx, repo.DSN)
+	default:
+		return nil, fmt.Errorf("the %v backend is not an SQL database", repo.Backend)
+	}
+}
+
+// openAuditSink returns the audit.FileSink appending to the file at the given path. If the path is empty,
+// it returns the given repo, which records the audit events with the modifications, if it is a services.AuditSink,
+// and an audit.MemorySink otherwise.
+func openAuditSink(path string, repo services.Repo) (services.AuditSink, error) {
+	if path != "" {
+		return audit.NewFileSink(path)
+	}
+	if sink, ok := repo.(services.AuditSink); ok {
+		return sink, nil
+	}
+	return audit.NewMemorySink(), nil
+}
+
+// newPublisher returns the publishers.Webhook posting the domain events to the given URL with the given timeout,
+// or a publishers.Logger if the URL is empty.
+func newPublisher(webhookURL string, timeout time.Duration) services.Publisher {
+	if webhookURL == "" {
+		return publishers.NewLogger(log.Default())
+	}
+	return publishers.NewWebhook(webhookURL, &http.Client{Timeout: timeout})
+}
+
+// newRateLimiter returns the server.RateLimiter of the given rate limits, keeping its buckets in memory.
+// The route groups and IP addresses whose limit is off are not limited.
+func newRateLimiter(limits config.RateLimits) (*server.RateLimiter, error) {
+	groups := make(map[server.RouteGroup]services.RateLimit)
+	for group, limit := range map[server.RouteGroup]config.RateLimit{
+		server.RouteGroupRead:       limits.Read,
+		server.RouteGroupEnrollment: limits.Enrollment,
+		server.RouteGroupWrite:      limits.Write,
+	} {
+		if !limit.Off {
+			groups[group] = limit.RateLimit
+		}
+	}
+	var opts []server.RateLimiterOption
+	if !limits.IP.Off {
+		opts = append(opts, server.WithIPRateLimit(limits.IP.RateLimit))
+	}
+	return server.NewRateLimiter(ratelimit.NewMemoryStore(), groups, opts...)
+}
+
+// newAuthenticator returns the server.Authenticator of the auth settings of the given Config,
+// which also authenticates the API keys of the given manager.
+// Without a secret nor a JWKS, requests are only left unauthenticated in development.
+func newAuthenticator(cfg config.Config, apiKeys *services.APIKeyManager) (*server.Authenticator, error) {
+	if !cfg.Auth.Enabled() {
+		if cfg.Environment == config.DevEnvironment {
+			log.Print("auth.hs256Secret and auth.jwksPath are not set, requests are not authenticated")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("auth.hs256Secret (AUTH_HS256_SECRET) or auth.jwksPath (AUTH_JWKS_PATH) must be set to authenticate the requests")
+	}
+	authenticator, err := server.NewAuthenticator(server.AuthConfig{
+		HS256Secret: cfg.Auth.HS256Secret,
+		JWKSPath:    cfg.Auth.JWKSPath,
+		Issuer:      cfg.Auth.Issuer,
+		Audience:    cfg.Auth.Audience,
+		RolesClaim:  cfg.Auth.RolesClaim,
+		APIKeys:     apiKeys,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure the authentication: %w", err)
+	}
+	return authenticator, nil
+}
+
+// checkMigrations makes sure the schema of the given repo is up-to-date before serving requests.
+// SQLite databases are embedded in the binary, so their pending migrations are applied on start,
+// whereas any other database must be migrated beforehand with the migrate subcommand.
+func checkMigrations(ctx context.Context, repo *db_sql.Repo) error {
+	if repo.Dialect().Name == db_sql.SQLite.Name {
+		if _, err := repo.Migrator().Up(ctx); err != nil {
+			return fmt.Errorf("unable to migrate the database: %w", err)
+		}
+		return nil
+	}
+	pending, err := repo.Migrator().Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to check the database migrations: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("the database has %d pending migrations, run \"course-manager migrate up\" first", len(pending))
+	}
+	return nil
+}
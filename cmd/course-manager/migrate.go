@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/config"
+	"github.com/tomasdembelli/course-manager/migrations"
+)
+
+const migrateUsage = "usage: course-manager migrate [flags] up|down|status"
+
+// runMigrate runs the migrate subcommand with the given arguments against the configured database.
+// The command may be preceded by the flags of the configuration, e.g. -config or -repo-dsn.
+//   - up applies every pending migration.
+//   - down reverts the latest applied migration.
+//   - status lists the migrations and whether they have been applied.
+func runMigrate(ctx context.Context, args []string, out io.Writer) error {
+	cfg, args, err := config.LoadCommand(args, os.Getenv)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+	repo, err := openSQLRepo(ctx, cfg.Repo)
+	if err != nil {
+		return fmt.Errorf("unable to open the database: %w", err)
+	}
+	defer repo.Close()
+	migrator := repo.Migrator()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d_%v\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Fprintln(out, "no applied migrations")
+			return nil
+		}
+		fmt.Fprintf(out, "reverted %04d_%v\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(out, statuses)
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
+
+func printStatus(out io.Writer, statuses []migrations.Status) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%v\t%v\n", status.Version, status.Name, appliedAt)
+	}
+	_ = w.Flush()
+}
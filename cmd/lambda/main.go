@@ -8,7 +8,9 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	db_memory "github.com/tomasdembelli/course-manager/db-memory"
 	db_mock "github.com/tomasdembelli/course-manager/db-mock"
+	"github.com/tomasdembelli/course-manager/models"
 	"github.com/tomasdembelli/course-manager/services"
 )
 
@@ -20,12 +22,12 @@ func handler(ctx context.Context, req events.APIGatewayProxyRequest) (*events.AP
 	res := &events.APIGatewayProxyResponse{}
 	switch req.Path {
 	case "/v1/listCourses":
-		courses, err := courseManager.List(ctx)
+		page, err := courseManager.List(ctx, models.CourseQuery{})
 		if err != nil {
 			return res, err
 		}
 
-		body, err := json.Marshal(courses)
+		body, err := json.Marshal(page)
 		if err != nil {
 			return res, err
 		}
@@ -41,9 +43,7 @@ func handler(ctx context.Context, req events.APIGatewayProxyRequest) (*events.AP
 
 func main() {
 	//TODO: fix this
-	repo := db_mock.NewMockRepo(&db_mock.Config{
-		CourseByUUID: db_mock.CourseByUUID,
-	})
+	repo := db_memory.NewRepo(db_mock.CourseByUUID, db_mock.TutorByUUID, db_mock.StudentByUUID)
 
 	var err error
 	courseManager, err = services.NewCourseManager(repo, log.Default())
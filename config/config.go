@@ -0,0 +1,526 @@
+// Package config loads the configuration of the course-manager service from a YAML file, the environment variables
+// and the command line flags. A setting of a flag overrides the one of an environment variable,
+// which overrides the one of the file, which overrides the default.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/services"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DevEnvironment is the environment in which the courses are kept in memory by default, and seeded with mock data.
+	DevEnvironment = "development"
+	// DefaultSQLitePath is the database file of the BackendSQLite repo, unless a DSN is configured.
+	DefaultSQLitePath = "course-manager.db"
+
+	// BackendMemory keeps the courses in memory, seeded with mock data.
+	BackendMemory = "memory"
+	// BackendPostgres persists the courses into the PostgreSQL database of the DSN.
+	BackendPostgres = "postgres"
+	// BackendSQLite persists the courses into the SQLite database file of the DSN.
+	BackendSQLite = "sqlite"
+
+	// DefaultWebhookTimeout is the default timeout of the deliveries of the events to the webhooks.
+	DefaultWebhookTimeout = 10 * time.Second
+	// DefaultRolesClaim is the default claim of the roles of the caller in the bearer tokens.
+	DefaultRolesClaim = "roles"
+
+	// envConfigFile is the environment variable of the path of the configuration file, unless the -config flag is set.
+	envConfigFile = "CONFIG_FILE"
+)
+
+// DefaultRateLimits are the default rate limits. The enrollments are limited the most, as scripts race to register
+// to the popular courses when they open, and the IP addresses the least, as several clients may share an address.
+var DefaultRateLimits = RateLimits{
+	Read:       RateLimit{RateLimit: services.RateLimit{Requests: 600, Period: time.Minute}},
+	Enrollment: RateLimit{RateLimit: services.RateLimit{Requests: 30, Period: time.Minute}},
+	Write:      RateLimit{RateLimit: services.RateLimit{Requests: 120, Period: time.Minute}},
+	IP:         RateLimit{RateLimit: services.RateLimit{Requests: 1200, Period: time.Minute}},
+}
+
+// Backends lists the backends of the repo.
+var Backends = []string{BackendMemory, BackendPostgres, BackendSQLite}
+
+// LogLevels lists the log levels, from the most to the least verbose.
+var LogLevels = []string{"debug", "info", "warn", "error", "off"}
+
+// Config is the configuration of the course-manager service.
+type Config struct {
+	// Environment is e.g. development or production.
+	Environment string `yaml:"environment"`
+	// Port is the port the API is served at.
+	Port int `yaml:"port"`
+	// LogLevel is one of LogLevels. The requests are logged at the info level.
+	LogLevel   string     `yaml:"logLevel"`
+	Repo       Repo       `yaml:"repo"`
+	Enrollment Enrollment `yaml:"enrollment"`
+	CORS       CORS       `yaml:"cors"`
+	Timeouts   Timeouts   `yaml:"timeouts"`
+	// TrustedProxies are the IP addresses or CIDR networks of the reverse proxies whose X-Forwarded-For header
+	// tells the IP address of the clients. Without any, the IP address of the clients is the one of the connection.
+	TrustedProxies []string   `yaml:"trustedProxies"`
+	Auth           Auth       `yaml:"auth"`
+	RateLimits     RateLimits `yaml:"rateLimits"`
+	Audit          Audit      `yaml:"audit"`
+	Events         Events     `yaml:"events"`
+}
+
+// Repo configures the repo of the courses, tutors and students.
+type Repo struct {
+	// Backend is one of Backends. It defaults to BackendMemory in the DevEnvironment, and to BackendPostgres otherwise.
+	Backend string `yaml:"backend"`
+	// DSN is the PostgreSQL connection string, or the path of the SQLite database file.
+	DSN string `yaml:"dsn"`
+}
+
+// Enrollment configures the enrollment limits, as a services.Policy.
+type Enrollment struct {
+	TutorMaxCourse   int `yaml:"tutorMaxCourse"`
+	StudentMaxCourse int `yaml:"studentMaxCourse"`
+	CourseMaxStudent int `yaml:"courseMaxStudent"`
+}
+
+// CORS configures the Cross-Origin Resource Sharing of the API.
+type CORS struct {
+	// AllowOrigins are the origins allowed to call the API from a browser, e.g. https://example.com, or * for any.
+	AllowOrigins []string `yaml:"allowOrigins"`
+}
+
+// Timeouts configures the timeouts of the HTTP server and clients. A zero timeout means no timeout.
+type Timeouts struct {
+	// Read is the maximum duration for reading a request, including its body.
+	Read time.Duration `yaml:"read"`
+	// Write is the maximum duration before timing out the writes of a response.
+	Write time.Duration `yaml:"write"`
+	// Idle is the maximum duration to wait for the next request of a keep-alive connection.
+	Idle time.Duration `yaml:"idle"`
+	// Webhook is the timeout of the deliveries of the events to the webhooks.
+	Webhook time.Duration `yaml:"webhook"`
+}
+
+// Auth configures the authentication of the requests with JWT bearer tokens.
+// Requests are only left unauthenticated in the DevEnvironment, without a secret nor a JWKS.
+type Auth struct {
+	// HS256Secret verifies the tokens signed with HS256, unless it is empty. It must have at least MinHS256SecretLength bytes.
+	HS256Secret string `yaml:"hs256Secret"`
+	// JWKSPath is the path of the JSON Web Key Set file verifying the tokens signed with RS256, unless it is empty.
+	JWKSPath string `yaml:"jwksPath"`
+	// Issuer is required in the "iss" claim of the tokens, unless it is empty.
+	Issuer string `yaml:"issuer"`
+	// Audience is required in the "aud" claim of the tokens, unless it is empty.
+	Audience string `yaml:"audience"`
+	// RolesClaim is the claim of the roles of the caller.
+	RolesClaim string `yaml:"rolesClaim"`
+}
+
+// RateLimits configures the rate limits of the requests of every client to the read, enrollment and write routes,
+// and of every IP address to any route.
+type RateLimits struct {
+	Read       RateLimit `yaml:"read"`
+	Enrollment RateLimit `yaml:"enrollment"`
+	Write      RateLimit `yaml:"write"`
+	IP         RateLimit `yaml:"ip"`
+}
+
+// RateLimit is a rate limit, written "<requests>/<period>", e.g. 600/1m, or "off" to not limit the requests.
+type RateLimit struct {
+	services.RateLimit
+	// Off turns the rate limit off.
+	Off bool
+}
+
+// Audit configures the audit log of the modifications of the courses.
+type Audit struct {
+	// LogPath is the path of the JSON lines file the audit log is appended to. Without it, the audit log is stored
+	// by the repo, or kept in memory.
+	LogPath string `yaml:"logPath"`
+}
+
+// Events configures the publication of the domain events.
+type Events struct {
+	// WebhookURL is the URL the events are posted to as JSON. Without it, the events are logged.
+	WebhookURL string `yaml:"webhookURL"`
+}
+
+// MinHS256SecretLength is the minimum length in bytes of the Auth.HS256Secret, the size of the SHA-256 hash,
+// as required by RFC 7518.
+const MinHS256SecretLength = 32
+
+// Enabled reports whether the requests are authenticated, that is whether a secret or a JWKS is configured.
+func (a Auth) Enabled() bool {
+	return a.HS256Secret != "" || a.JWKSPath != ""
+}
+
+// String returns the "<requests>/<period>" form of the RateLimit, or "off".
+func (l RateLimit) String() string {
+	if l.Off {
+		return "off"
+	}
+	return l.RateLimit.String()
+}
+
+// UnmarshalYAML parses the RateLimit of a YAML scalar, as the environment variables and the flags.
+func (l *RateLimit) UnmarshalYAML(value *yaml.Node) error {
+	return l.set(value.Value)
+}
+
+// set parses the given "<requests>/<period>" or "off" into the RateLimit.
+func (l *RateLimit) set(raw string) error {
+	if raw == "off" {
+		*l = RateLimit{Off: true}
+		return nil
+	}
+	limit, err := services.ParseRateLimit(raw)
+	if err != nil {
+		return err
+	}
+	*l = RateLimit{RateLimit: limit}
+	return nil
+}
+
+// Policy returns the services.Policy of the enrollment limits.
+func (e Enrollment) Policy() services.Policy {
+	return services.Policy{
+		TutorMaxCourse:   e.TutorMaxCourse,
+		StudentMaxCourse: e.StudentMaxCourse,
+		CourseMaxStudent: e.CourseMaxStudent,
+	}
+}
+
+// Default returns the default configuration, which is overridden by the file, the environment and the flags.
+func Default() Config {
+	return Config{
+		Port:     8000,
+		LogLevel: "info",
+		Enrollment: Enrollment{
+			TutorMaxCourse:   services.DefaultPolicy.TutorMaxCourse,
+			StudentMaxCourse: services.DefaultPolicy.StudentMaxCourse,
+			CourseMaxStudent: services.DefaultPolicy.CourseMaxStudent,
+		},
+		CORS: CORS{AllowOrigins: []string{"*"}},
+		Timeouts: Timeouts{
+			Read:    15 * time.Second,
+			Write:   15 * time.Second,
+			Idle:    time.Minute,
+			Webhook: DefaultWebhookTimeout,
+		},
+		Auth:       Auth{RolesClaim: DefaultRolesClaim},
+		RateLimits: DefaultRateLimits,
+	}
+}
+
+// setting is a setting of the Config which can be overridden by an environment variable and a flag.
+type setting struct {
+	env, flag, usage string
+	// field returns a pointer to the field of the setting, a *string, *int, *time.Duration, *RateLimit or *[]string.
+	field func(c *Config) interface{}
+}
+
+// settings lists the settings which can be overridden by the environment variables and the flags.
+var settings = []setting{
+	{env: "ENVIRONMENT", flag: "environment", usage: "environment, e.g. development or production",
+		field: func(c *Config) interface{} { return &c.Environment }},
+	{env: "PORT", flag: "port", usage: "port the API is served at",
+		field: func(c *Config) interface{} { return &c.Port }},
+	{env: "LOG_LEVEL", flag: "log-level", usage: "log level, one of " + strings.Join(LogLevels, ", "),
+		field: func(c *Config) interface{} { return &c.LogLevel }},
+	{env: "DATABASE_DRIVER", flag: "repo-backend", usage: "backend of the repo, one of " + strings.Join(Backends, ", "),
+		field: func(c *Config) interface{} { return &c.Repo.Backend }},
+	{env: "DATABASE_URL", flag: "repo-dsn", usage: "PostgreSQL connection string, or path of the SQLite database file",
+		field: func(c *Config) interface{} { return &c.Repo.DSN }},
+	{env: "TUTOR_MAX_COURSE", flag: "tutor-max-course", usage: "maximum number of courses a tutor can facilitate",
+		field: func(c *Config) interface{} { return &c.Enrollment.TutorMaxCourse }},
+	{env: "STUDENT_MAX_COURSE", flag: "student-max-course", usage: "maximum number of courses a student can register to",
+		field: func(c *Config) interface{} { return &c.Enrollment.StudentMaxCourse }},
+	{env: "COURSE_MAX_STUDENT", flag: "course-max-student", usage: "default maximum number of students of a course",
+		field: func(c *Config) interface{} { return &c.Enrollment.CourseMaxStudent }},
+	{env: "CORS_ALLOW_ORIGINS", flag: "cors-allow-origins", usage: "comma separated origins allowed to call the API, or *",
+		field: func(c *Config) interface{} { return &c.CORS.AllowOrigins }},
+	{env: "READ_TIMEOUT", flag: "read-timeout", usage: "maximum duration for reading a request",
+		field: func(c *Config) interface{} { return &c.Timeouts.Read }},
+	{env: "WRITE_TIMEOUT", flag: "write-timeout", usage: "maximum duration for writing a response",
+		field: func(c *Config) interface{} { return &c.Timeouts.Write }},
+	{env: "IDLE_TIMEOUT", flag: "idle-timeout", usage: "maximum duration of an idle keep-alive connection",
+		field: func(c *Config) interface{} { return &c.Timeouts.Idle }},
+	{env: "WEBHOOK_TIMEOUT", flag: "webhook-timeout", usage: "timeout of the deliveries of the events to the webhooks",
+		field: func(c *Config) interface{} { return &c.Timeouts.Webhook }},
+	{env: "TRUSTED_PROXIES", flag: "trusted-proxies", usage: "comma separated IP addresses or CIDR networks of the trusted reverse proxies",
+		field: func(c *Config) interface{} { return &c.TrustedProxies }},
+	{env: "AUTH_HS256_SECRET", flag: "auth-hs256-secret", usage: "secret verifying the bearer tokens signed with HS256",
+		field: func(c *Config) interface{} { return &c.Auth.HS256Secret }},
+	{env: "AUTH_JWKS_PATH", flag: "auth-jwks-path", usage: "path of the JWKS file verifying the bearer tokens signed with RS256",
+		field: func(c *Config) interface{} { return &c.Auth.JWKSPath }},
+	{env: "AUTH_ISSUER", flag: "auth-issuer", usage: "issuer required in the bearer tokens",
+		field: func(c *Config) interface{} { return &c.Auth.Issuer }},
+	{env: "AUTH_AUDIENCE", flag: "auth-audience", usage: "audience required in the bearer tokens",
+		field: func(c *Config) interface{} { return &c.Auth.Audience }},
+	{env: "AUTH_ROLES_CLAIM", flag: "auth-roles-claim", usage: "claim of the roles of the caller in the bearer tokens",
+		field: func(c *Config) interface{} { return &c.Auth.RolesClaim }},
+	{env: "RATE_LIMIT_READ", flag: "rate-limit-read", usage: "rate limit of the read routes per client, e.g. 600/1m, or off",
+		field: func(c *Config) interface{} { return &c.RateLimits.Read }},
+	{env: "RATE_LIMIT_ENROLLMENT", flag: "rate-limit-enrollment", usage: "rate limit of the enrollment routes per client, or off",
+		field: func(c *Config) interface{} { return &c.RateLimits.Enrollment }},
+	{env: "RATE_LIMIT_WRITE", flag: "rate-limit-write", usage: "rate limit of the write routes per client, or off",
+		field: func(c *Config) interface{} { return &c.RateLimits.Write }},
+	{env: "RATE_LIMIT_IP", flag: "rate-limit-ip", usage: "rate limit of every IP address before authentication, or off",
+		field: func(c *Config) interface{} { return &c.RateLimits.IP }},
+	{env: "AUDIT_LOG_PATH", flag: "audit-log-path", usage: "path of the JSON lines file the audit log is appended to",
+		field: func(c *Config) interface{} { return &c.Audit.LogPath }},
+	{env: "EVENTS_WEBHOOK_URL", flag: "events-webhook-url", usage: "URL the domain events are posted to",
+		field: func(c *Config) interface{} { return &c.Events.WebhookURL }},
+}
+
+// Load returns the Config of the YAML file of the -config flag or of the CONFIG_FILE environment variable if any,
+// overridden by the environment variables returned by getenv, and by the command line flags of the given arguments.
+// It returns an error if the flags, the file or a variable cannot be parsed, or if the Config is invalid.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	config, _, err := LoadCommand(args, getenv)
+	return config, err
+}
+
+// LoadCommand returns the Config like Load, and the arguments following the flags, e.g. the command of a subcommand
+// such as "migrate -config config.yaml up".
+func LoadCommand(args []string, getenv func(string) string) (Config, []string, error) {
+	flags := flag.NewFlagSet("course-manager", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", getenv(envConfigFile), "path of the YAML configuration file")
+	for _, s := range settings {
+		flags.String(s.flag, "", fmt.Sprintf("%v (env %v)", s.usage, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, nil, fmt.Errorf("invalid flags: %w\nusage of course-manager:\n%v", err, usage(flags))
+	}
+
+	config := Default()
+	if *configFile != "" {
+		if err := config.readFile(*configFile); err != nil {
+			return Config{}, nil, err
+		}
+	}
+	for _, s := range settings {
+		if raw := getenv(s.env); raw != "" {
+			if err := set(s.field(&config), raw); err != nil {
+				return Config{}, nil, fmt.Errorf("invalid environment variable %v=%q: %w", s.env, raw, err)
+			}
+		}
+	}
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := set(s.field(&config), f.Value.String()); err != nil {
+					flagErr = fmt.Errorf("invalid flag -%v=%q: %w", f.Name, f.Value, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return Config{}, nil, flagErr
+	}
+
+	config.resolve()
+	return config, flags.Args(), config.Validate()
+}
+
+// readFile overrides the Config with the settings of the given YAML file, which cannot have unknown settings.
+// Other formats, e.g. TOML, are not supported, and are refused by the extension of the file.
+func (c *Config) readFile(path string) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".yaml" && ext != ".yml" {
+		return fmt.Errorf("the configuration file %v must be a YAML file with a .yaml or .yml extension, got %q", path, ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read the configuration file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid configuration file %v: %w", path, err)
+	}
+	return nil
+}
+
+// resolve sets the defaults which depend on other settings.
+func (c *Config) resolve() {
+	if c.Repo.Backend == "" {
+		c.Repo.Backend = BackendPostgres
+		if c.Environment == DevEnvironment {
+			c.Repo.Backend = BackendMemory
+		}
+	}
+	if c.Repo.Backend == BackendSQLite && c.Repo.DSN == "" {
+		c.Repo.DSN = DefaultSQLitePath
+	}
+}
+
+// Validate returns an error listing every invalid setting of the Config.
+func (c Config) Validate() error {
+	var problems []string
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		invalid("port must be between 1 and 65535, got %d", c.Port)
+	}
+	if !contains(LogLevels, c.LogLevel) {
+		invalid("logLevel must be one of %v, got %q", strings.Join(LogLevels, ", "), c.LogLevel)
+	}
+	if !contains(Backends, c.Repo.Backend) {
+		invalid("repo.backend must be one of %v, got %q", strings.Join(Backends, ", "), c.Repo.Backend)
+	}
+	if c.Repo.Backend == BackendPostgres && c.Repo.DSN == "" {
+		invalid("repo.dsn is required by the %v backend", BackendPostgres)
+	}
+	if err := c.Enrollment.Policy().Validate(); err != nil {
+		invalid("enrollment: %v", err)
+	}
+	if len(c.CORS.AllowOrigins) == 0 {
+		invalid("cors.allowOrigins cannot be empty, use * to allow any origin")
+	}
+	for _, origin := range c.CORS.AllowOrigins {
+		if parsed, err := url.Parse(origin); origin != "*" &&
+			(err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.Trim(parsed.Path, "/") != "") {
+			invalid("cors.allowOrigins must only contain * or origins such as https://example.com, got %q", origin)
+		}
+	}
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{name: "read", value: c.Timeouts.Read},
+		{name: "write", value: c.Timeouts.Write},
+		{name: "idle", value: c.Timeouts.Idle},
+		{name: "webhook", value: c.Timeouts.Webhook},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			invalid("timeouts.%v cannot be negative, got %v", timeout.name, timeout.value)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if parseNetwork(proxy) == nil {
+			invalid("trustedProxies must only contain IP addresses or CIDR networks such as 10.0.0.0/8, got %q", proxy)
+		}
+	}
+	if c.Auth.HS256Secret != "" && len(c.Auth.HS256Secret) < MinHS256SecretLength {
+		invalid("auth.hs256Secret must have at least %d bytes, got %d", MinHS256SecretLength, len(c.Auth.HS256Secret))
+	}
+	if c.Auth.RolesClaim == "" {
+		invalid("auth.rolesClaim cannot be empty")
+	}
+	rateLimits := []struct {
+		name  string
+		value RateLimit
+	}{
+		{name: "read", value: c.RateLimits.Read},
+		{name: "enrollment", value: c.RateLimits.Enrollment},
+		{name: "write", value: c.RateLimits.Write},
+		{name: "ip", value: c.RateLimits.IP},
+	}
+	for _, limit := range rateLimits {
+		if err := limit.value.Validate(); err != nil && !limit.value.Off {
+			invalid("rateLimits.%v: %v", limit.name, err)
+		}
+	}
+	if c.Events.WebhookURL != "" {
+		if parsed, err := url.Parse(c.Events.WebhookURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			invalid("events.webhookURL must be an http or https URL, got %q", c.Events.WebhookURL)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %v", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// TrustedProxyNetworks returns the networks of the TrustedProxies, an IP address being the network of its own.
+// The invalid proxies, reported by Validate, are skipped.
+func (c Config) TrustedProxyNetworks() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, proxy := range c.TrustedProxies {
+		if network := parseNetwork(proxy); network != nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// parseNetwork returns the network of the given CIDR notation or IP address, or nil if it is neither.
+func parseNetwork(raw string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(raw); err == nil {
+		return network
+	}
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// set parses the given raw value into the given field of a setting.
+func set(field interface{}, raw string) error {
+	switch value := field.(type) {
+	case *string:
+		*value = raw
+	case *int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		*value = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("must be a duration such as 30s or 1m")
+		}
+		*value = parsed
+	case *RateLimit:
+		return value.set(raw)
+	case *[]string:
+		*value = nil
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*value = append(*value, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported setting of type %T", field)
+	}
+	return nil
+}
+
+// usage returns the usage of the given flags.
+func usage(flags *flag.FlagSet) string {
+	var builder strings.Builder
+	flags.SetOutput(&builder)
+	flags.PrintDefaults()
+	flags.SetOutput(io.Discard)
+	return builder.String()
+}
+
+// contains reports whether the given values contain the given value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
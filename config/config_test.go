@@ -0,0 +1,305 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+// env returns a getenv function of the given variables.
+func env(variables map[string]string) func(string) string {
+	return func(key string) string {
+		return variables[key]
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(file, []byte(`
+port: 9000
+logLevel: warn
+repo:
+  backend: sqlite
+enrollment:
+  tutorMaxCourse: 3
+cors:
+  allowOrigins: [https://example.com]
+timeouts:
+  read: 5s
+auth:
+  issuer: https://auth.example.com
+rateLimits:
+  enrollment: 10/1m
+  ip: off
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := filepath.Join(dir, "unknown.yaml")
+	if err = os.WriteFile(unknown, []byte("prot: 9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fromFile := Default()
+	fromFile.Port = 9000
+	fromFile.LogLevel = "warn"
+	fromFile.Repo = Repo{Backend: BackendSQLite, DSN: DefaultSQLitePath}
+	fromFile.Enrollment.TutorMaxCourse = 3
+	fromFile.CORS.AllowOrigins = []string{"https://example.com"}
+	fromFile.Timeouts.Read = 5 * time.Second
+	fromFile.Auth.Issuer = "https://auth.example.com"
+	fromFile.RateLimits.Enrollment = RateLimit{RateLimit: services.RateLimit{Requests: 10, Period: time.Minute}}
+	fromFile.RateLimits.IP = RateLimit{Off: true}
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    func(c *Config)
+		wantErr string
+	}{
+		{
+			name: "defaults in development",
+			env:  map[string]string{"ENVIRONMENT": DevEnvironment},
+			want: func(c *Config) {
+				*c = Default()
+				c.Environment = DevEnvironment
+				c.Repo.Backend = BackendMemory
+			},
+		},
+		{
+			name: "postgres by default outside development",
+			env:  map[string]string{"DATABASE_URL": "postgres://localhost"},
+			want: func(c *Config) {
+				*c = Default()
+				c.Repo = Repo{Backend: BackendPostgres, DSN: "postgres://localhost"}
+			},
+		},
+		{
+			name: "file",
+			args: []string{"-config", file},
+			want: func(c *Config) { *c = fromFile },
+		},
+		{
+			name: "file of the environment",
+			env:  map[string]string{"CONFIG_FILE": file},
+			want: func(c *Config) { *c = fromFile },
+		},
+		{
+			name: "environment overrides the file",
+			args: []string{"-config", file},
+			env: map[string]string{"PORT": "9001", "TUTOR_MAX_COURSE": "4", "CORS_ALLOW_ORIGINS": "https://a.com, http://b.com:8080",
+				"WEBHOOK_TIMEOUT": "1m", "TRUSTED_PROXIES": "10.0.0.1, 10.1.0.0/16"},
+			want: func(c *Config) {
+				*c = fromFile
+				c.Port = 9001
+				c.Enrollment.TutorMaxCourse = 4
+				c.CORS.AllowOrigins = []string{"https://a.com", "http://b.com:8080"}
+				c.Timeouts.Webhook = time.Minute
+				c.TrustedProxies = []string{"10.0.0.1", "10.1.0.0/16"}
+			},
+		},
+		{
+			name: "environment of the auth, rate limits, audit and events",
+			env: map[string]string{"ENVIRONMENT": DevEnvironment, "AUTH_HS256_SECRET": strings.Repeat("s", MinHS256SecretLength),
+				"AUTH_AUDIENCE": "course-manager", "AUTH_ROLES_CLAIM": "groups", "RATE_LIMIT_WRITE": "off",
+				"RATE_LIMIT_READ": "100/10s", "AUDIT_LOG_PATH": "audit.jsonl", "EVENTS_WEBHOOK_URL": "https://events.example.com"},
+			want: func(c *Config) {
+				*c = Default()
+				c.Environment = DevEnvironment
+				c.Repo.Backend = BackendMemory
+				c.Auth.HS256Secret = strings.Repeat("s", MinHS256SecretLength)
+				c.Auth.Audience = "course-manager"
+				c.Auth.RolesClaim = "groups"
+				c.RateLimits.Read = RateLimit{RateLimit: services.RateLimit{Requests: 100, Period: 10 * time.Second}}
+				c.RateLimits.Write = RateLimit{Off: true}
+				c.Audit.LogPath = "audit.jsonl"
+				c.Events.WebhookURL = "https://events.example.com"
+			},
+		},
+		{
+			name: "flags override the environment",
+			args: []string{"-config", file, "-port", "9002", "-repo-dsn", "test.db", "-read-timeout=0s"},
+			env:  map[string]string{"PORT": "9001", "DATABASE_URL": "env.db"},
+			want: func(c *Config) {
+				*c = fromFile
+				c.Port = 9002
+				c.Repo.DSN = "test.db"
+				c.Timeouts.Read = 0
+			},
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-prot", "9000"},
+			wantErr: "flag provided but not defined: -prot",
+		},
+		{
+			name:    "missing file",
+			args:    []string{"-config", filepath.Join(dir, "missing.yaml")},
+			wantErr: "unable to read the configuration file",
+		},
+		{
+			name:    "TOML file",
+			args:    []string{"-config", filepath.Join(dir, "config.toml")},
+			wantErr: `must be a YAML file with a .yaml or .yml extension, got ".toml"`,
+		},
+		{
+			name:    "unknown setting of the file",
+			args:    []string{"-config", unknown},
+			wantErr: "field prot not found",
+		},
+		{
+			name:    "invalid integer",
+			env:     map[string]string{"ENVIRONMENT": DevEnvironment, "PORT": "eighty"},
+			wantErr: `invalid environment variable PORT="eighty": must be an integer`,
+		},
+		{
+			name:    "invalid duration",
+			args:    []string{"-idle-timeout", "10"},
+			env:     map[string]string{"ENVIRONMENT": DevEnvironment},
+			wantErr: `invalid flag -idle-timeout="10": must be a duration`,
+		},
+		{
+			name:    "invalid rate limit",
+			args:    []string{"-rate-limit-enrollment", "10 per minute"},
+			env:     map[string]string{"ENVIRONMENT": DevEnvironment},
+			wantErr: `invalid flag -rate-limit-enrollment="10 per minute": rate limit "10 per minute" must be <requests>/<period>`,
+		},
+		{
+			name:    "invalid configuration",
+			args:    []string{"-port", "0"},
+			wantErr: "port must be between 1 and 65535, got 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.args, env(tt.env))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error %v", err)
+			}
+			var want Config
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Load() got = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoad_example(t *testing.T) {
+	got, err := Load([]string{"-config", "../config.example.yaml"}, env(nil))
+	if err != nil {
+		t.Fatalf("Load() unexpected error %v", err)
+	}
+	want := Default()
+	want.Environment = DevEnvironment
+	want.Repo.Backend = BackendMemory
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() got = %+v, want the defaults %+v", got, want)
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	got, args, err := LoadCommand([]string{"-repo-backend", "sqlite", "-repo-dsn", "test.db", "up"}, env(nil))
+	if err != nil {
+		t.Fatalf("LoadCommand() unexpected error %v", err)
+	}
+	if got.Repo != (Repo{Backend: BackendSQLite, DSN: "test.db"}) || !reflect.DeepEqual(args, []string{"up"}) {
+		t.Errorf("LoadCommand() got = %+v, %v, want the sqlite repo of test.db and [up]", got.Repo, args)
+	}
+}
+
+func TestConfig_TrustedProxyNetworks(t *testing.T) {
+	config := Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::1", "invalid"}}
+	var got []string
+	for _, network := range config.TrustedProxyNetworks() {
+		got = append(got, network.String())
+	}
+	if want := []string{"10.0.0.0/8", "192.168.1.10/32", "2001:db8::1/128"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TrustedProxyNetworks() got = %v, want %v", got, want)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{
+			name: "every problem",
+			modify: func(c *Config) {
+				c.Port = 70000
+				c.LogLevel = "verbose"
+				c.Repo.Backend = "mysql"
+				c.Enrollment.CourseMaxStudent = 0
+				c.CORS.AllowOrigins = []string{"*", "example.com", "https://example.com/path", "ftp://example.com"}
+				c.Timeouts.Idle = -time.Second
+				c.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"}
+				c.Auth.HS256Secret = "secret"
+				c.Auth.RolesClaim = ""
+				c.RateLimits.Read = RateLimit{}
+				c.Events.WebhookURL = "events.example.com"
+			},
+			want: []string{
+				"port must be between 1 and 65535, got 70000",
+				`logLevel must be one of debug, info, warn, error, off, got "verbose"`,
+				`repo.backend must be one of memory, postgres, sqlite, got "mysql"`,
+				"enrollment: ",
+				`cors.allowOrigins must only contain * or origins such as https://example.com, got "example.com"`,
+				`got "https://example.com/path"`,
+				`got "ftp://example.com"`,
+				"timeouts.idle cannot be negative, got -1s",
+				`trustedProxies must only contain IP addresses or CIDR networks such as 10.0.0.0/8, got "proxy.internal"`,
+				"auth.hs256Secret must have at least 32 bytes, got 6",
+				"auth.rolesClaim cannot be empty",
+				"rateLimits.read: rate limit 0/0s must have a positive number of requests and period",
+				`events.webhookURL must be an http or https URL, got "events.example.com"`,
+			},
+		},
+		{
+			name:   "postgres without a DSN",
+			modify: func(c *Config) { c.Repo = Repo{Backend: BackendPostgres} },
+			want:   []string{"repo.dsn is required by the postgres backend"},
+		},
+		{
+			name:   "no origins",
+			modify: func(c *Config) { c.CORS.AllowOrigins = nil },
+			want:   []string{"cors.allowOrigins cannot be empty"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Default()
+			config.Repo.Backend = BackendMemory
+			tt.modify(&config)
+			err := config.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
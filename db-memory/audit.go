@@ -0,0 +1,52 @@
+package db_memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// Record appends a copy of the given event to the audit log, within the unit of work of the context if any.
+func (r *Repo) Record(ctx context.Context, event models.AuditEvent) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	r.auditLog = append(r.auditLog, copyAuditEvent(event))
+	return nil
+}
+
+// History returns copies of the audit events of the given course in the order they have been recorded.
+func (r *Repo) History(ctx context.Context, courseUUID uuid.UUID) ([]models.AuditEvent, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	var events []models.AuditEvent
+	for _, event := range r.auditLog {
+		if event.CourseUUID == courseUUID {
+			events = append(events, copyAuditEvent(event))
+		}
+	}
+	return events, nil
+}
+
+// copyAuditEvent returns a copy of the given event which does not share its courses.
+func copyAuditEvent(event models.AuditEvent) models.AuditEvent {
+	if event.Before != nil {
+		course := copyCourse(*event.Before)
+		event.Before = &course
+	}
+	if event.After != nil {
+		course := copyCourse(*event.After)
+		event.After = &course
+	}
+	if event.StudentUUID != nil {
+		studentUUID := *event.StudentUUID
+		event.StudentUUID = &studentUUID
+	}
+	return event
+}
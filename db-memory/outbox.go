@@ -0,0 +1,115 @@
+package db_memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// outboxEntry is a stored event, with the consumers which have published it.
+// Entries are replaced rather than mutated, so that WithTx can restore the outbox.
+type outboxEntry struct {
+	event       models.Event
+	publishedBy map[string]bool
+}
+
+// AddToOutbox stores copies of the given events after the stored ones.
+func (r *Repo) AddToOutbox(ctx context.Context, events ...models.Event) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, event := range events {
+		r.outbox = append(r.outbox, outboxEntry{event: copyEvent(event)})
+	}
+	return nil
+}
+
+// PendingEvents returns copies of up to limit stored events which the given consumer has not published,
+// in the order they have been stored.
+func (r *Repo) PendingEvents(ctx context.Context, consumer string, limit int) ([]models.Event, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	var events []models.Event
+	for _, entry := range r.outbox {
+		if len(events) == limit {
+			break
+		}
+		if !entry.publishedBy[consumer] {
+			events = append(events, copyEvent(entry.event))
+		}
+	}
+	return events, nil
+}
+
+// MarkPublished records that the given consumer has published the given events. Unknown events are ignored.
+func (r *Repo) MarkPublished(ctx context.Context, consumer string, eventIDs ...uuid.UUID) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	published := make(map[uuid.UUID]bool, len(eventIDs))
+	for _, eventID := range eventIDs {
+		published[eventID] = true
+	}
+	// The outbox is replaced rather than updated in place, so that WithTx can restore it.
+	outbox := make([]outboxEntry, 0, len(r.outbox))
+	for _, entry := range r.outbox {
+		if published[entry.event.ID] && !entry.publishedBy[consumer] {
+			publishedBy := map[string]bool{consumer: true}
+			for name := range entry.publishedBy {
+				publishedBy[name] = true
+			}
+			entry.publishedBy = publishedBy
+		}
+		outbox = append(outbox, entry)
+	}
+	r.outbox = outbox
+	return nil
+}
+
+// RemovePublished removes the events which have been published by every one of the given consumers.
+func (r *Repo) RemovePublished(ctx context.Context, consumers ...string) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var outbox []outboxEntry
+	for _, entry := range r.outbox {
+		if !publishedByAll(entry, consumers) {
+			outbox = append(outbox, entry)
+		}
+	}
+	r.outbox = outbox
+	return nil
+}
+
+// publishedByAll reports whether every one of the given consumers has published the event of the given entry.
+func publishedByAll(entry outboxEntry, consumers []string) bool {
+	for _, consumer := range consumers {
+		if !entry.publishedBy[consumer] {
+			return false
+		}
+	}
+	return true
+}
+
+// copyEvent returns a copy of the given event which does not share its course.
+func copyEvent(event models.Event) models.Event {
+	if event.Course != nil {
+		course := copyCourse(*event.Course)
+		event.Course = &course
+	}
+	if event.StudentUUID != nil {
+		studentUUID := *event.StudentUUID
+		event.StudentUUID = &studentUUID
+	}
+	return event
+}
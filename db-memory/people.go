@@ -0,0 +1,172 @@
+package db_memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// TutorById returns the tutor for the given UUID.
+// It returns a *models.NotFoundError if there is no such tutor.
+func (r *Repo) TutorById(ctx context.Context, tutorUUID uuid.UUID) (*models.Tutor, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	tutor, ok := r.tutorByUUID[tutorUUID]
+	if !ok {
+		return nil, models.NewTutorNotFoundErr(tutorUUID)
+	}
+	return &tutor, nil
+}
+
+// ListTutors returns all tutors ordered by lastname and name.
+func (r *Repo) ListTutors(ctx context.Context) ([]models.Tutor, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	tutors := make([]models.Tutor, 0, len(r.tutorByUUID))
+	for _, tutor := range r.tutorByUUID {
+		tutors = append(tutors, tutor)
+	}
+	sort.Slice(tutors, func(i, j int) bool {
+		return lessUser(tutors[i].User, tutors[j].User)
+	})
+	return tutors, nil
+}
+
+// CreateTutor stores the given tutor.
+// It returns a *models.AlreadyExistsError if a tutor with the same UUID exists.
+func (r *Repo) CreateTutor(ctx context.Context, tutor models.Tutor) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, ok := r.tutorByUUID[tutor.Uuid]; ok {
+		return models.NewAlreadyExistsErr(models.ResourceTutor, tutor.Uuid)
+	}
+	r.tutorByUUID[tutor.Uuid] = tutor
+	return nil
+}
+
+// UpdateTutor replaces the stored tutor with the given one.
+// It returns a *models.NotFoundError if the tutor does not exist.
+func (r *Repo) UpdateTutor(ctx context.Context, tutor models.Tutor) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, ok := r.tutorByUUID[tutor.Uuid]; !ok {
+		return models.NewTutorNotFoundErr(tutor.Uuid)
+	}
+	r.tutorByUUID[tutor.Uuid] = tutor
+	return nil
+}
+
+// DeleteTutor deletes the tutor for the given UUID.
+// Deleting a tutor which does not exist is a no-op.
+func (r *Repo) DeleteTutor(ctx context.Context, tutorUUID uuid.UUID) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	delete(r.tutorByUUID, tutorUUID)
+	return nil
+}
+
+// StudentById returns the student for the given UUID.
+// It returns a *models.NotFoundError if there is no such student.
+func (r *Repo) StudentById(ctx context.Context, studentUUID uuid.UUID) (*models.Student, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	student, ok := r.studentByUUID[studentUUID]
+	if !ok {
+		return nil, models.NewStudentNotFoundErr(studentUUID)
+	}
+	return &student, nil
+}
+
+// ListStudents returns all students ordered by lastname and name.
+func (r *Repo) ListStudents(ctx context.Context) ([]models.Student, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	students := make([]models.Student, 0, len(r.studentByUUID))
+	for _, student := range r.studentByUUID {
+		students = append(students, student)
+	}
+	sort.Slice(students, func(i, j int) bool {
+		return lessUser(students[i].User, students[j].User)
+	})
+	return students, nil
+}
+
+// CreateStudent stores the given student.
+// It returns a *models.AlreadyExistsError if a student with the same UUID exists.
+func (r *Repo) CreateStudent(ctx context.Context, student models.Student) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, ok := r.studentByUUID[student.Uuid]; ok {
+		return models.NewAlreadyExistsErr(models.ResourceStudent, student.Uuid)
+	}
+	r.studentByUUID[student.Uuid] = student
+	return nil
+}
+
+// UpdateStudent replaces the stored student with the given one.
+// It returns a *models.NotFoundError if the student does not exist.
+func (r *Repo) UpdateStudent(ctx context.Context, student models.Student) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, ok := r.studentByUUID[student.Uuid]; !ok {
+		return models.NewStudentNotFoundErr(student.Uuid)
+	}
+	r.studentByUUID[student.Uuid] = student
+	return nil
+}
+
+// DeleteStudent deletes the student for the given UUID.
+// Deleting a student who does not exist is a no-op.
+func (r *Repo) DeleteStudent(ctx context.Context, studentUUID uuid.UUID) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if r.countStudentCourses(studentUUID) > 0 {
+		return fmt.Errorf("unable to delete the student in courses: %w", models.ErrConstraint)
+	}
+	delete(r.studentByUUID, studentUUID)
+	return nil
+}
+
+// lessUser orders users by lastname, name and UUID.
+func lessUser(a, b models.User) bool {
+	if a.Lastname != b.Lastname {
+		return a.Lastname < b.Lastname
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.Uuid.String() < b.Uuid.String()
+}
@@ -0,0 +1,290 @@
+package db_memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// txKey is the context key marking the calls made within Repo.WithTx.
+type txKey struct {
+	repo *Repo
+}
+
+// Repo is a services.Repo keeping courses, tutors, students, the outbox and the audit log in memory. It is safe for concurrent use.
+// Courses are deep-copied in and out of the Repo, so callers cannot mutate the stored state
+// through the Students map or the Waitlist of a course.
+// Its calls fail with the error of their context if it is done.
+type Repo struct {
+	mu            sync.RWMutex
+	courseByUUID  map[uuid.UUID]models.Course
+	tutorByUUID   map[uuid.UUID]models.Tutor
+	studentByUUID map[uuid.UUID]models.Student
+	outbox        []outboxEntry
+	auditLog      []models.AuditEvent
+}
+
+// NewRepo returns a Repo holding a copy of the given courses, tutors and students.
+// Courses without a version are stored at version 1.
+func NewRepo(courseByUUID map[uuid.UUID]models.Course, tutorByUUID map[uuid.UUID]models.Tutor,
+	studentByUUID map[uuid.UUID]models.Student) *Repo {
+	r := &Repo{
+		courseByUUID:  make(map[uuid.UUID]models.Course, len(courseByUUID)),
+		tutorByUUID:   make(map[uuid.UUID]models.Tutor, len(tutorByUUID)),
+		studentByUUID: make(map[uuid.UUID]models.Student, len(studentByUUID)),
+	}
+	for courseUUID, course := range courseByUUID {
+		course = copyCourse(course)
+		if course.Version == 0 {
+			course.Version = 1
+		}
+		r.courseByUUID[courseUUID] = course
+	}
+	for tutorUUID, tutor := range tutorByUUID {
+		r.tutorByUUID[tutorUUID] = tutor
+	}
+	for studentUUID, student := range studentByUUID {
+		r.studentByUUID[studentUUID] = student
+	}
+	return r
+}
+
+// WithTx runs fn while holding the write lock of the Repo, and restores the courses, tutors, students,
+// outbox and audit log if fn fails.
+func (r *Repo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.inTx(ctx) {
+		return fn(ctx)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Stored values are replaced rather than mutated, so copying the maps is enough to snapshot them.
+	snapshot := make(map[uuid.UUID]models.Course, len(r.courseByUUID))
+	for courseUUID, course := range r.courseByUUID {
+		snapshot[courseUUID] = course
+	}
+	tutorSnapshot := make(map[uuid.UUID]models.Tutor, len(r.tutorByUUID))
+	for tutorUUID, tutor := range r.tutorByUUID {
+		tutorSnapshot[tutorUUID] = tutor
+	}
+	studentSnapshot := make(map[uuid.UUID]models.Student, len(r.studentByUUID))
+	for studentUUID, student := range r.studentByUUID {
+		studentSnapshot[studentUUID] = student
+	}
+	// Entries are only appended to the outbox, or replaced with the whole outbox, so its length is enough to snapshot it.
+	outboxSnapshot := r.outbox[:len(r.outbox):len(r.outbox)]
+	auditSnapshot := r.auditLog[:len(r.auditLog):len(r.auditLog)]
+	if err := fn(context.WithValue(ctx, txKey{r}, true)); err != nil {
+		r.courseByUUID = snapshot
+		r.tutorByUUID = tutorSnapshot
+		r.studentByUUID = studentSnapshot
+		r.outbox = outboxSnapshot
+		r.auditLog = auditSnapshot
+		return err
+	}
+	return nil
+}
+
+// ById returns a copy of the course for the given UUID.
+// It returns a *models.NotFoundError if there is no such course.
+func (r *Repo) ById(ctx context.Context, courseUUID uuid.UUID) (*models.Course, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	course, ok := r.courseByUUID[courseUUID]
+	if !ok {
+		return nil, models.NewCourseNotFoundErr(courseUUID)
+	}
+	course = copyCourse(course)
+	return &course, nil
+}
+
+// ByTutor returns copies of the courses facilitated by the given tutor.
+func (r *Repo) ByTutor(ctx context.Context, tutorUUID uuid.UUID) ([]models.Course, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.filter(func(course models.Course) bool {
+		return course.TutorUUID == tutorUUID
+	}), nil
+}
+
+// ByStudent returns copies of the courses the given student has registered to.
+func (r *Repo) ByStudent(ctx context.Context, studentUUID uuid.UUID) ([]models.Course, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.filter(func(course models.Course) bool {
+		_, ok := course.Students[studentUUID]
+		return ok
+	}), nil
+}
+
+// CountStudentCourses returns the number of courses the given student is registered to or waitlisted for.
+func (r *Repo) CountStudentCourses(ctx context.Context, studentUUID uuid.UUID) (int, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	return r.countStudentCourses(studentUUID), nil
+}
+
+// countStudentCourses returns the number of courses the given student is registered to or waitlisted for.
+// The caller must hold the lock.
+func (r *Repo) countStudentCourses(studentUUID uuid.UUID) int {
+	var count int
+	for _, course := range r.courseByUUID {
+		if _, ok := course.Students[studentUUID]; ok || onWaitlist(course, studentUUID) {
+			count++
+		}
+	}
+	return count
+}
+
+// List returns copies of the page of the courses selected by the given query.
+func (r *Repo) List(ctx context.Context, query models.CourseQuery) (*models.CoursePage, error) {
+	unlock, err := r.rLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return query.Page(r.filter(func(course models.Course) bool {
+		return query.Match(course, r.tutorByUUID[course.TutorUUID].Faculty)
+	}))
+}
+
+// Create stores a copy of the given course at version 1.
+// It returns a *models.AlreadyExistsError if a course with the same UUID exists.
+func (r *Repo) Create(ctx context.Context, course models.Course) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, ok := r.courseByUUID[course.Uuid]; ok {
+		return models.NewAlreadyExistsErr(models.ResourceCourse, course.Uuid)
+	}
+	course = copyCourse(course)
+	course.Version = 1
+	r.courseByUUID[course.Uuid] = course
+	return nil
+}
+
+// Delete deletes the course for the given UUID.
+// Deleting a course which does not exist is a no-op.
+func (r *Repo) Delete(ctx context.Context, courseUUID uuid.UUID) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	delete(r.courseByUUID, courseUUID)
+	return nil
+}
+
+// Update stores a copy of the given course and increments its version, keeping the creation time and creator of the stored one.
+// It returns a *models.VersionConflictErr if the stored course is not at the version of the given one,
+// and a *models.NotFoundError if the course does not exist.
+func (r *Repo) Update(ctx context.Context, course models.Course) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	stored, ok := r.courseByUUID[course.Uuid]
+	if !ok {
+		return models.NewCourseNotFoundErr(course.Uuid)
+	}
+	if stored.Version != course.Version {
+		return models.NewVersionConflictErr(course.Uuid, course.Version, stored.Version)
+	}
+	course = copyCourse(course)
+	course.Version++
+	course.CreatedAt = stored.CreatedAt
+	course.CreatedBy = stored.CreatedBy
+	r.courseByUUID[course.Uuid] = course
+	return nil
+}
+
+// filter returns copies of the courses matching the given predicate ordered by name.
+// The caller must hold the lock.
+func (r *Repo) filter(match func(course models.Course) bool) []models.Course {
+	var courses []models.Course
+	for _, course := range r.courseByUUID {
+		if match(course) {
+			courses = append(courses, copyCourse(course))
+		}
+	}
+	sort.Slice(courses, func(i, j int) bool {
+		if courses[i].Name != courses[j].Name {
+			return courses[i].Name < courses[j].Name
+		}
+		return courses[i].Uuid.String() < courses[j].Uuid.String()
+	})
+	return courses
+}
+
+func (r *Repo) inTx(ctx context.Context) bool {
+	return ctx.Value(txKey{r}) != nil
+}
+
+// lock takes the write lock, unless the call is made within WithTx which already holds it.
+// It returns the function releasing the lock, or the error of the context if it is done.
+func (r *Repo) lock(ctx context.Context) (func(), error) {
+	if r.inTx(ctx) {
+		return func() {}, ctx.Err()
+	}
+	r.mu.Lock()
+	if err := ctx.Err(); err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	return r.mu.Unlock, nil
+}
+
+// rLock takes the read lock, unless the call is made within WithTx which already holds the write lock.
+// It returns the function releasing the lock, or the error of the context if it is done.
+func (r *Repo) rLock(ctx context.Context) (func(), error) {
+	if r.inTx(ctx) {
+		return func() {}, ctx.Err()
+	}
+	r.mu.RLock()
+	if err := ctx.Err(); err != nil {
+		r.mu.RUnlock()
+		return nil, err
+	}
+	return r.mu.RUnlock, nil
+}
+
+// copyCourse returns a deep copy of the given course.
+func copyCourse(course models.Course) models.Course {
+	students := course.Students
+	course.Students = make(map[uuid.UUID]models.Enrollment, len(students))
+	for studentUUID, enrollment := range students {
+		course.Students[studentUUID] = enrollment
+	}
+	course.Waitlist = append([]uuid.UUID(nil), course.Waitlist...)
+	return course
+}
+
+// onWaitlist reports whether the given student is waitlisted for the given course.
+func onWaitlist(course models.Course, studentUUID uuid.UUID) bool {
+	for _, waitlisted := range course.Waitlist {
+		if waitlisted == studentUUID {
+			return true
+		}
+	}
+	return false
+}
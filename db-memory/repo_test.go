@@ -0,0 +1,199 @@
+package db_memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/repotest"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+func newCourse(numberOfStudents int) models.Course {
+	course := models.Course{
+		CourseMeta: models.CourseMeta{
+			Uuid:      uuid.New(),
+			Name:      "test course",
+			TutorUUID: uuid.New(),
+		},
+		Students: make(map[uuid.UUID]models.Enrollment),
+	}
+	for i := 0; i < numberOfStudents; i++ {
+		studentUUID := uuid.New()
+		course.Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID}
+	}
+	return course
+}
+
+func TestRepo_copiesCourses(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewRepo(nil, nil, nil)
+	course := newCourse(2)
+	course.Waitlist = []uuid.UUID{uuid.New()}
+	if err := repo.Create(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	course.Waitlist[0] = uuid.Nil
+	course.Students[uuid.New()] = models.Enrollment{}
+
+	got, err := repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Waitlist[0] == uuid.Nil || len(got.Students) != 2 {
+		t.Errorf("stored course has been mutated through the created one: %v", got)
+	}
+
+	got.Waitlist[0] = uuid.Nil
+	got.Students[uuid.New()] = models.Enrollment{}
+	page, err := repo.List(ctx, models.CourseQuery{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if page.Courses[0].Waitlist[0] == uuid.Nil || len(page.Courses[0].Students) != 2 {
+		t.Errorf("stored course has been mutated through the returned one: %v", page.Courses[0])
+	}
+}
+
+func TestRepo_Update(t *testing.T) {
+	ctx := context.TODO()
+	course := newCourse(1)
+	repo := NewRepo(map[uuid.UUID]models.Course{course.Uuid: course}, nil, nil)
+
+	got, err := repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Version != 1 {
+		t.Errorf("expected the seeded course at version 1, got %d", got.Version)
+	}
+	got.Name = "renamed course"
+	if err = repo.Update(ctx, *got); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var conflictErr *models.VersionConflictErr
+	if err = repo.Update(ctx, *got); !errors.As(err, &conflictErr) {
+		t.Errorf("Update() error = %v, want a version conflict", err)
+	}
+	if err = repo.Update(ctx, newCourse(0)); err == nil {
+		t.Errorf("expected error updating an unknown course, but none raised")
+	}
+
+	got, err = repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Name != "renamed course" || got.Version != 2 {
+		t.Errorf("ById() got = %v, want the renamed course at version 2", got)
+	}
+}
+
+func TestRepo_WithTx(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewRepo(nil, nil, nil)
+	course := newCourse(1)
+
+	errRollback := errors.New("rollback")
+	err := repo.WithTx(ctx, func(ctx context.Context) error {
+		if err := repo.CreateTutor(ctx, models.Tutor{User: models.User{Uuid: course.TutorUUID}}); err != nil {
+			return err
+		}
+		if err := repo.Create(ctx, course); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errRollback)
+	}
+	page, err := repo.List(ctx, models.CourseQuery{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(page.Courses) != 0 {
+		t.Errorf("expected the course creation to be rolled back, got %v", page.Courses)
+	}
+	tutors, err := repo.ListTutors(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(tutors) != 0 {
+		t.Errorf("expected the tutor creation to be rolled back, got %v", tutors)
+	}
+
+	err = repo.WithTx(ctx, func(ctx context.Context) error {
+		return repo.Create(ctx, course)
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course.Version = 1
+	got, err := repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, course) {
+		t.Errorf("ById() got = %v, want %v", *got, course)
+	}
+}
+
+func TestRepo_concurrentRegistrations(t *testing.T) {
+	ctx := context.TODO()
+	const registrations = 15
+	courseMeta := newCourse(0).CourseMeta
+	tutorByUUID := map[uuid.UUID]models.Tutor{courseMeta.TutorUUID: {User: models.User{Uuid: courseMeta.TutorUUID}}}
+	studentByUUID := make(map[uuid.UUID]models.Student, registrations)
+	for i := 0; i < registrations; i++ {
+		studentUUID := uuid.New()
+		studentByUUID[studentUUID] = models.Student{User: models.User{Uuid: studentUUID}}
+	}
+	repo := NewRepo(nil, tutorByUUID, studentByUUID)
+	courseManager, err := services.NewCourseManager(repo, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course, err := courseManager.Create(ctx, courseMeta)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	var wg sync.WaitGroup
+	for studentUUID := range studentByUUID {
+		wg.Add(2)
+		go func(studentUUID uuid.UUID) {
+			defer wg.Done()
+			if _, err := courseManager.RegisterStudent(ctx, course.Uuid, studentUUID, 0); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}(studentUUID)
+		go func() {
+			defer wg.Done()
+			if _, err := courseManager.List(ctx, models.CourseQuery{}); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := courseManager.Get(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(got.Students) != registrations {
+		t.Errorf("expected %d students, got %d", registrations, len(got.Students))
+	}
+	if got.Version != registrations+1 {
+		t.Errorf("expected version %d, got %d", registrations+1, got.Version)
+	}
+}
+
+func TestRepo_conformance(t *testing.T) {
+	repotest.Run(t, func() services.Repo {
+		return NewRepo(nil, nil, nil)
+	})
+}
@@ -2,6 +2,8 @@ package db_mock
 
 import (
 	"context"
+	"sync"
+
 	"github.com/google/uuid"
 	"github.com/tomasdembelli/course-manager/models"
 )
@@ -22,47 +24,71 @@ func (e *mockError) Error() string {
 	return e.message
 }
 
+// Is reports whether the target is a mock error too, so that every mock error matches NewMockError().
 func (e *mockError) Is(target error) bool {
-	return target.Error() == e.message
+	_, ok := target.(*mockError)
+	return ok
 }
 
+// txKey is the context key marking the calls made within MockRepo.WithTx.
+type txKey struct{}
+
 type Config struct {
-	CourseByUUID map[uuid.UUID]models.Course
-	ErrByTutor   error
-	ErrByStudent error
-	ErrCreate    error
-	ErrById      error
-	ErrUpdate    error
-	ErrDelete    error
-	ErrList      error
+	CourseByUUID  map[uuid.UUID]models.Course
+	TutorByUUID   map[uuid.UUID]models.Tutor
+	StudentByUUID map[uuid.UUID]models.Student
+	ErrWithTx     error
+	ErrByTutor    error
+	ErrByStudent  error
+	ErrCreate     error
+	ErrById       error
+	ErrUpdate     error
+	ErrDelete     error
+	ErrList       error
+	ErrOutbox     error
 }
 
+// MockRepo is a services.Repo for testing, returning the errors given in its Config.
+// Its calls fail with the error of their context if it is done.
+// It is not safe for concurrent use outside WithTx, see db_memory.Repo for a concurrency-safe in-memory repo.
 type MockRepo struct {
-	courseByUUID map[uuid.UUID]models.Course
-	errByTutor   error
-	errByStudent error
-	errCreate    error
-	errById      error
-	errUpdate    error
-	errDelete    error
-	errList      error
+	txMu          sync.Mutex
+	courseByUUID  map[uuid.UUID]models.Course
+	tutorByUUID   map[uuid.UUID]models.Tutor
+	studentByUUID map[uuid.UUID]models.Student
+	errWithTx     error
+	errByTutor    error
+	errByStudent  error
+	errCreate     error
+	errById       error
+	errUpdate     error
+	errDelete     error
+	errList       error
+	errOutbox     error
+	outbox        []outboxEntry
 }
 
 func NewMockRepo(config *Config) *MockRepo {
 	if config == nil {
 		return &MockRepo{
-			courseByUUID: make(map[uuid.UUID]models.Course),
+			courseByUUID:  make(map[uuid.UUID]models.Course),
+			tutorByUUID:   make(map[uuid.UUID]models.Tutor),
+			studentByUUID: make(map[uuid.UUID]models.Student),
 		}
 	}
 	return &MockRepo{
-		courseByUUID: config.CourseByUUID,
-		errByTutor:   config.ErrByTutor,
-		errByStudent: config.ErrByStudent,
-		errCreate:    config.ErrCreate,
-		errById:      config.ErrById,
-		errUpdate:    config.ErrUpdate,
-		errDelete:    config.ErrDelete,
-		errList:      config.ErrList,
+		courseByUUID:  config.CourseByUUID,
+		tutorByUUID:   config.TutorByUUID,
+		studentByUUID: config.StudentByUUID,
+		errWithTx:     config.ErrWithTx,
+		errByTutor:    config.ErrByTutor,
+		errByStudent:  config.ErrByStudent,
+		errCreate:     config.ErrCreate,
+		errById:       config.ErrById,
+		errUpdate:     config.ErrUpdate,
+		errDelete:     config.ErrDelete,
+		errList:       config.ErrList,
+		errOutbox:     config.ErrOutbox,
 	}
 }
 
@@ -70,67 +96,160 @@ func (m *MockRepo) safeInit() {
 	if m.courseByUUID == nil {
 		m.courseByUUID = make(map[uuid.UUID]models.Course)
 	}
+	if m.tutorByUUID == nil {
+		m.tutorByUUID = make(map[uuid.UUID]models.Tutor)
+	}
+	if m.studentByUUID == nil {
+		m.studentByUUID = make(map[uuid.UUID]models.Student)
+	}
+}
+
+// WithTx runs fn while holding the transaction lock of the MockRepo, and restores the courses, tutors, students
+// and outbox if fn fails.
+// The calls made outside WithTx are not synchronized.
+func (m *MockRepo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.errWithTx != nil {
+		return m.errWithTx
+	}
+	if ctx.Value(txKey{}) == m {
+		return fn(ctx)
+	}
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.safeInit()
+	snapshot := make(map[uuid.UUID]models.Course, len(m.courseByUUID))
+	for courseUUID, course := range m.courseByUUID {
+		snapshot[courseUUID] = copyCourse(course)
+	}
+	tutorSnapshot := make(map[uuid.UUID]models.Tutor, len(m.tutorByUUID))
+	for tutorUUID, tutor := range m.tutorByUUID {
+		tutorSnapshot[tutorUUID] = tutor
+	}
+	studentSnapshot := make(map[uuid.UUID]models.Student, len(m.studentByUUID))
+	for studentUUID, student := range m.studentByUUID {
+		studentSnapshot[studentUUID] = student
+	}
+	outboxSnapshot := append([]outboxEntry(nil), m.outbox...)
+	if err := fn(context.WithValue(ctx, txKey{}, m)); err != nil {
+		m.courseByUUID = snapshot
+		m.tutorByUUID = tutorSnapshot
+		m.studentByUUID = studentSnapshot
+		m.outbox = outboxSnapshot
+		return err
+	}
+	return nil
 }
 
-func (m *MockRepo) ById(_ context.Context, courseUuid uuid.UUID) (*models.Course, error) {
+func (m *MockRepo) ById(ctx context.Context, courseUuid uuid.UUID) (*models.Course, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if m.errById != nil {
 		return nil, m.errById
 	}
-	course := m.courseByUUID[courseUuid]
+	course, ok := m.courseByUUID[courseUuid]
+	if !ok {
+		return nil, models.NewCourseNotFoundErr(courseUuid)
+	}
 	return &course, nil
 }
 
-func (m *MockRepo) ByTutor(_ context.Context, tutorUuid uuid.UUID) ([]models.Course, error) {
+func (m *MockRepo) ByTutor(ctx context.Context, tutorUuid uuid.UUID) ([]models.Course, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if m.errByTutor != nil {
 		return nil, m.errByTutor
 	}
 	var result []models.Course
 	for _, course := range m.courseByUUID {
-		if course.Tutor.Uuid == tutorUuid {
+		if course.TutorUUID == tutorUuid {
 			result = append(result, course)
 		}
 	}
 	return result, nil
 }
 
-func (m *MockRepo) ByStudent(_ context.Context, studentUuid uuid.UUID) ([]models.Course, error) {
+func (m *MockRepo) ByStudent(ctx context.Context, studentUuid uuid.UUID) ([]models.Course, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if m.errByStudent != nil {
 		return nil, m.errByStudent
 	}
 	var result []models.Course
 	for _, course := range m.courseByUUID {
-		for _, student := range course.Students {
-			if student.Uuid == studentUuid {
-				result = append(result, course)
-			}
+		if _, ok := course.Students[studentUuid]; ok {
+			result = append(result, course)
 		}
 	}
 	return result, nil
 }
 
-func (m *MockRepo) List(_ context.Context) ([]models.Course, error) {
+func (m *MockRepo) CountStudentCourses(ctx context.Context, studentUUID uuid.UUID) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if m.errByStudent != nil {
+		return 0, m.errByStudent
+	}
+	return m.countStudentCourses(studentUUID), nil
+}
+
+// countStudentCourses returns the number of courses the given student is registered to or waitlisted for.
+func (m *MockRepo) countStudentCourses(studentUUID uuid.UUID) int {
+	var count int
+	for _, course := range m.courseByUUID {
+		_, ok := course.Students[studentUUID]
+		for _, waitlisted := range course.Waitlist {
+			ok = ok || waitlisted == studentUUID
+		}
+		if ok {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *MockRepo) List(ctx context.Context, query models.CourseQuery) (*models.CoursePage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if m.errList != nil {
 		return nil, m.errList
 	}
-	var courses = make([]models.Course, len(m.courseByUUID))
-	var counter int
+	var courses []models.Course
 	for _, course := range m.courseByUUID {
-		courses[counter] = course
-		counter++
+		if query.Match(course, m.tutorByUUID[course.TutorUUID].Faculty) {
+			courses = append(courses, course)
+		}
 	}
-	return courses, nil
+	return query.Page(courses)
 }
 
-func (m *MockRepo) Create(_ context.Context, course models.Course) error {
+func (m *MockRepo) Create(ctx context.Context, course models.Course) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.safeInit()
 	if m.errCreate != nil {
 		return m.errCreate
 	}
+	if _, ok := m.courseByUUID[course.Uuid]; ok {
+		return models.NewAlreadyExistsErr(models.ResourceCourse, course.Uuid)
+	}
+	course.Version = 1
 	m.courseByUUID[course.Uuid] = course
 	return nil
 }
 
-func (m *MockRepo) Delete(_ context.Context, courseUUID uuid.UUID) error {
+func (m *MockRepo) Delete(ctx context.Context, courseUUID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.safeInit()
 	if m.errDelete != nil {
 		return m.errDelete
@@ -139,11 +258,38 @@ func (m *MockRepo) Delete(_ context.Context, courseUUID uuid.UUID) error {
 	return nil
 }
 
-func (m *MockRepo) Update(_ context.Context, course models.Course) error {
+func (m *MockRepo) Update(ctx context.Context, course models.Course) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.safeInit()
 	if m.errUpdate != nil {
 		return NewMockError()
 	}
+	stored, ok := m.courseByUUID[course.Uuid]
+	if !ok {
+		return models.NewCourseNotFoundErr(course.Uuid)
+	}
+	if stored.Version != course.Version {
+		return models.NewVersionConflictErr(course.Uuid, course.Version, stored.Version)
+	}
+	course.Version++
+	course.CreatedAt = stored.CreatedAt
+	course.CreatedBy = stored.CreatedBy
 	m.courseByUUID[course.Uuid] = course
 	return nil
 }
+
+func copyCourse(course models.Course) models.Course {
+	students := course.Students
+	if students != nil {
+		course.Students = make(map[uuid.UUID]models.Enrollment, len(students))
+		for studentUUID, enrollment := range students {
+			course.Students[studentUUID] = enrollment
+		}
+	}
+	if course.Waitlist != nil {
+		course.Waitlist = append([]uuid.UUID(nil), course.Waitlist...)
+	}
+	return course
+}
@@ -5,30 +5,43 @@ import (
 	"github.com/tomasdembelli/course-manager/models"
 )
 
+var (
+	mockTutorUUID   = uuid.MustParse("8b0e3a52-3c3f-4b8e-9d0c-6f6f1d2a7c11")
+	mockStudentUUID = uuid.MustParse("e6a4b1f0-5d7c-4f1e-8a2b-3c9d0e1f2a34")
+)
+
+var TutorByUUID = map[uuid.UUID]models.Tutor{
+	mockTutorUUID: {
+		User: models.User{
+			Uuid:     mockTutorUUID,
+			Name:     "Mock",
+			Lastname: "Tutor",
+		},
+		Faculty:    "Mock Faculty",
+		LecturerOf: "Mock Lecturer of",
+	},
+}
+
+var StudentByUUID = map[uuid.UUID]models.Student{
+	mockStudentUUID: {
+		User: models.User{
+			Uuid:     mockStudentUUID,
+			Name:     "Mock",
+			Lastname: "Student",
+		},
+		Faculty: "Mock Faculty",
+	},
+}
+
 var CourseByUUID = map[uuid.UUID]models.Course{
 	uuid.MustParse("2d2e10a1-94e2-4dff-a244-8733bee8b7a9"): {
 		CourseMeta: models.CourseMeta{
-			Uuid: uuid.MustParse("2d2e10a1-94e2-4dff-a244-8733bee8b7a9"),
-			Name: "Mock Course",
-			Tutor: &models.Tutor{
-				User: models.User{
-					Uuid:     uuid.New(),
-					Name:     "Mock",
-					Lastname: "Tutor",
-				},
-				Faculty:    "Mock Faculty",
-				LecturerOf: "Mock Lecturer of",
-			},
+			Uuid:      uuid.MustParse("2d2e10a1-94e2-4dff-a244-8733bee8b7a9"),
+			Name:      "Mock Course",
+			TutorUUID: mockTutorUUID,
 		},
-		Students: map[uuid.UUID]models.Student{
-			uuid.New(): {
-				User: models.User{
-					Uuid:     uuid.New(),
-					Name:     "Mock",
-					Lastname: "Student",
-				},
-				Faculty: "Mock Faculty",
-			},
+		Students: map[uuid.UUID]models.Enrollment{
+			mockStudentUUID: {StudentUUID: mockStudentUUID},
 		},
 	},
 }
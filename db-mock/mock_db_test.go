@@ -0,0 +1,14 @@
+package db_mock
+
+import (
+	"testing"
+
+	"github.com/tomasdembelli/course-manager/repotest"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+func TestMockRepo_conformance(t *testing.T) {
+	repotest.Run(t, func() services.Repo {
+		return NewMockRepo(nil)
+	})
+}
@@ -0,0 +1,100 @@
+package db_mock
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// outboxEntry is an event of the outbox, with the consumers which have published it.
+// Entries are replaced rather than mutated, so that WithTx can restore the outbox.
+type outboxEntry struct {
+	event       models.Event
+	publishedBy map[string]bool
+}
+
+func (m *MockRepo) AddToOutbox(ctx context.Context, events ...models.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.errOutbox != nil {
+		return m.errOutbox
+	}
+	for _, event := range events {
+		m.outbox = append(m.outbox, outboxEntry{event: event})
+	}
+	return nil
+}
+
+func (m *MockRepo) PendingEvents(ctx context.Context, consumer string, limit int) ([]models.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if m.errOutbox != nil {
+		return nil, m.errOutbox
+	}
+	var events []models.Event
+	for _, entry := range m.outbox {
+		if len(events) == limit {
+			break
+		}
+		if !entry.publishedBy[consumer] {
+			events = append(events, entry.event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockRepo) MarkPublished(ctx context.Context, consumer string, eventIDs ...uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.errOutbox != nil {
+		return m.errOutbox
+	}
+	published := make(map[uuid.UUID]bool, len(eventIDs))
+	for _, eventID := range eventIDs {
+		published[eventID] = true
+	}
+	outbox := make([]outboxEntry, 0, len(m.outbox))
+	for _, entry := range m.outbox {
+		if published[entry.event.ID] && !entry.publishedBy[consumer] {
+			publishedBy := map[string]bool{consumer: true}
+			for name := range entry.publishedBy {
+				publishedBy[name] = true
+			}
+			entry.publishedBy = publishedBy
+		}
+		outbox = append(outbox, entry)
+	}
+	m.outbox = outbox
+	return nil
+}
+
+func (m *MockRepo) RemovePublished(ctx context.Context, consumers ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.errOutbox != nil {
+		return m.errOutbox
+	}
+	var outbox []outboxEntry
+	for _, entry := range m.outbox {
+		if !publishedByAll(entry, consumers) {
+			outbox = append(outbox, entry)
+		}
+	}
+	m.outbox = outbox
+	return nil
+}
+
+// publishedByAll reports whether every one of the given consumers has published the event of the given entry.
+func publishedByAll(entry outboxEntry, consumers []string) bool {
+	for _, consumer := range consumers {
+		if !entry.publishedBy[consumer] {
+			return false
+		}
+	}
+	return true
+}
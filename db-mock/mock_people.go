@@ -0,0 +1,122 @@
+package db_mock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func (m *MockRepo) TutorById(ctx context.Context, tutorUUID uuid.UUID) (*models.Tutor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tutor, ok := m.tutorByUUID[tutorUUID]
+	if !ok {
+		return nil, models.NewTutorNotFoundErr(tutorUUID)
+	}
+	return &tutor, nil
+}
+
+func (m *MockRepo) ListTutors(ctx context.Context) ([]models.Tutor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var tutors []models.Tutor
+	for _, tutor := range m.tutorByUUID {
+		tutors = append(tutors, tutor)
+	}
+	return tutors, nil
+}
+
+func (m *MockRepo) CreateTutor(ctx context.Context, tutor models.Tutor) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.safeInit()
+	if _, ok := m.tutorByUUID[tutor.Uuid]; ok {
+		return models.NewAlreadyExistsErr(models.ResourceTutor, tutor.Uuid)
+	}
+	m.tutorByUUID[tutor.Uuid] = tutor
+	return nil
+}
+
+func (m *MockRepo) UpdateTutor(ctx context.Context, tutor models.Tutor) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.safeInit()
+	if _, ok := m.tutorByUUID[tutor.Uuid]; !ok {
+		return models.NewTutorNotFoundErr(tutor.Uuid)
+	}
+	m.tutorByUUID[tutor.Uuid] = tutor
+	return nil
+}
+
+func (m *MockRepo) DeleteTutor(ctx context.Context, tutorUUID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.safeInit()
+	delete(m.tutorByUUID, tutorUUID)
+	return nil
+}
+
+func (m *MockRepo) StudentById(ctx context.Context, studentUUID uuid.UUID) (*models.Student, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	student, ok := m.studentByUUID[studentUUID]
+	if !ok {
+		return nil, models.NewStudentNotFoundErr(studentUUID)
+	}
+	return &student, nil
+}
+
+func (m *MockRepo) ListStudents(ctx context.Context) ([]models.Student, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var students []models.Student
+	for _, student := range m.studentByUUID {
+		students = append(students, student)
+	}
+	return students, nil
+}
+
+func (m *MockRepo) CreateStudent(ctx context.Context, student models.Student) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.safeInit()
+	if _, ok := m.studentByUUID[student.Uuid]; ok {
+		return models.NewAlreadyExistsErr(models.ResourceStudent, student.Uuid)
+	}
+	m.studentByUUID[student.Uuid] = student
+	return nil
+}
+
+func (m *MockRepo) UpdateStudent(ctx context.Context, student models.Student) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.safeInit()
+	if _, ok := m.studentByUUID[student.Uuid]; !ok {
+		return models.NewStudentNotFoundErr(student.Uuid)
+	}
+	m.studentByUUID[student.Uuid] = student
+	return nil
+}
+
+func (m *MockRepo) DeleteStudent(ctx context.Context, studentUUID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.safeInit()
+	if m.countStudentCourses(studentUUID) > 0 {
+		return fmt.Errorf("unable to delete the student in courses: %w", models.ErrConstraint)
+	}
+	delete(m.studentByUUID, studentUUID)
+	return nil
+}
@@ -0,0 +1,121 @@
+package db_sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// CreateAPIKey stores the given API key as a JSON row of the api_keys table, with its hash but without its plaintext.
+// It returns a *models.AlreadyExistsError if a key with the same ID or hash exists.
+func (r *Repo) CreateAPIKey(ctx context.Context, apiKey models.APIKey) error {
+	apiKey.Key = ""
+	payload, err := json.Marshal(apiKey)
+	if err != nil {
+		return fmt.Errorf("unable to encode the API key: %w", err)
+	}
+	_, err = r.querier(ctx).ExecContext(ctx, `INSERT INTO api_keys (id, hash, payload) VALUES ($1, $2, $3)`,
+		apiKey.ID, apiKey.Hash, string(payload))
+	if r.dialect.duplicate(err) {
+		return models.NewAlreadyExistsErr(models.ResourceAPIKey, apiKey.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to store the API key: %w", err)
+	}
+	return nil
+}
+
+// APIKeyByID returns the API key with the given ID, or a *models.NotFoundError if it does not exist.
+func (r *Repo) APIKeyByID(ctx context.Context, apiKeyID uuid.UUID) (*models.APIKey, error) {
+	apiKey, err := r.queryAPIKey(ctx, `SELECT hash, payload FROM api_keys WHERE id = $1`, apiKeyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewAPIKeyNotFoundErr(apiKeyID)
+	}
+	return apiKey, err
+}
+
+// APIKeyByHash returns the API key with the given hash, or an error matching models.ErrNotFound if it does not exist.
+func (r *Repo) APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	apiKey, err := r.queryAPIKey(ctx, `SELECT hash, payload FROM api_keys WHERE hash = $1`, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no API key has the given hash: %w", models.ErrNotFound)
+	}
+	return apiKey, err
+}
+
+// ListAPIKeys returns the API keys in the order they have been created.
+func (r *Repo) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, `SELECT hash, payload FROM api_keys ORDER BY position`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var apiKeys []models.APIKey
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, *apiKey)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the API keys: %w", err)
+	}
+	return apiKeys, nil
+}
+
+// UpdateAPIKey stores the given API key. It returns a *models.NotFoundError if the key does not exist.
+// The hash of a key cannot be changed.
+func (r *Repo) UpdateAPIKey(ctx context.Context, apiKey models.APIKey) error {
+	apiKey.Key = ""
+	payload, err := json.Marshal(apiKey)
+	if err != nil {
+		return fmt.Errorf("unable to encode the API key: %w", err)
+	}
+	result, err := r.querier(ctx).ExecContext(ctx, `UPDATE api_keys SET payload = $1 WHERE id = $2`, string(payload), apiKey.ID)
+	if err != nil {
+		return fmt.Errorf("unable to update the API key: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to update the API key: %w", err)
+	}
+	if updated == 0 {
+		return models.NewAPIKeyNotFoundErr(apiKey.ID)
+	}
+	return nil
+}
+
+// queryAPIKey returns the API key selected by the given query, or sql.ErrNoRows if there is none.
+func (r *Repo) queryAPIKey(ctx context.Context, query string, args ...interface{}) (*models.APIKey, error) {
+	return scanAPIKey(r.querier(ctx).QueryRowContext(ctx, query, args...))
+}
+
+// scanner is the interface of *sql.Row and *sql.Rows scanning the columns of a row.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey decodes the API key of the hash and payload of the given row. Its hash is not part of the payload,
+// as it is not encoded in JSON.
+func scanAPIKey(row scanner) (*models.APIKey, error) {
+	var hash, payload string
+	if err := row.Scan(&hash, &payload); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to scan the API key: %w", err)
+	}
+	var apiKey models.APIKey
+	if err := json.Unmarshal([]byte(payload), &apiKey); err != nil {
+		return nil, fmt.Errorf("unable to decode the API key: %w", err)
+	}
+	apiKey.Hash = hash
+	return &apiKey, nil
+}
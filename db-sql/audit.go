@@ -0,0 +1,51 @@
+package db_sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// Record stores the given event as a JSON row of the audit_log table, within the transaction of the context if any.
+func (r *Repo) Record(ctx context.Context, event models.AuditEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("unable to encode the audit event: %w", err)
+	}
+	_, err = r.querier(ctx).ExecContext(ctx, `INSERT INTO audit_log (course_uuid, payload) VALUES ($1, $2)`,
+		event.CourseUUID, string(payload))
+	if err != nil {
+		return fmt.Errorf("unable to store the audit event: %w", err)
+	}
+	return nil
+}
+
+// History returns the audit events of the given course in the order they have been stored.
+func (r *Repo) History(ctx context.Context, courseUUID uuid.UUID) ([]models.AuditEvent, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx,
+		`SELECT payload FROM audit_log WHERE course_uuid = $1 ORDER BY position`, courseUUID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("unable to scan the audit event: %w", err)
+		}
+		var event models.AuditEvent
+		if err = json.Unmarshal([]byte(payload), &event); err != nil {
+			return nil, fmt.Errorf("unable to decode the audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the audit log: %w", err)
+	}
+	return events, nil
+}
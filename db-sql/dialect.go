@@ -0,0 +1,69 @@
+package db_sql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect captures what differs between the SQL databases supported by Repo.
+// The queries issued by Repo are shared by every dialect, while the schema of each
+// dialect is managed by the migrations package.
+type Dialect struct {
+	// Name is the database/sql driver name of the dialect, and the name of its migrations.
+	Name string
+	// txIsolation is the isolation level of the transactions started by Repo.WithTx.
+	txIsolation sql.IsolationLevel
+	// retryable reports whether a transaction failed with the given error can be retried.
+	retryable func(err error) bool
+	// duplicate reports whether a statement failed with the given error because it violates a primary key
+	// or a unique constraint.
+	duplicate func(err error) bool
+	// referenced reports whether a statement failed with the given error because it violates a foreign key.
+	referenced func(err error) bool
+}
+
+// Postgres is the Dialect for PostgreSQL databases.
+// Its units of work are serializable, and retried when they fail to serialize.
+var Postgres = Dialect{
+	Name:        "postgres",
+	txIsolation: sql.LevelSerializable,
+	retryable: func(err error) bool {
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) {
+			return false
+		}
+		// serialization_failure and deadlock_detected.
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	},
+	duplicate: func(err error) bool {
+		var pqErr *pq.Error
+		// unique_violation.
+		return errors.As(err, &pqErr) && pqErr.Code == "23505"
+	},
+	referenced: func(err error) bool {
+		var pqErr *pq.Error
+		// foreign_key_violation.
+		return errors.As(err, &pqErr) && pqErr.Code == "23503"
+	},
+}
+
+// SQLite is the Dialect for SQLite databases, backed by a pure Go driver.
+// Its transactions take the database write lock as soon as they begin, so they are serialized.
+var SQLite = Dialect{
+	Name:        "sqlite",
+	txIsolation: sql.LevelDefault,
+	retryable:   func(err error) bool { return false },
+	duplicate: func(err error) bool {
+		var sqliteErr *sqlite.Error
+		return errors.As(err, &sqliteErr) &&
+			(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE)
+	},
+	referenced: func(err error) bool {
+		var sqliteErr *sqlite.Error
+		return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	},
+}
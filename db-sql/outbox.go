@@ -0,0 +1,119 @@
+package db_sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// AddToOutbox stores the given events as JSON rows of the outbox table, after the stored ones.
+func (r *Repo) AddToOutbox(ctx context.Context, events ...models.Event) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, event := range events {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return fmt.Errorf("unable to encode the event: %w", err)
+			}
+			_, err = tx.ExecContext(ctx, `INSERT INTO outbox (id, payload) VALUES ($1, $2)`, event.ID, string(payload))
+			if err != nil {
+				return fmt.Errorf("unable to store the event: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// PendingEvents returns up to limit stored events which the given consumer has not published,
+// in the order they have been stored.
+// On PostgreSQL, events stored by concurrent transactions are ordered by the start of their insertion
+// rather than by the commit of their transaction.
+func (r *Repo) PendingEvents(ctx context.Context, consumer string, limit int) ([]models.Event, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, `SELECT payload FROM outbox
+		WHERE NOT EXISTS (SELECT 1 FROM outbox_published WHERE consumer = $1 AND event_id = outbox.id)
+		ORDER BY position LIMIT $2`, consumer, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("unable to scan the event: %w", err)
+		}
+		var event models.Event
+		if err = json.Unmarshal([]byte(payload), &event); err != nil {
+			return nil, fmt.Errorf("unable to decode the event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the outbox: %w", err)
+	}
+	return events, nil
+}
+
+// MarkPublished stores a row of the outbox_published table for each of the given events and the given consumer.
+// Unknown events are ignored.
+func (r *Repo) MarkPublished(ctx context.Context, consumer string, eventIDs ...uuid.UUID) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, eventID := range eventIDs {
+			_, err := tx.ExecContext(ctx, `INSERT INTO outbox_published (consumer, event_id)
+				SELECT $1, id FROM outbox WHERE id = $2 ON CONFLICT DO NOTHING`, consumer, eventID)
+			if err != nil {
+				return fmt.Errorf("unable to mark the event as published: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// RemovePublished deletes the events which have been published by every one of the given consumers
+// from the outbox table, with their rows of the outbox_published table.
+func (r *Repo) RemovePublished(ctx context.Context, consumers ...string) error {
+	if len(consumers) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(consumers))
+	args := make([]interface{}, len(consumers))
+	for i, consumer := range consumers {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = consumer
+	}
+	query := fmt.Sprintf(`SELECT event_id FROM outbox_published WHERE consumer IN (%v)
+		GROUP BY event_id HAVING COUNT(*) = %d`, strings.Join(placeholders, ", "), len(consumers))
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("unable to query the published events: %w", err)
+		}
+		var eventIDs []uuid.UUID
+		for rows.Next() {
+			var eventID uuid.UUID
+			if err = rows.Scan(&eventID); err != nil {
+				rows.Close()
+				return fmt.Errorf("unable to scan the published event: %w", err)
+			}
+			eventIDs = append(eventIDs, eventID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("unable to query the published events: %w", err)
+		}
+		for _, eventID := range eventIDs {
+			if _, err = tx.ExecContext(ctx, `DELETE FROM outbox_published WHERE event_id = $1`, eventID); err != nil {
+				return fmt.Errorf("unable to delete the event: %w", err)
+			}
+			if _, err = tx.ExecContext(ctx, `DELETE FROM outbox WHERE id = $1`, eventID); err != nil {
+				return fmt.Errorf("unable to delete the event: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,183 @@
+package db_sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// TutorById returns the tutor for the given UUID.
+// It returns a *models.NotFoundError if there is no such tutor.
+func (r *Repo) TutorById(ctx context.Context, tutorUUID uuid.UUID) (*models.Tutor, error) {
+	var tutor models.Tutor
+	err := r.querier(ctx).QueryRowContext(ctx, `SELECT uuid, name, lastname, faculty, lecturer_of
+		FROM tutors WHERE uuid = $1`, tutorUUID).
+		Scan(&tutor.Uuid, &tutor.Name, &tutor.Lastname, &tutor.Faculty, &tutor.LecturerOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewTutorNotFoundErr(tutorUUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the tutor: %w", err)
+	}
+	return &tutor, nil
+}
+
+// ListTutors returns all tutors ordered by lastname and name.
+func (r *Repo) ListTutors(ctx context.Context) ([]models.Tutor, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, `SELECT uuid, name, lastname, faculty, lecturer_of
+		FROM tutors ORDER BY lastname, name, uuid`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the tutors: %w", err)
+	}
+	defer rows.Close()
+
+	tutors := []models.Tutor{}
+	for rows.Next() {
+		var tutor models.Tutor
+		err = rows.Scan(&tutor.Uuid, &tutor.Name, &tutor.Lastname, &tutor.Faculty, &tutor.LecturerOf)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan the tutor: %w", err)
+		}
+		tutors = append(tutors, tutor)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the tutors: %w", err)
+	}
+	return tutors, nil
+}
+
+// CreateTutor inserts the given tutor.
+// It returns a *models.AlreadyExistsError if a tutor with the same UUID exists.
+func (r *Repo) CreateTutor(ctx context.Context, tutor models.Tutor) error {
+	_, err := r.querier(ctx).ExecContext(ctx, `INSERT INTO tutors (uuid, name, lastname, faculty, lecturer_of)
+		VALUES ($1, $2, $3, $4, $5)`,
+		tutor.Uuid, tutor.Name, tutor.Lastname, tutor.Faculty, tutor.LecturerOf)
+	if r.dialect.duplicate(err) {
+		return models.NewAlreadyExistsErr(models.ResourceTutor, tutor.Uuid)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to insert the tutor: %w", err)
+	}
+	return nil
+}
+
+// UpdateTutor replaces the stored tutor with the given one.
+// It returns an error if the tutor does not exist.
+func (r *Repo) UpdateTutor(ctx context.Context, tutor models.Tutor) error {
+	result, err := r.querier(ctx).ExecContext(ctx, `UPDATE tutors
+		SET name = $2, lastname = $3, faculty = $4, lecturer_of = $5
+		WHERE uuid = $1`,
+		tutor.Uuid, tutor.Name, tutor.Lastname, tutor.Faculty, tutor.LecturerOf)
+	return checkUpdated(result, err, models.NewTutorNotFoundErr(tutor.Uuid))
+}
+
+// DeleteTutor deletes the tutor for the given UUID.
+// Deleting a tutor which does not exist is a no-op.
+func (r *Repo) DeleteTutor(ctx context.Context, tutorUUID uuid.UUID) error {
+	_, err := r.querier(ctx).ExecContext(ctx, `DELETE FROM tutors WHERE uuid = $1`, tutorUUID)
+	if err != nil {
+		return fmt.Errorf("unable to delete the tutor: %w", err)
+	}
+	return nil
+}
+
+// StudentById returns the student for the given UUID.
+// It returns a *models.NotFoundError if there is no such student.
+func (r *Repo) StudentById(ctx context.Context, studentUUID uuid.UUID) (*models.Student, error) {
+	var student models.Student
+	err := r.querier(ctx).QueryRowContext(ctx, `SELECT uuid, name, lastname, faculty
+		FROM students WHERE uuid = $1`, studentUUID).
+		Scan(&student.Uuid, &student.Name, &student.Lastname, &student.Faculty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewStudentNotFoundErr(studentUUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the student: %w", err)
+	}
+	return &student, nil
+}
+
+// ListStudents returns all students ordered by lastname and name.
+func (r *Repo) ListStudents(ctx context.Context) ([]models.Student, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, `SELECT uuid, name, lastname, faculty
+		FROM students ORDER BY lastname, name, uuid`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the students: %w", err)
+	}
+	defer rows.Close()
+
+	students := []models.Student{}
+	for rows.Next() {
+		var student models.Student
+		err = rows.Scan(&student.Uuid, &student.Name, &student.Lastname, &student.Faculty)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan the student: %w", err)
+		}
+		students = append(students, student)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the students: %w", err)
+	}
+	return students, nil
+}
+
+// CreateStudent inserts the given student.
+// It returns a *models.AlreadyExistsError if a student with the same UUID exists.
+func (r *Repo) CreateStudent(ctx context.Context, student models.Student) error {
+	_, err := r.querier(ctx).ExecContext(ctx, `INSERT INTO students (uuid, name, lastname, faculty)
+		VALUES ($1, $2, $3, $4)`,
+		student.Uuid, student.Name, student.Lastname, student.Faculty)
+	if r.dialect.duplicate(err) {
+		return models.NewAlreadyExistsErr(models.ResourceStudent, student.Uuid)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to insert the student: %w", err)
+	}
+	return nil
+}
+
+// UpdateStudent replaces the stored student with the given one.
+// It returns an error if the student does not exist.
+func (r *Repo) UpdateStudent(ctx context.Context, student models.Student) error {
+	result, err := r.querier(ctx).ExecContext(ctx, `UPDATE students
+		SET name = $2, lastname = $3, faculty = $4
+		WHERE uuid = $1`,
+		student.Uuid, student.Name, student.Lastname, student.Faculty)
+	return checkUpdated(result, err, models.NewStudentNotFoundErr(student.Uuid))
+}
+
+// DeleteStudent deletes the student for the given UUID.
+// Deleting a student who does not exist is a no-op.
+// The foreign keys of the enrollments and the waitlist prevent deleting a student who is registered to
+// or waitlisted for a course, which is reported with an error matching models.ErrConstraint.
+func (r *Repo) DeleteStudent(ctx context.Context, studentUUID uuid.UUID) error {
+	_, err := r.querier(ctx).ExecContext(ctx, `DELETE FROM students WHERE uuid = $1`, studentUUID)
+	if r.dialect.referenced(err) {
+		return fmt.Errorf("unable to delete the student in courses: %w", models.ErrConstraint)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete the student: %w", err)
+	}
+	return nil
+}
+
+// checkUpdated returns an error if the given UPDATE failed, and notFoundErr if it did not match any row.
+func checkUpdated(result sql.Result, err error, notFoundErr *models.NotFoundError) error {
+	resource := strings.ToLower(notFoundErr.Resource)
+	if err != nil {
+		return fmt.Errorf("unable to update the %v: %w", resource, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to update the %v: %w", resource, err)
+	}
+	if affected == 0 {
+		return notFoundErr
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package db_sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/tomasdembelli/course-manager/migrations"
+	"github.com/tomasdembelli/course-manager/models"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	courseColumns = `c.uuid, c.name, c.tutor_uuid, c.max_students, c.version,
+		c.created_at, c.created_by, c.updated_at, c.updated_by`
+	// enrollmentsColumn is the number of students enrolled to the course c.
+	enrollmentsColumn = `(SELECT COUNT(*) FROM enrollments e WHERE e.course_uuid = c.uuid)`
+	orderByName       = ` ORDER BY c.name, c.uuid`
+	maxTxAttempts     = 3
+)
+
+// txKey is the context key of the transaction of a Repo.WithTx call.
+type txKey struct {
+	repo *Repo
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Repo is a services.Repo persisting courses, tutors and students into normalized SQL tables.
+// Courses reference their tutor, and the students registered to or waitlisted for a course
+// are stored as rows of the enrollments and waitlist tables.
+type Repo struct {
+	db       *sql.DB
+	dialect  Dialect
+	migrator *migrations.Migrator
+}
+
+// NewRepo returns a Repo using the given database handle speaking the given Dialect.
+func NewRepo(db *sql.DB, dialect Dialect) (*Repo, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	migrator, err := migrations.NewMigrator(db, dialect.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{
+		db:       db,
+		dialect:  dialect,
+		migrator: migrator,
+	}, nil
+}
+
+// OpenPostgres connects to the PostgreSQL database at the given DSN and returns a Repo using it.
+func OpenPostgres(ctx context.Context, dsn string) (*Repo, error) {
+	db, err := sql.Open(Postgres.Name, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the database: %w", err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("unable to connect to the database: %w", err)
+	}
+	repo, err := NewRepo(db, Postgres)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// OpenSQLite opens the SQLite database file at the given path, creating it if it does not exist,
+// and returns a Repo using it.
+func OpenSQLite(ctx context.Context, path string) (*Repo, error) {
+	// Foreign keys are enforced per connection, immediate transactions
+	// serialize the writers instead of failing them on commit, and times are written in a sortable format.
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite"
+	db, err := sql.Open(SQLite.Name, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the database: %w", err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("unable to open the database: %w", err)
+	}
+	repo, err := NewRepo(db, SQLite)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Dialect returns the Dialect of the Repo.
+func (r *Repo) Dialect() Dialect {
+	return r.dialect
+}
+
+// Migrator returns the migrations.Migrator managing the schema of the Repo.
+func (r *Repo) Migrator() *migrations.Migrator {
+	return r.migrator
+}
+
+// Close closes the underlying database handle.
+func (r *Repo) Close() error {
+	return r.db.Close()
+}
+
+// WithTx runs fn within a database transaction, which is committed if fn returns nil and rolled back otherwise.
+// The Repo calls made with the context given to fn are part of the transaction.
+// On PostgreSQL, transactions failing to serialize with concurrent ones are retried.
+func (r *Repo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{r}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = r.beginTx(ctx, &sql.TxOptions{Isolation: r.dialect.txIsolation}, func(tx *sql.Tx) error {
+			return fn(context.WithValue(ctx, txKey{r}, tx))
+		})
+		if err == nil || !r.dialect.retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// ById returns the course for the given UUID.
+// It returns a *models.NotFoundError if there is no such course.
+func (r *Repo) ById(ctx context.Context, courseUUID uuid.UUID) (*models.Course, error) {
+	courses, err := r.queryCourses(ctx, `WHERE c.uuid = $1`, courseUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(courses) == 0 {
+		return nil, models.NewCourseNotFoundErr(courseUUID)
+	}
+	return &courses[0], nil
+}
+
+// ByTutor returns the courses facilitated by the given tutor.
+func (r *Repo) ByTutor(ctx context.Context, tutorUUID uuid.UUID) ([]models.Course, error) {
+	return r.queryCourses(ctx, `WHERE c.tutor_uuid = $1`+orderByName, tutorUUID)
+}
+
+// ByStudent returns the courses the given student has registered to.
+func (r *Repo) ByStudent(ctx context.Context, studentUUID uuid.UUID) ([]models.Course, error) {
+	return r.queryCourses(ctx,
+		`WHERE c.uuid IN (SELECT e.course_uuid FROM enrollments e WHERE e.student_uuid = $1)`+orderByName, studentUUID)
+}
+
+// CountStudentCourses returns the number of courses the given student is registered to or waitlisted for.
+func (r *Repo) CountStudentCourses(ctx context.Context, studentUUID uuid.UUID) (int, error) {
+	var count int
+	err := r.querier(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM (
+			SELECT e.course_uuid FROM enrollments e WHERE e.student_uuid = $1
+			UNION
+			SELECT w.course_uuid FROM waitlist w WHERE w.student_uuid = $1
+		) student_courses`, studentUUID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count the courses of the student: %w", err)
+	}
+	return count, nil
+}
+
+// List returns the page of the courses selected by the given query.
+// The pages are read with keyset pagination, resuming after the course of the cursor in the order of the query.
+func (r *Repo) List(ctx context.Context, query models.CourseQuery) (*models.CoursePage, error) {
+	after, err := query.After()
+	if err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := []string{`1 = 1`}
+	if query.TutorUUID != uuid.Nil {
+		where = append(where, `c.tutor_uuid = `+arg(query.TutorUUID))
+	}
+	if query.Faculty != "" {
+		where = append(where, `c.tutor_uuid IN (SELECT t.uuid FROM tutors t WHERE t.faculty = `+arg(query.Faculty)+`)`)
+	}
+	if query.NameContains != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(query.NameContains)) + "%"
+		where = append(where, `LOWER(c.name) LIKE `+arg(pattern)+` ESCAPE '\'`)
+	}
+	if query.HasFreeSeats {
+		where = append(where, enrollmentsColumn+` < CASE WHEN c.max_students > 0 THEN c.max_students ELSE `+
+			arg(query.DefaultCapacity)+` END`)
+	}
+
+	key := `c.name`
+	switch query.Sort {
+	case models.SortByCreatedAt:
+		key = `c.created_at`
+	case models.SortByEnrollments:
+		key = enrollmentsColumn
+	}
+	direction, comparison := `ASC`, `>`
+	if query.Descending {
+		direction, comparison = `DESC`, `<`
+	}
+	if after != nil {
+		var afterKey string
+		switch query.Sort {
+		case models.SortByCreatedAt:
+			afterKey = arg(after.CreatedAt.UTC())
+		case models.SortByEnrollments:
+			afterKey = arg(after.Enrollments)
+		default:
+			afterKey = arg(after.Name)
+		}
+		where = append(where, fmt.Sprintf(`(%[1]v %[2]v %[3]v OR (%[1]v = %[3]v AND c.uuid %[2]v %[4]v))`,
+			key, comparison, afterKey, arg(after.Uuid)))
+	}
+	selection := `WHERE ` + strings.Join(where, ` AND `) +
+		fmt.Sprintf(` ORDER BY %[1]v %[2]v, c.uuid %[2]v`, key, direction)
+	if query.Limit > 0 {
+		// One more course tells whether there is a next page.
+		selection += ` LIMIT ` + arg(query.Limit+1)
+	}
+
+	courses, err := r.queryCourses(ctx, selection, args...)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.CoursePage{Courses: courses}
+	if query.Limit > 0 && len(courses) > query.Limit {
+		page.Courses = courses[:query.Limit]
+		page.NextCursor = query.NextCursor(page.Courses[query.Limit-1])
+	}
+	return page, nil
+}
+
+// Create inserts the given course at version 1 together with its enrollments and waitlist.
+// It returns a *models.AlreadyExistsError if a course with the same UUID exists.
+func (r *Repo) Create(ctx context.Context, course models.Course) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO courses
+			(uuid, name, tutor_uuid, max_students, version, created_at, created_by, updated_at, updated_by)
+			VALUES ($1, $2, $3, $4, 1, $5, $6, $7, $8)`,
+			course.Uuid, course.Name, nullUUID(course.TutorUUID), course.MaxStudents,
+			course.CreatedAt.UTC(), course.CreatedBy, course.UpdatedAt.UTC(), course.UpdatedBy)
+		if r.dialect.duplicate(err) {
+			return models.NewAlreadyExistsErr(models.ResourceCourse, course.Uuid)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to insert the course: %w", err)
+		}
+		if err = insertEnrollments(ctx, tx, course); err != nil {
+			return err
+		}
+		return insertWaitlist(ctx, tx, course)
+	})
+}
+
+// Update replaces the stored course, its enrollments and its waitlist with the given ones, and increments its version.
+// The creation time and creator of the stored course are kept.
+// It returns a *models.VersionConflictErr if the stored course is not at the version of the given one,
+// and a *models.NotFoundError if the course does not exist.
+func (r *Repo) Update(ctx context.Context, course models.Course) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE courses SET name = $2, tutor_uuid = $3, max_students = $4,
+			version = version + 1, updated_at = $6, updated_by = $7
+			WHERE uuid = $1 AND version = $5`,
+			course.Uuid, course.Name, nullUUID(course.TutorUUID), course.MaxStudents, course.Version,
+			course.UpdatedAt.UTC(), course.UpdatedBy)
+		if err != nil {
+			return fmt.Errorf("unable to update the course: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("unable to update the course: %w", err)
+		}
+		if affected == 0 {
+			var version int
+			err = tx.QueryRowContext(ctx, `SELECT version FROM courses WHERE uuid = $1`, course.Uuid).Scan(&version)
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.NewCourseNotFoundErr(course.Uuid)
+			}
+			if err != nil {
+				return fmt.Errorf("unable to update the course: %w", err)
+			}
+			return models.NewVersionConflictErr(course.Uuid, course.Version, version)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM enrollments WHERE course_uuid = $1`, course.Uuid)
+		if err != nil {
+			return fmt.Errorf("unable to delete the enrollments: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM waitlist WHERE course_uuid = $1`, course.Uuid)
+		if err != nil {
+			return fmt.Errorf("unable to delete the waitlist: %w", err)
+		}
+		if err = insertEnrollments(ctx, tx, course); err != nil {
+			return err
+		}
+		return insertWaitlist(ctx, tx, course)
+	})
+}
+
+// Delete deletes the course for the given UUID together with its enrollments and waitlist.
+// Deleting a course which does not exist is a no-op.
+func (r *Repo) Delete(ctx context.Context, courseUUID uuid.UUID) error {
+	_, err := r.querier(ctx).ExecContext(ctx, `DELETE FROM courses WHERE uuid = $1`, courseUUID)
+	if err != nil {
+		return fmt.Errorf("unable to delete the course: %w", err)
+	}
+	return nil
+}
+
+// querier returns the transaction of the context if there is one, and the database handle otherwise.
+func (r *Repo) querier(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{r}).(*sql.Tx); ok {
+		return tx
+	}
+	return r.db
+}
+
+// inTx runs fn within the transaction of the context if there is one, and within a new transaction otherwise.
+func (r *Repo) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{r}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+	return r.beginTx(ctx, nil, fn)
+}
+
+func (r *Repo) beginTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("unable to begin the transaction: %w", err)
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit the transaction: %w", err)
+	}
+	return nil
+}
+
+// queryCourses returns the courses c selected by the given clauses, e.g. WHERE and ORDER BY,
+// with their enrollments and waitlist.
+func (r *Repo) queryCourses(ctx context.Context, selection string, args ...interface{}) ([]models.Course, error) {
+	q := r.querier(ctx)
+	rows, err := q.QueryContext(ctx, `SELECT `+courseColumns+`
+		FROM courses c
+		`+selection, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the courses: %w", err)
+	}
+	defer rows.Close()
+
+	var courses []models.Course
+	indexByUUID := make(map[uuid.UUID]int)
+	for rows.Next() {
+		course, err := scanCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		indexByUUID[course.Uuid] = len(courses)
+		courses = append(courses, course)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the courses: %w", err)
+	}
+	if len(courses) == 0 {
+		return courses, nil
+	}
+
+	studentRows, err := q.QueryContext(ctx, `SELECT e.course_uuid, e.student_uuid, e.enrolled_at
+		FROM enrollments e
+		WHERE e.course_uuid IN (SELECT c.uuid FROM courses c `+selection+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the enrollments: %w", err)
+	}
+	defer studentRows.Close()
+
+	for studentRows.Next() {
+		var courseUUID, studentUUID uuid.UUID
+		var enrolledAt time.Time
+		if err = studentRows.Scan(&courseUUID, &studentUUID, &enrolledAt); err != nil {
+			return nil, fmt.Errorf("unable to scan the enrollment: %w", err)
+		}
+		if i, ok := indexByUUID[courseUUID]; ok {
+			courses[i].Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID, EnrolledAt: enrolledAt.UTC()}
+		}
+	}
+	if err = studentRows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the enrollments: %w", err)
+	}
+
+	waitlistRows, err := q.QueryContext(ctx, `SELECT w.course_uuid, w.student_uuid
+		FROM waitlist w
+		WHERE w.course_uuid IN (SELECT c.uuid FROM courses c `+selection+`)
+		ORDER BY w.course_uuid, w.position`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the waitlist: %w", err)
+	}
+	defer waitlistRows.Close()
+
+	for waitlistRows.Next() {
+		var courseUUID, studentUUID uuid.UUID
+		if err = waitlistRows.Scan(&courseUUID, &studentUUID); err != nil {
+			return nil, fmt.Errorf("unable to scan the waitlisted student: %w", err)
+		}
+		if i, ok := indexByUUID[courseUUID]; ok {
+			courses[i].Waitlist = append(courses[i].Waitlist, studentUUID)
+		}
+	}
+	if err = waitlistRows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the waitlist: %w", err)
+	}
+	return courses, nil
+}
+
+func scanCourse(rows *sql.Rows) (models.Course, error) {
+	var course models.Course
+	var tutorUUID uuid.NullUUID
+	err := rows.Scan(&course.Uuid, &course.Name, &tutorUUID, &course.MaxStudents, &course.Version,
+		&course.CreatedAt, &course.CreatedBy, &course.UpdatedAt, &course.UpdatedBy)
+	if err != nil {
+		return models.Course{}, fmt.Errorf("unable to scan the course: %w", err)
+	}
+	course.TutorUUID = tutorUUID.UUID
+	course.CreatedAt = course.CreatedAt.UTC()
+	course.UpdatedAt = course.UpdatedAt.UTC()
+	course.Students = make(map[uuid.UUID]models.Enrollment)
+	return course, nil
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, whose escape character is a backslash.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// nullUUID returns a NULL UUID for uuid.Nil, so that courses without a tutor do not reference one.
+func nullUUID(u uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: u, Valid: u != uuid.Nil}
+}
+
+// insertEnrollments enrolls the students of the given course to it.
+func insertEnrollments(ctx context.Context, q querier, course models.Course) error {
+	for studentUUID, enrollment := range course.Students {
+		_, err := q.ExecContext(ctx, `INSERT INTO enrollments (course_uuid, student_uuid, enrolled_at) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, course.Uuid, studentUUID, enrollment.EnrolledAt.UTC())
+		if err != nil {
+			return fmt.Errorf("unable to store the enrollment: %w", err)
+		}
+	}
+	return nil
+}
+
+// insertWaitlist queues the waitlisted students of the given course in order.
+func insertWaitlist(ctx context.Context, q querier, course models.Course) error {
+	for i, studentUUID := range course.Waitlist {
+		_, err := q.ExecContext(ctx, `INSERT INTO waitlist (course_uuid, student_uuid, position) VALUES ($1, $2, $3)`,
+			course.Uuid, studentUUID, i+1)
+		if err != nil {
+			return fmt.Errorf("unable to store the waitlisted student: %w", err)
+		}
+	}
+	return nil
+}
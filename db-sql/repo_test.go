@@ -0,0 +1,336 @@
+package db_sql
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/apikeys/apikeystest"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/repotest"
+	"github.com/tomasdembelli/course-manager/services"
+	"github.com/tomasdembelli/course-manager/webhooks/webhookstest"
+)
+
+func newSQLiteRepo(t *testing.T) *Repo {
+	repo, err := OpenSQLite(context.TODO(), filepath.Join(t.TempDir(), "course-manager.db"))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = repo.Migrator().Up(context.TODO()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+// newCourse returns a course which has not been created yet, after creating its tutor and students in the given repo.
+func newCourse(t *testing.T, repo *Repo, numberOfStudents int) models.Course {
+	tutor := models.Tutor{
+		User:       models.User{Uuid: uuid.New(), Name: "John", Lastname: "Stone"},
+		Faculty:    "Computer Science",
+		LecturerOf: "Golang",
+	}
+	if err := repo.CreateTutor(context.TODO(), tutor); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course := models.Course{
+		CourseMeta: models.CourseMeta{
+			Uuid:      uuid.New(),
+			Name:      "test course",
+			TutorUUID: tutor.Uuid,
+		},
+		Students: make(map[uuid.UUID]models.Enrollment),
+	}
+	for i := 0; i < numberOfStudents; i++ {
+		student := newStudent(t, repo)
+		course.Students[student.Uuid] = models.Enrollment{StudentUUID: student.Uuid}
+	}
+	return course
+}
+
+// newStudent creates a student in the given repo.
+func newStudent(t *testing.T, repo *Repo) models.Student {
+	student := models.Student{
+		User:    models.User{Uuid: uuid.New(), Name: "Alice", Lastname: "Smith"},
+		Faculty: "Computer Science",
+	}
+	if err := repo.CreateStudent(context.TODO(), student); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	return student
+}
+
+// testRepo exercises a round-trip of the given Repo.
+func testRepo(t *testing.T, repo *Repo) {
+	ctx := context.TODO()
+
+	course := newCourse(t, repo, 3)
+	if err := repo.Create(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	t.Cleanup(func() { _ = repo.Delete(ctx, course.Uuid) })
+	course.Version = 1
+
+	got, err := repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, course) {
+		t.Errorf("ById() got = %v, want %v", *got, course)
+	}
+
+	byTutor, err := repo.ByTutor(ctx, course.TutorUUID)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(byTutor) != 1 || byTutor[0].Uuid != course.Uuid {
+		t.Errorf("ByTutor() got = %v, want %v", byTutor, course)
+	}
+
+	var studentUUID uuid.UUID
+	for studentUUID = range course.Students {
+		break
+	}
+	delete(course.Students, studentUUID)
+	course.Waitlist = []uuid.UUID{newStudent(t, repo).Uuid, studentUUID}
+	course.Name = "renamed course"
+	course.MaxStudents = 10
+	if err = repo.Update(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course.Version = 2
+	byStudent, err := repo.ByStudent(ctx, studentUUID)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(byStudent) != 0 {
+		t.Errorf("ByStudent() got = %v, want no courses", byStudent)
+	}
+	got, err = repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, course) {
+		t.Errorf("ById() got = %v, want %v", *got, course)
+	}
+
+	stale := course
+	stale.Version = 1
+	var conflictErr *models.VersionConflictErr
+	if err = repo.Update(ctx, stale); !errors.As(err, &conflictErr) {
+		t.Errorf("Update() error = %v, want a version conflict", err)
+	} else if conflictErr.Actual != 2 {
+		t.Errorf("Update() conflict actual version = %d, want 2", conflictErr.Actual)
+	}
+
+	if err = repo.Delete(ctx, course.Uuid); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = repo.ById(ctx, course.Uuid); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("failed to delete course %v", course.Uuid)
+	}
+	if err = repo.Update(ctx, course); err == nil {
+		t.Errorf("expected error updating a deleted course, but none raised")
+	}
+}
+
+// testPeople exercises a round-trip of the tutors and students of the given Repo.
+func testPeople(t *testing.T, repo *Repo) {
+	ctx := context.TODO()
+
+	tutor := models.Tutor{User: models.User{Uuid: uuid.New(), Name: "John", Lastname: "Stone"}, LecturerOf: "Golang"}
+	if err := repo.CreateTutor(ctx, tutor); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := repo.CreateTutor(ctx, tutor); err == nil {
+		t.Errorf("expected error creating a tutor twice, but none raised")
+	}
+	tutor.Faculty = "Computer Science"
+	if err := repo.UpdateTutor(ctx, tutor); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	gotTutor, err := repo.TutorById(ctx, tutor.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*gotTutor, tutor) {
+		t.Errorf("TutorById() got = %v, want %v", *gotTutor, tutor)
+	}
+	tutors, err := repo.ListTutors(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(tutors) == 0 {
+		t.Errorf("ListTutors() got no tutors")
+	}
+	if err = repo.DeleteTutor(ctx, tutor.Uuid); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if gotTutor, err = repo.TutorById(ctx, tutor.Uuid); !errors.Is(err, models.NewTutorNotFoundErr(tutor.Uuid)) {
+		t.Errorf("TutorById() got = %v, %v, want a not found error", gotTutor, err)
+	}
+	if err = repo.UpdateTutor(ctx, tutor); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("UpdateTutor() error = %v, want a not found error", err)
+	}
+
+	student := models.Student{User: models.User{Uuid: uuid.New(), Name: "Alice", Lastname: "Smith"}}
+	if err = repo.CreateStudent(ctx, student); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	student.Faculty = "Physics"
+	if err = repo.UpdateStudent(ctx, student); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	gotStudent, err := repo.StudentById(ctx, student.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*gotStudent, student) {
+		t.Errorf("StudentById() got = %v, want %v", *gotStudent, student)
+	}
+	students, err := repo.ListStudents(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(students) == 0 {
+		t.Errorf("ListStudents() got no students")
+	}
+	if err = repo.DeleteStudent(ctx, student.Uuid); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if gotStudent, err = repo.StudentById(ctx, student.Uuid); !errors.Is(err, models.NewStudentNotFoundErr(student.Uuid)) {
+		t.Errorf("StudentById() got = %v, %v, want a not found error", gotStudent, err)
+	}
+}
+
+func TestRepo_SQLite(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	testRepo(t, repo)
+	testPeople(t, repo)
+	repotest.Run(t, func() services.Repo {
+		return newSQLiteRepo(t)
+	})
+	webhookstest.Run(t, func() services.WebhookStore {
+		return newSQLiteRepo(t)
+	})
+	apikeystest.Run(t, func() services.APIKeyStore {
+		return newSQLiteRepo(t)
+	})
+}
+
+func TestOpenSQLite_existingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "course-manager.db")
+	repo, err := OpenSQLite(context.TODO(), path)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = repo.Migrator().Up(context.TODO()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course := newCourse(t, repo, 2)
+	if err = repo.Create(context.TODO(), course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course.Version = 1
+	if err = repo.Close(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	repo, err = OpenSQLite(context.TODO(), path)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	defer repo.Close()
+	got, err := repo.ById(context.TODO(), course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, course) {
+		t.Errorf("ById() got = %v, want %v", *got, course)
+	}
+}
+
+func TestRepo_WithTx(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.TODO()
+	course := newCourse(t, repo, 1)
+
+	errRollback := errors.New("rollback")
+	err := repo.WithTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, course); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errRollback)
+	}
+	if got, err := repo.ById(ctx, course.Uuid); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected the course creation to be rolled back, got %v", got)
+	}
+
+	err = repo.WithTx(ctx, func(ctx context.Context) error {
+		return repo.Create(ctx, course)
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course.Version = 1
+	got, err := repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, course) {
+		t.Errorf("ById() got = %v, want %v", *got, course)
+	}
+}
+
+func TestRepo_WithTx_concurrentReadModifyWrite(t *testing.T) {
+	repo := newSQLiteRepo(t)
+	ctx := context.TODO()
+	course := newCourse(t, repo, 0)
+	if err := repo.Create(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	const capacity, registrations = 3, 10
+	var wg sync.WaitGroup
+	for i := 0; i < registrations; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.WithTx(ctx, func(ctx context.Context) error {
+				got, err := repo.ById(ctx, course.Uuid)
+				if err != nil {
+					return err
+				}
+				if len(got.Students) >= capacity {
+					return nil
+				}
+				student := models.Student{User: models.User{Uuid: uuid.New()}}
+				if err = repo.CreateStudent(ctx, student); err != nil {
+					return err
+				}
+				got.Students[student.Uuid] = models.Enrollment{StudentUUID: student.Uuid}
+				return repo.Update(ctx, *got)
+			})
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(got.Students) != capacity {
+		t.Errorf("expected %d students, got %d", capacity, len(got.Students))
+	}
+}
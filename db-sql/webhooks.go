@@ -0,0 +1,215 @@
+package db_sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/webhooks"
+)
+
+// CreateWebhook stores the given webhook as a JSON row of the webhooks table.
+// It returns a *models.AlreadyExistsError if a webhook with the same ID exists.
+func (r *Repo) CreateWebhook(ctx context.Context, webhook models.Webhook) error {
+	payload, err := json.Marshal(webhook)
+	if err != nil {
+		return fmt.Errorf("unable to encode the webhook: %w", err)
+	}
+	_, err = r.querier(ctx).ExecContext(ctx, `INSERT INTO webhooks (id, payload) VALUES ($1, $2)`, webhook.ID, string(payload))
+	if r.dialect.duplicate(err) {
+		return models.NewAlreadyExistsErr(models.ResourceWebhook, webhook.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to store the webhook: %w", err)
+	}
+	return nil
+}
+
+// WebhookByID returns the webhook with the given ID, or a *models.NotFoundError if it does not exist.
+func (r *Repo) WebhookByID(ctx context.Context, webhookID uuid.UUID) (*models.Webhook, error) {
+	var payload string
+	err := r.querier(ctx).QueryRowContext(ctx, `SELECT payload FROM webhooks WHERE id = $1`, webhookID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NewWebhookNotFoundErr(webhookID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the webhook: %w", err)
+	}
+	var webhook models.Webhook
+	if err = json.Unmarshal([]byte(payload), &webhook); err != nil {
+		return nil, fmt.Errorf("unable to decode the webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks returns the webhooks in the order they have been created.
+func (r *Repo) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, `SELECT payload FROM webhooks ORDER BY position`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("unable to scan the webhook: %w", err)
+		}
+		var webhook models.Webhook
+		if err = json.Unmarshal([]byte(payload), &webhook); err != nil {
+			return nil, fmt.Errorf("unable to decode the webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook stores the given webhook. It returns a *models.NotFoundError if the webhook does not exist.
+func (r *Repo) UpdateWebhook(ctx context.Context, webhook models.Webhook) error {
+	payload, err := json.Marshal(webhook)
+	if err != nil {
+		return fmt.Errorf("unable to encode the webhook: %w", err)
+	}
+	result, err := r.querier(ctx).ExecContext(ctx, `UPDATE webhooks SET payload = $1 WHERE id = $2`, string(payload), webhook.ID)
+	if err != nil {
+		return fmt.Errorf("unable to update the webhook: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to update the webhook: %w", err)
+	}
+	if updated == 0 {
+		return models.NewWebhookNotFoundErr(webhook.ID)
+	}
+	return nil
+}
+
+// DeleteWebhook deletes the webhook with the given ID and its deliveries. It is a no-op if the webhook does not exist.
+func (r *Repo) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = $1`, webhookID); err != nil {
+			return fmt.Errorf("unable to delete the deliveries of the webhook: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, webhookID); err != nil {
+			return fmt.Errorf("unable to delete the webhook: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddDeliveries stores the given deliveries as JSON rows of the webhook_deliveries table, ignoring those
+// of an event which is already stored for the same webhook, and those of a webhook which has been deleted.
+// Only the latest webhooks.MaxDeliveries finished deliveries of a webhook are kept.
+func (r *Repo) AddDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, delivery := range deliveries {
+			payload, err := json.Marshal(delivery)
+			if err != nil {
+				return fmt.Errorf("unable to encode the delivery: %w", err)
+			}
+			var exists int
+			err = tx.QueryRowContext(ctx, `SELECT 1 FROM webhooks WHERE id = $1`, delivery.WebhookID).Scan(&exists)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("unable to query the webhook of the delivery: %w", err)
+			}
+			_, err = tx.ExecContext(ctx, `INSERT INTO webhook_deliveries
+				(id, webhook_id, event_id, status, next_attempt_at, payload) VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (webhook_id, event_id) DO NOTHING`,
+				delivery.ID, delivery.WebhookID, delivery.Event.ID, string(delivery.Status), nextAttemptAt(delivery),
+				string(payload))
+			if err != nil {
+				return fmt.Errorf("unable to store the delivery: %w", err)
+			}
+			if err = pruneDeliveries(ctx, tx, delivery.WebhookID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateDelivery stores the given delivery. It is a no-op if the delivery does not exist.
+func (r *Repo) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	payload, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("unable to encode the delivery: %w", err)
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, next_attempt_at = $2, payload = $3
+			WHERE id = $4`, string(delivery.Status), nextAttemptAt(delivery), string(payload), delivery.ID)
+		if err != nil {
+			return fmt.Errorf("unable to update the delivery: %w", err)
+		}
+		return pruneDeliveries(ctx, tx, delivery.WebhookID)
+	})
+}
+
+// DueDeliveries returns up to limit pending deliveries whose NextAttemptAt is not after the given time, earliest first.
+func (r *Repo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return r.queryDeliveries(ctx, `SELECT payload FROM webhook_deliveries
+		WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at, position LIMIT $3`,
+		string(models.DeliveryPending), now.UnixNano(), limit)
+}
+
+// Deliveries returns the latest deliveries of the given webhook in the order they have been stored.
+func (r *Repo) Deliveries(ctx context.Context, webhookID uuid.UUID) ([]models.WebhookDelivery, error) {
+	return r.queryDeliveries(ctx, `SELECT payload FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY position`, webhookID)
+}
+
+// queryDeliveries returns the deliveries decoded from the payloads selected by the given query.
+func (r *Repo) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("unable to scan the delivery: %w", err)
+		}
+		var delivery models.WebhookDelivery
+		if err = json.Unmarshal([]byte(payload), &delivery); err != nil {
+			return nil, fmt.Errorf("unable to decode the delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// pruneDeliveries deletes the oldest finished deliveries of the given webhook beyond webhooks.MaxDeliveries.
+func pruneDeliveries(ctx context.Context, q querier, webhookID uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = $1 AND status <> $2
+		AND position NOT IN (SELECT position FROM webhook_deliveries WHERE webhook_id = $1 AND status <> $2
+			ORDER BY position DESC LIMIT $3)`, webhookID, string(models.DeliveryPending), webhooks.MaxDeliveries)
+	if err != nil {
+		return fmt.Errorf("unable to prune the deliveries: %w", err)
+	}
+	return nil
+}
+
+// nextAttemptAt returns the NextAttemptAt of the given delivery in nanoseconds since the Unix epoch,
+// which sort the same way in every dialect, or nil if it is not set.
+func nextAttemptAt(delivery models.WebhookDelivery) interface{} {
+	if delivery.NextAttemptAt == nil {
+		return nil
+	}
+	return delivery.NextAttemptAt.UnixNano()
+}
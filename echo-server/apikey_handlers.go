@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (a *ApiV1) ListAPIKeys(ec echo.Context) error {
+	apiKeys, err := a.apiKeyManagerSvc.List(ec.Request().Context())
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, apiKeys)
+}
+
+// CreateAPIKey generates an API key, and returns it with its plaintext, which is only returned once.
+func (a *ApiV1) CreateAPIKey(ec echo.Context) error {
+	request := new(CreateAPIKey)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	apiKey, err := a.apiKeyManagerSvc.Create(ec.Request().Context(), request.APIKey)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusCreated, apiKey)
+}
+
+// RevokeAPIKey revokes an API key, which is still listed with the time of its revocation.
+func (a *ApiV1) RevokeAPIKey(ec echo.Context) error {
+	request := new(APIKeyByID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	if _, err := a.apiKeyManagerSvc.Revoke(ec.Request().Context(), request.ID); err != nil {
+		return err
+	}
+	return ec.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+const (
+	headerWWWAuthenticate = "WWW-Authenticate"
+	// HeaderAPIKey is the header of the API keys of the machine clients, accepted instead of a bearer token.
+	HeaderAPIKey = "X-API-Key"
+	bearerPrefix = "Bearer "
+)
+
+// AuthConfig configures the JWT bearer authentication of the API.
+// At least one of HS256Secret and JWKSPath must be set, and so must RolesClaim.
+type AuthConfig struct {
+	// HS256Secret verifies the tokens signed with HS256, unless it is empty.
+	HS256Secret string
+	// JWKSPath is the path of a JSON Web Key Set file whose RSA keys verify the tokens signed with RS256,
+	// by the "kid" header of the tokens, unless it is empty.
+	JWKSPath string
+	// Issuer is required in the "iss" claim of the tokens, unless it is empty.
+	Issuer string
+	// Audience is required in the "aud" claim of the tokens, unless it is empty.
+	Audience string
+	// RolesClaim is the claim of the roles of the caller, a string array or a space separated string, e.g. "roles".
+	RolesClaim string
+	// APIKeys authenticates the requests with an X-API-Key header instead of a bearer token, unless it is nil.
+	APIKeys *services.APIKeyManager
+}
+
+// Authenticator verifies the JWT bearer tokens of the requests.
+type Authenticator struct {
+	hs256Secret []byte
+	// rsaKeys are the RSA public keys of the JWKS, by key ID.
+	rsaKeys    map[string]*rsa.PublicKey
+	issuer     string
+	audience   string
+	rolesClaim string
+	apiKeys    *services.APIKeyManager
+	now        func() time.Time
+}
+
+// NewAuthenticator returns an Authenticator verifying the tokens as configured.
+// It returns an error if no key or roles claim is configured, or if the JWKS file cannot be read.
+func NewAuthenticator(config AuthConfig) (*Authenticator, error) {
+	authenticator := &Authenticator{
+		hs256Secret: []byte(config.HS256Secret),
+		issuer:      config.Issuer,
+		audience:    config.Audience,
+		rolesClaim:  config.RolesClaim,
+		apiKeys:     config.APIKeys,
+		now:         time.Now,
+	}
+	if authenticator.rolesClaim == "" {
+		return nil, errors.New("the claim of the roles of the callers is required")
+	}
+	if config.JWKSPath != "" {
+		data, err := os.ReadFile(config.JWKSPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the JWKS: %w", err)
+		}
+		if authenticator.rsaKeys, err = parseJWKS(data); err != nil {
+			return nil, fmt.Errorf("unable to parse the JWKS %v: %w", config.JWKSPath, err)
+		}
+	}
+	if len(authenticator.hs256Secret) == 0 && len(authenticator.rsaKeys) == 0 {
+		return nil, errors.New("either an HS256 secret or a JWKS is required to verify the tokens")
+	}
+	return authenticator, nil
+}
+
+// Middleware returns the echo middleware authenticating the requests with the bearer token
+// of their Authorization header, or with the API key of their X-API-Key header. The verified subject and roles
+// are put into the context of the request as a services.Principal, and requests without a valid token or key
+// are answered with 401 Unauthorized. Requests which the scopes of their API key do not grant
+// are answered with 403 Forbidden.
+func (a *Authenticator) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			var principal services.Principal
+			if key := ec.Request().Header.Get(HeaderAPIKey); key != "" {
+				if a.apiKeys == nil {
+					return unauthorized(ec, "API keys are not accepted")
+				}
+				apiKey, err := a.apiKeys.Authenticate(ec.Request().Context(), key)
+				if errors.Is(err, services.ErrInvalidAPIKey) {
+					return unauthorized(ec, "the API key is invalid").SetInternal(err)
+				}
+				if err != nil {
+					return err
+				}
+				if !scopesGrant(apiKey.Scopes, ec.Request().Method, ec.Path()) {
+					return echo.NewHTTPError(http.StatusForbidden, "the scopes of the API key do not grant this request")
+				}
+				principal = services.APIKeyPrincipal(*apiKey)
+			} else {
+				authorization := ec.Request().Header.Get(echo.HeaderAuthorization)
+				if !strings.HasPrefix(authorization, bearerPrefix) {
+					return unauthorized(ec, "a bearer token is required")
+				}
+				var err error
+				principal, err = a.Authenticate(strings.TrimSpace(strings.TrimPrefix(authorization, bearerPrefix)))
+				if err != nil {
+					return unauthorized(ec, "the bearer token is invalid").SetInternal(err)
+				}
+			}
+			ctx := services.ContextWithPrincipal(ec.Request().Context(), principal)
+			ec.SetRequest(ec.Request().WithContext(ctx))
+			return next(ec)
+		}
+	}
+}
+
+// Authenticate verifies the given token, and returns the principal of its subject and roles.
+func (a *Authenticator) Authenticate(token string) (services.Principal, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}}
+	if _, err := parser.ParseWithClaims(token, claims, a.key); err != nil {
+		return services.Principal{}, err
+	}
+	now := a.now().Unix()
+	if !claims.VerifyExpiresAt(now, true) {
+		return services.Principal{}, errors.New("the token has expired or has no expiration time")
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return services.Principal{}, fmt.Errorf("the token is not issued by %v", a.issuer)
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return services.Principal{}, fmt.Errorf("the token is not intended for %v", a.audience)
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return services.Principal{}, errors.New("the token has no subject")
+	}
+	roles, err := parseRoles(claims[a.rolesClaim])
+	if err != nil {
+		return services.Principal{}, fmt.Errorf("invalid %v claim: %w", a.rolesClaim, err)
+	}
+	return services.Principal{Subject: subject, Roles: roles}, nil
+}
+
+// key returns the key verifying the signature of the given token.
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method {
+	case jwt.SigningMethodHS256:
+		if len(a.hs256Secret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return a.hs256Secret, nil
+	case jwt.SigningMethodRS256:
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		if len(a.rsaKeys) == 1 && kid == "" {
+			for _, key := range a.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+}
+
+// unauthorized returns the error answering a request with 401 Unauthorized and a bearer challenge.
+func unauthorized(ec echo.Context, message string) *echo.HTTPError {
+	ec.Response().Header().Set(headerWWWAuthenticate, `Bearer realm="course-manager"`)
+	return echo.NewHTTPError(http.StatusUnauthorized, message)
+}
+
+// scopesGrant reports whether the given scopes of an API key grant a request of the given method
+// to the route of the given path: models.ScopeReadOnly grants the safe methods, models.ScopeEnrollmentWrite
+// additionally grants the enrollmentRoutes, and models.ScopeAdmin grants every request.
+func scopesGrant(scopes []models.Scope, method, path string) bool {
+	safe := method == http.MethodGet || method == http.MethodHead
+	for _, scope := range scopes {
+		switch scope {
+		case models.ScopeAdmin:
+			return true
+		case models.ScopeReadOnly:
+			if safe {
+				return true
+			}
+		case models.ScopeEnrollmentWrite:
+			if safe || enrollmentRoutes[strings.TrimPrefix(path, pathV1)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseRoles returns the roles of the given claim, a string array or a space separated string.
+func parseRoles(claim interface{}) ([]services.Role, error) {
+	var roles []services.Role
+	switch value := claim.(type) {
+	case nil:
+	case string:
+		for _, role := range strings.Fields(value) {
+			roles = append(roles, services.Role(role))
+		}
+	case []interface{}:
+		for _, role := range value {
+			name, ok := role.(string)
+			if !ok {
+				return nil, fmt.Errorf("the role %v is not a string", role)
+			}
+			roles = append(roles, services.Role(name))
+		}
+	default:
+		return nil, fmt.Errorf("the roles must be a string array, got %T", claim)
+	}
+	return roles, nil
+}
+
+// jsonWebKey is a key of a JSON Web Key Set (RFC 7517). Only the members of RSA public keys are decoded.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// parseJWKS returns the RSA signature keys of the given JSON Web Key Set, by key ID.
+// It returns an error if the set has none.
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range jwks.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") || (key.Alg != "" && key.Alg != jwt.SigningMethodRS256.Alg()) {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of the key %q: %w", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of the key %q: %w", key.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent of the key %q", key.Kid)
+		}
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("the JWKS has no RSA signature keys")
+	}
+	return keys, nil
+}
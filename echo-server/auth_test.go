@@ -0,0 +1,416 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/apikeys"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+const testSecret = "test-secret"
+
+// writeJWKS writes a JSON Web Key Set of the public key of the given RSA key into a temporary file,
+// and returns its path.
+func writeJWKS(t *testing.T, kid string, key *rsa.PrivateKey) string {
+	t.Helper()
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "EC", "kid": "ignored", "crv": "P-256"},
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	return path
+}
+
+// mint returns a token of the given claims signed with the given method and key, with the given key ID if any.
+func mint(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	return signed
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	noRSAKeys := filepath.Join(t.TempDir(), "empty.json")
+	if err = os.WriteFile(noRSAKeys, []byte(`{"keys": []}`), 0o600); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	tests := []struct {
+		name    string
+		config  AuthConfig
+		wantErr bool
+	}{
+		{name: "no keys", config: AuthConfig{Issuer: "issuer", RolesClaim: "roles"}, wantErr: true},
+		{name: "no roles claim", config: AuthConfig{HS256Secret: testSecret}, wantErr: true},
+		{name: "missing JWKS", config: AuthConfig{JWKSPath: filepath.Join(t.TempDir(), "missing.json"), RolesClaim: "roles"}, wantErr: true},
+		{name: "JWKS without RSA keys", config: AuthConfig{JWKSPath: noRSAKeys, RolesClaim: "roles"}, wantErr: true},
+		{name: "HS256", config: AuthConfig{HS256Secret: testSecret, RolesClaim: "roles"}},
+		{name: "JWKS", config: AuthConfig{JWKSPath: writeJWKS(t, "key-1", key), RolesClaim: "roles"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAuthenticator(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAuthenticator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	authenticator, err := NewAuthenticator(AuthConfig{
+		HS256Secret: testSecret,
+		JWKSPath:    writeJWKS(t, "key-1", key),
+		Issuer:      "https://issuer.example.com",
+		Audience:    "course-manager",
+		RolesClaim:  "roles",
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	now := time.Now()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"sub":   "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+			"roles": []string{"tutor", "admin"},
+			"iss":   "https://issuer.example.com",
+			"aud":   []string{"course-manager", "reporting"},
+			"exp":   now.Add(time.Hour).Unix(),
+		}
+		for name, value := range overrides {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+	wantPrincipal := &services.Principal{
+		Subject: "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+		Roles:   []services.Role{services.RoleTutor, services.RoleAdmin},
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantPrincipal *services.Principal
+	}{
+		{
+			name:          "HS256",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(nil)),
+			wantPrincipal: wantPrincipal,
+		},
+		{
+			name:          "RS256",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodRS256, key, "key-1", claims(nil)),
+			wantPrincipal: wantPrincipal,
+		},
+		{
+			name:          "RS256 without key ID",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodRS256, key, "", claims(nil)),
+			wantPrincipal: wantPrincipal,
+		},
+		{
+			name:          "space separated roles",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"roles": "tutor admin"})),
+			wantPrincipal: wantPrincipal,
+		},
+		{
+			name:          "no roles",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"roles": nil})),
+			wantPrincipal: &services.Principal{Subject: wantPrincipal.Subject},
+		},
+		{name: "no token"},
+		{name: "basic credentials", authorization: "Basic YWxpY2U6c2VjcmV0"},
+		{name: "malformed token", authorization: "Bearer not.a.token"},
+		{
+			name:          "wrong secret",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte("wrong"), "", claims(nil)),
+		},
+		{
+			name:          "unknown RSA key",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodRS256, otherKey, "key-1", claims(nil)),
+		},
+		{
+			name:          "unknown key ID",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodRS256, key, "key-2", claims(nil)),
+		},
+		{
+			name:          "unsupported algorithm",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS512, []byte(testSecret), "", claims(nil)),
+		},
+		{
+			name:          "unsigned",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil)),
+		},
+		{
+			name:          "expired",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})),
+		},
+		{
+			name:          "no expiration",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": nil})),
+		},
+		{
+			name:          "not yet valid",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()})),
+		},
+		{
+			name:          "other issuer",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"iss": "https://other.example.com"})),
+		},
+		{
+			name:          "other audience",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"aud": "reporting"})),
+		},
+		{
+			name:          "no subject",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"sub": nil})),
+		},
+		{
+			name:          "invalid roles",
+			authorization: "Bearer " + mint(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"roles": 1})),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler
+			var gotPrincipal *services.Principal
+			var gotActor string
+			e.GET("/", func(ec echo.Context) error {
+				if principal, ok := services.PrincipalFromContext(ec.Request().Context()); ok {
+					gotPrincipal = &principal
+				}
+				gotActor = services.ActorFromContext(ec.Request().Context())
+				return ec.NoContent(http.StatusNoContent)
+			}, authenticator.Middleware())
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				request.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, request)
+
+			if tt.wantPrincipal == nil {
+				if recorder.Code != http.StatusUnauthorized || recorder.Header().Get(headerWWWAuthenticate) == "" {
+					t.Errorf("got the status %v and the headers %v, want 401 with a bearer challenge", recorder.Code, recorder.Header())
+				}
+				if gotPrincipal != nil {
+					t.Errorf("the handler has been called with %v", gotPrincipal)
+				}
+				return
+			}
+			if recorder.Code != http.StatusNoContent {
+				t.Fatalf("got the status %v, want %v: %v", recorder.Code, http.StatusNoContent, recorder.Body)
+			}
+			if !reflect.DeepEqual(gotPrincipal, tt.wantPrincipal) || gotActor != tt.wantPrincipal.Subject {
+				t.Errorf("got the principal %v and the actor %q, want %v", gotPrincipal, gotActor, tt.wantPrincipal)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Middleware_apiKeys(t *testing.T) {
+	ctx := context.TODO()
+	apiKeyManager, err := services.NewAPIKeyManager(apikeys.NewMemoryStore(), nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	keys := make(map[models.Scope]*models.APIKey)
+	for _, scope := range models.Scopes {
+		if keys[scope], err = apiKeyManager.Create(ctx, models.APIKeyMeta{Name: string(scope), Scopes: []models.Scope{scope}}); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	revoked, err := apiKeyManager.Create(ctx, models.APIKeyMeta{Name: "revoked", Scopes: []models.Scope{models.ScopeAdmin}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = apiKeyManager.Revoke(ctx, revoked.ID); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	withAPIKeys, err := NewAuthenticator(AuthConfig{HS256Secret: testSecret, RolesClaim: "roles", APIKeys: &apiKeyManager})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	withoutAPIKeys, err := NewAuthenticator(AuthConfig{HS256Secret: testSecret, RolesClaim: "roles"})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	courseUUID := uuid.New()
+	tests := []struct {
+		name          string
+		authenticator *Authenticator
+		key           string
+		method        string
+		path          string
+		wantStatus    int
+		wantRoles     []services.Role
+	}{
+		{
+			name:          "read-only gets",
+			authenticator: withAPIKeys,
+			key:           keys[models.ScopeReadOnly].Key,
+			method:        http.MethodGet,
+			path:          "/v1/getCourse/" + courseUUID.String(),
+			wantStatus:    http.StatusNoContent,
+		},
+		{
+			name:          "read-only registers",
+			authenticator: withAPIKeys,
+			key:           keys[models.ScopeReadOnly].Key,
+			method:        http.MethodPut,
+			path:          "/v1/registerStudent/" + courseUUID.String(),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "enrollment-write registers",
+			authenticator: withAPIKeys,
+			key:           keys[models.ScopeEnrollmentWrite].Key,
+			method:        http.MethodPut,
+			path:          "/v1/registerStudent/" + courseUUID.String(),
+			wantStatus:    http.StatusNoContent,
+			wantRoles:     []services.Role{services.RoleRegistrar},
+		},
+		{
+			name:          "enrollment-write gets",
+			authenticator: withAPIKeys,
+			key:           keys[models.ScopeEnrollmentWrite].Key,
+			method:        http.MethodGet,
+			path:          "/v1/getCourse/" + courseUUID.String(),
+			wantStatus:    http.StatusNoContent,
+			wantRoles:     []services.Role{services.RoleRegistrar},
+		},
+		{
+			name:          "enrollment-write deletes",
+			authenticator: withAPIKeys,
+			key:           keys[models.ScopeEnrollmentWrite].Key,
+			method:        http.MethodDelete,
+			path:          "/v1/deleteCourse/" + courseUUID.String(),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "admin deletes",
+			authenticator: withAPIKeys,
+			key:           keys[models.ScopeAdmin].Key,
+			method:        http.MethodDelete,
+			path:          "/v1/deleteCourse/" + courseUUID.String(),
+			wantStatus:    http.StatusNoContent,
+			wantRoles:     []services.Role{services.RoleAdmin},
+		},
+		{
+			name:          "revoked",
+			authenticator: withAPIKeys,
+			key:           revoked.Key,
+			method:        http.MethodGet,
+			path:          "/v1/getCourse/" + courseUUID.String(),
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "unknown",
+			authenticator: withAPIKeys,
+			key:           services.APIKeyPrefix + "unknown",
+			method:        http.MethodGet,
+			path:          "/v1/getCourse/" + courseUUID.String(),
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "API keys not accepted",
+			authenticator: withoutAPIKeys,
+			key:           keys[models.ScopeAdmin].Key,
+			method:        http.MethodGet,
+			path:          "/v1/getCourse/" + courseUUID.String(),
+			wantStatus:    http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler
+			var gotPrincipal *services.Principal
+			handler := func(ec echo.Context) error {
+				if principal, ok := services.PrincipalFromContext(ec.Request().Context()); ok {
+					gotPrincipal = &principal
+				}
+				return ec.NoContent(http.StatusNoContent)
+			}
+			v1 := e.Group(pathV1, tt.authenticator.Middleware())
+			v1.GET("/getCourse/:courseUUID", handler)
+			v1.PUT(routeRegisterStudent, handler)
+			v1.DELETE("/deleteCourse/:courseUUID", handler)
+
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			request.Header.Set(HeaderAPIKey, tt.key)
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("got the status %v, want %v: %v", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantStatus != http.StatusNoContent {
+				if gotPrincipal != nil {
+					t.Errorf("the handler has been called with %v", gotPrincipal)
+				}
+				return
+			}
+			if gotPrincipal == nil || !strings.HasPrefix(gotPrincipal.Subject, "apikey:") ||
+				!reflect.DeepEqual(gotPrincipal.Roles, tt.wantRoles) {
+				t.Errorf("got the principal %v, want an API key with the roles %v", gotPrincipal, tt.wantRoles)
+			}
+		})
+	}
+}
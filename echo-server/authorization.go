@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+// requireRoles returns the echo middleware answering the requests of authenticated callers without any of the
+// given roles with 403 Forbidden, telling that only those roles may do what the routes do.
+// Requests are not authorized when they are not authenticated, which should only be the case in development.
+func requireRoles(what string, roles ...services.Role) echo.MiddlewareFunc {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role) + "s"
+	}
+	message := "only " + strings.Join(names, " and ") + " may " + what
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			principal, ok := services.PrincipalFromContext(ec.Request().Context())
+			if !ok {
+				return next(ec)
+			}
+			for _, role := range roles {
+				if principal.HasRole(role) {
+					return next(ec)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, message)
+		}
+	}
+}
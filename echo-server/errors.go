@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+const mimeProblemJSON = "application/problem+json"
+
+// Codes of the problems rendered for the errors of the services.
+// They are part of the API, so they must not change.
+const (
+	codeNotFound           = "not_found"
+	codeInvalidInput       = "invalid_input"
+	codeValidationFailed   = "validation_failed"
+	codeConstraintViolated = "constraint_violated"
+	codeVersionConflict    = "version_conflict"
+	codeAlreadyExists      = "already_exists"
+	codeForbidden          = "forbidden"
+	codeUnavailable        = "service_unavailable"
+	codeInternal           = "internal_error"
+)
+
+// Problem is the RFC 7807 problem details body of the error responses.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code identifies the kind of the problem, so that clients can branch on it.
+	Code string `json:"code"`
+	// Fields lists the invalid fields of a validation_failed problem.
+	Fields []models.FieldError `json:"fields,omitempty"`
+	// Constraint is the violated constraint of a constraint_violated problem.
+	Constraint services.Constraint `json:"constraint,omitempty"`
+}
+
+// HTTPErrorHandler renders the errors returned by the handlers as application/problem+json responses.
+// It should be set as the echo.Echo.HTTPErrorHandler.
+func HTTPErrorHandler(err error, ec echo.Context) {
+	if ec.Response().Committed {
+		return
+	}
+	problem := newProblem(err)
+	if problem.Status >= http.StatusInternalServerError {
+		ec.Logger().Error(err)
+	}
+	problem.Instance = ec.Request().URL.Path
+
+	if ec.Request().Method == http.MethodHead {
+		err = ec.NoContent(problem.Status)
+	} else {
+		ec.Response().Header().Set(echo.HeaderContentType, mimeProblemJSON)
+		ec.Response().WriteHeader(problem.Status)
+		err = json.NewEncoder(ec.Response()).Encode(problem)
+	}
+	if err != nil {
+		ec.Logger().Error(err)
+	}
+}
+
+// newProblem returns the problem describing the given error.
+// The details of unexpected errors are not disclosed.
+func newProblem(err error) Problem {
+	var (
+		httpErr       *echo.HTTPError
+		validationErr *models.ValidationErr
+		notFoundErr   *models.NotFoundError
+		constraintErr *services.CourseConstraintErr
+		forbiddenErr  *services.ForbiddenErr
+		existsErr     *models.AlreadyExistsError
+	)
+	switch {
+	case errors.As(err, &httpErr):
+		return problem(httpErr.Code, statusCode(httpErr.Code), fmt.Sprint(httpErr.Message))
+	case errors.As(err, &validationErr):
+		validationProblem := problem(http.StatusUnprocessableEntity, codeValidationFailed, validationErr.Error())
+		validationProblem.Fields = validationErr.Fields
+		return validationProblem
+	case errors.As(err, &notFoundErr):
+		return problem(http.StatusNotFound, codeNotFound, notFoundErr.Error())
+	case errors.Is(err, models.ErrInvalid):
+		return problem(http.StatusBadRequest, codeInvalidInput, err.Error())
+	case errors.As(err, &constraintErr):
+		constraintProblem := problem(http.StatusConflict, codeConstraintViolated, constraintErr.Error())
+		constraintProblem.Constraint = constraintErr.Constraint
+		return constraintProblem
+	case errors.As(err, &existsErr):
+		return problem(http.StatusConflict, codeAlreadyExists, existsErr.Error())
+	case errors.As(err, &forbiddenErr):
+		return problem(http.StatusForbidden, codeForbidden, forbiddenErr.Error())
+	case errors.Is(err, models.ErrConflict):
+		return problem(http.StatusPreconditionFailed, codeVersionConflict, "the course has been modified, fetch it again")
+	case unavailable(err):
+		return problem(http.StatusServiceUnavailable, codeUnavailable, "the database is unavailable, retry later")
+	default:
+		return problem(http.StatusInternalServerError, codeInternal, "")
+	}
+}
+
+func problem(status int, code, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// statusCode returns the problem code of an HTTP status, e.g. unsupported_media_type.
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// unavailable reports whether the given error is caused by the repo being unreachable.
+func unavailable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.As(err, &netErr)
+}
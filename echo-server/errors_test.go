@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	courseUUID := uuid.New()
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "not found",
+			err:        models.NewCourseNotFoundErr(courseUUID),
+			wantStatus: http.StatusNotFound,
+			wantCode:   codeNotFound,
+			wantDetail: models.NewCourseNotFoundErr(courseUUID).Error(),
+		},
+		{
+			name:       "nil input",
+			err:        services.NewNilErr("repo"),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   codeInvalidInput,
+			wantDetail: "repo cannot be nil",
+		},
+		{
+			name:       "validation failed",
+			err:        models.CourseMeta{Uuid: courseUUID, TutorUUID: courseUUID}.Validate(),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   codeValidationFailed,
+			wantDetail: "validation failed: name cannot be empty",
+		},
+		{
+			name:       "wrapped constraint",
+			err:        fmt.Errorf("unable to delete the tutor: %w", services.NewCourseConstraintErr(services.TutorHasCourses, 1)),
+			wantStatus: http.StatusConflict,
+			wantCode:   codeConstraintViolated,
+			wantDetail: "validation failed: the tutor facilitates 1 courses",
+		},
+		{
+			name:       "version conflict",
+			err:        models.NewVersionConflictErr(courseUUID, 1, 2),
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   codeVersionConflict,
+			wantDetail: "the course has been modified, fetch it again",
+		},
+		{
+			name:       "already exists",
+			err:        fmt.Errorf("unable to create the course: %w", models.NewAlreadyExistsErr(models.ResourceCourse, courseUUID)),
+			wantStatus: http.StatusConflict,
+			wantCode:   codeAlreadyExists,
+			wantDetail: "Course with UUID = " + courseUUID.String() + " already exists",
+		},
+		{
+			name:       "forbidden",
+			err:        fmt.Errorf("wrapped: %w", services.NewForbiddenErr("", services.ActionDeleteCourse, courseUUID)),
+			wantStatus: http.StatusForbidden,
+			wantCode:   codeForbidden,
+			wantDetail: "an unauthenticated caller is not allowed to delete the course with UUID = " + courseUUID.String(),
+		},
+		{
+			name:       "echo error",
+			err:        echo.ErrUnsupportedMediaType,
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantCode:   "unsupported_media_type",
+			wantDetail: "Unsupported Media Type",
+		},
+		{
+			name:       "repo unavailable",
+			err:        fmt.Errorf("unable to retrieve courses: %w", context.DeadlineExceeded),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   codeUnavailable,
+			wantDetail: "the database is unavailable, retry later",
+		},
+		{
+			name:       "unexpected error",
+			err:        errors.New("pq: relation \"courses\" does not exist"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			ec := e.NewContext(httptest.NewRequest(http.MethodGet, "/v1/getCourse/"+courseUUID.String(), nil), rec)
+
+			HTTPErrorHandler(tt.err, ec)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("HTTPErrorHandler() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); got != mimeProblemJSON {
+				t.Errorf("HTTPErrorHandler() content type = %v, want %v", got, mimeProblemJSON)
+			}
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if problem.Status != tt.wantStatus || problem.Code != tt.wantCode || problem.Detail != tt.wantDetail {
+				t.Errorf("HTTPErrorHandler() got = %+v, want status %v, code %v and detail %q",
+					problem, tt.wantStatus, tt.wantCode, tt.wantDetail)
+			}
+			if problem.Instance != "/v1/getCourse/"+courseUUID.String() {
+				t.Errorf("HTTPErrorHandler() instance = %v, want the request path", problem.Instance)
+			}
+		})
+	}
+}
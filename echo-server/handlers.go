@@ -1,49 +1,132 @@
 package server
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/models"
 	"github.com/tomasdembelli/course-manager/services"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-var (
-	notFoundMessage = map[string]string{"message": "not found"}
-	successMessage  = map[string]string{"message": "successful"}
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+
+	mimeMergePatchJSON = "application/merge-patch+json"
+
+	// pathV1 is the path of the group of the routes of ApiV1.
+	pathV1 = "/v1"
+
+	routeRegisterStudent   = "/registerStudent/:courseUUID"
+	routeUnregisterStudent = "/unregisterStudent/:courseUUID"
+	routeLeaveWaitlist     = "/leaveWaitlist/:courseUUID"
 )
 
-// ApiV1 exposes a services.CourseManager via HTTP endpoints.
+// enrollmentRoutes are the routes modifying the enrollments, which the API keys with the
+// models.ScopeEnrollmentWrite scope may call.
+var enrollmentRoutes = map[string]bool{
+	routeRegisterStudent:   true,
+	routeUnregisterStudent: true,
+	routeLeaveWaitlist:     true,
+}
+
+// ApiV1 exposes a services.CourseManager, a services.TutorManager, a services.StudentManager,
+// a services.WebhookManager and a services.APIKeyManager via HTTP endpoints.
 type ApiV1 struct {
-	courseManagerSvc *services.CourseManager
+	courseManagerSvc  *services.CourseManager
+	tutorManagerSvc   *services.TutorManager
+	studentManagerSvc *services.StudentManager
+	webhookManagerSvc *services.WebhookManager
+	apiKeyManagerSvc  *services.APIKeyManager
 }
 
-// NewApiV1 returns a new API that wraps the given services.CourseManager with HTTP endpoints.
-func NewApiV1(courseManager *services.CourseManager) (*ApiV1, error) {
+// NewApiV1 returns a new API that wraps the given services with HTTP endpoints.
+func NewApiV1(courseManager *services.CourseManager, tutorManager *services.TutorManager,
+	studentManager *services.StudentManager, webhookManager *services.WebhookManager,
+	apiKeyManager *services.APIKeyManager) (*ApiV1, error) {
 	if courseManager == nil {
 		return nil, fmt.Errorf("coursse manager cannot be nil")
 	}
+	if tutorManager == nil {
+		return nil, fmt.Errorf("tutor manager cannot be nil")
+	}
+	if studentManager == nil {
+		return nil, fmt.Errorf("student manager cannot be nil")
+	}
+	if webhookManager == nil {
+		return nil, fmt.Errorf("webhook manager cannot be nil")
+	}
+	if apiKeyManager == nil {
+		return nil, fmt.Errorf("API key manager cannot be nil")
+	}
 
 	return &ApiV1{
-		courseManagerSvc: courseManager,
+		courseManagerSvc:  courseManager,
+		tutorManagerSvc:   tutorManager,
+		studentManagerSvc: studentManager,
+		webhookManagerSvc: webhookManager,
+		apiKeyManagerSvc:  apiKeyManager,
 	}, nil
 }
 
+// Attach adds the routes of the ApiV1 to the given group. The tutors and students are only managed by the admins,
+// the webhooks by the admins and the registrars, e.g. the API keys of the LMS sync jobs, and the API keys by the admins.
+// The courses are authorized by the services.Authorizer of the course manager.
 func (a *ApiV1) Attach(group *echo.Group) {
+	managePeople := requireRoles("manage the tutors and students", services.RoleAdmin)
+	manageWebhooks := requireRoles("manage the webhooks", services.RoleAdmin, services.RoleRegistrar)
+	manageAPIKeys := requireRoles("manage the API keys", services.RoleAdmin)
+
 	group.GET("/listCourses", a.ListCourses)
 	group.GET("/getCourse/:courseUUID", a.GetCourse)
 	group.DELETE("/deleteCourse/:courseUUID", a.DeleteCourse)
-	group.PUT("/registerStudent/:courseUUID", a.RegisterStudent)
-	group.PUT("/unregisterStudent/:courseUUID", a.UnregisterStudent)
+	group.PUT(routeRegisterStudent, a.RegisterStudent)
+	group.PUT(routeUnregisterStudent, a.UnregisterStudent)
+	group.GET("/waitlistPosition/:courseUUID/:studentUUID", a.WaitlistPosition)
+	group.PUT(routeLeaveWaitlist, a.LeaveWaitlist)
 	group.POST("/createCourse", a.Create)
+	group.PATCH("/updateCourse/:courseUUID", a.UpdateCourse)
+	group.GET("/courseHistory/:courseUUID", a.CourseHistory)
+
+	group.GET("/tutors", a.ListTutors)
+	group.POST("/tutors", a.CreateTutor, managePeople)
+	group.GET("/tutors/:tutorUUID", a.GetTutor)
+	group.PUT("/tutors/:tutorUUID", a.UpdateTutor, managePeople)
+	group.DELETE("/tutors/:tutorUUID", a.DeleteTutor, managePeople)
+
+	group.GET("/students", a.ListStudents)
+	group.POST("/students", a.CreateStudent, managePeople)
+	group.GET("/students/:studentUUID", a.GetStudent)
+	group.PUT("/students/:studentUUID", a.UpdateStudent, managePeople)
+	group.DELETE("/students/:studentUUID", a.DeleteStudent, managePeople)
+
+	group.GET("/webhooks", a.ListWebhooks, manageWebhooks)
+	group.POST("/webhooks", a.CreateWebhook, manageWebhooks)
+	group.GET("/webhooks/:webhookID", a.GetWebhook, manageWebhooks)
+	group.PUT("/webhooks/:webhookID", a.UpdateWebhook, manageWebhooks)
+	group.DELETE("/webhooks/:webhookID", a.DeleteWebhook, manageWebhooks)
+	group.GET("/webhooks/:webhookID/deliveries", a.WebhookDeliveries, manageWebhooks)
+
+	group.GET("/apikeys", a.ListAPIKeys, manageAPIKeys)
+	group.POST("/apikeys", a.CreateAPIKey, manageAPIKeys)
+	group.DELETE("/apikeys/:apiKeyID", a.RevokeAPIKey, manageAPIKeys)
 }
 
 func (a *ApiV1) ListCourses(ec echo.Context) error {
-	courses, err := a.courseManagerSvc.List(ec.Request().Context())
+	request := new(ListCourses)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	page, err := a.courseManagerSvc.List(ec.Request().Context(), request.Query())
 	if err != nil {
 		return err
 	}
-	return ec.JSON(http.StatusOK, courses)
+	return ec.JSON(http.StatusOK, page)
 }
 
 func (a *ApiV1) GetCourse(ec echo.Context) error {
@@ -54,13 +137,9 @@ func (a *ApiV1) GetCourse(ec echo.Context) error {
 	}
 	course, err := a.courseManagerSvc.Get(ec.Request().Context(), request.UUID)
 	if err != nil {
-		if errors.Is(err, services.NewCourseNotFoundErr(request.UUID)) {
-			return ec.JSON(http.StatusNotFound, notFoundMessage)
-		} else {
-			ec.Logger().Error(err)
-			return err
-		}
+		return err
 	}
+	ec.Response().Header().Set(headerETag, etag(course.Version))
 	return ec.JSON(http.StatusOK, course)
 }
 
@@ -70,9 +149,11 @@ func (a *ApiV1) DeleteCourse(ec echo.Context) error {
 		ec.Logger().Error(err)
 		return err
 	}
-	err := a.courseManagerSvc.Delete(ec.Request().Context(), request.UUID)
+	version, err := ifMatchVersion(ec)
 	if err != nil {
-		ec.Logger().Error(err)
+		return err
+	}
+	if err = a.courseManagerSvc.Delete(ec.Request().Context(), request.UUID, version); err != nil {
 		return err
 	}
 	return ec.NoContent(http.StatusNoContent)
@@ -84,10 +165,20 @@ func (a *ApiV1) RegisterStudent(ec echo.Context) error {
 		ec.Logger().Error(err)
 		return err
 	}
-	err := a.courseManagerSvc.RegisterStudent(ec.Request().Context(), request.CourseUUID, request.Student)
+	version, err := ifMatchVersion(ec)
 	if err != nil {
-		ec.Logger().Error(err)
-		return ec.JSON(http.StatusBadRequest, map[string]string{"message": "unable to register student"})
+		return err
+	}
+	status, err := a.courseManagerSvc.RegisterStudent(ec.Request().Context(), request.CourseUUID, request.StudentUUID, version)
+	if err != nil {
+		return err
+	}
+	if status == services.Waitlisted {
+		position, err := a.courseManagerSvc.WaitlistPosition(ec.Request().Context(), request.CourseUUID, request.StudentUUID)
+		if err != nil {
+			return err
+		}
+		return ec.JSON(http.StatusAccepted, Waitlisted{Status: status, Position: position})
 	}
 	return ec.NoContent(http.StatusNoContent)
 }
@@ -98,10 +189,43 @@ func (a *ApiV1) UnregisterStudent(ec echo.Context) error {
 		ec.Logger().Error(err)
 		return err
 	}
-	err := a.courseManagerSvc.UnregisterStudent(ec.Request().Context(), request.CourseUUID, request.StudentUUID)
+	version, err := ifMatchVersion(ec)
+	if err != nil {
+		return err
+	}
+	err = a.courseManagerSvc.UnregisterStudent(ec.Request().Context(), request.CourseUUID, request.StudentUUID, version)
 	if err != nil {
+		return err
+	}
+	return ec.NoContent(http.StatusNoContent)
+}
+
+func (a *ApiV1) WaitlistPosition(ec echo.Context) error {
+	request := new(WaitlistPosition)
+	if err := ec.Bind(request); err != nil {
 		ec.Logger().Error(err)
-		return ec.JSON(http.StatusBadRequest, map[string]string{"message": "unable to unregister student"})
+		return err
+	}
+	position, err := a.courseManagerSvc.WaitlistPosition(ec.Request().Context(), request.CourseUUID, request.StudentUUID)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, Waitlisted{Position: position})
+}
+
+func (a *ApiV1) LeaveWaitlist(ec echo.Context) error {
+	request := new(LeaveWaitlist)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	version, err := ifMatchVersion(ec)
+	if err != nil {
+		return err
+	}
+	err = a.courseManagerSvc.LeaveWaitlist(ec.Request().Context(), request.CourseUUID, request.StudentUUID, version)
+	if err != nil {
+		return err
 	}
 	return ec.NoContent(http.StatusNoContent)
 }
@@ -114,10 +238,99 @@ func (a *ApiV1) Create(ec echo.Context) error {
 	}
 	course, err := a.courseManagerSvc.Create(ec.Request().Context(), request.Course)
 	if err != nil {
-		ec.Logger().Error(err)
-		return ec.JSON(http.StatusBadRequest, map[string]string{"message": "unable to create the course",
-			"error": err.Error(),
-		})
+		return err
 	}
 	return ec.JSON(http.StatusCreated, course)
 }
+
+// UpdateCourse applies the JSON Merge Patch (RFC 7386) in the request body to the metadata of the course.
+func (a *ApiV1) UpdateCourse(ec echo.Context) error {
+	request := new(CourseByUUID)
+	if err := (&echo.DefaultBinder{}).BindPathParams(ec, request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	contentType := ec.Request().Header.Get(echo.HeaderContentType)
+	if !strings.HasPrefix(contentType, mimeMergePatchJSON) && !strings.HasPrefix(contentType, echo.MIMEApplicationJSON) {
+		return echo.ErrUnsupportedMediaType
+	}
+	version, err := ifMatchVersion(ec)
+	if err != nil {
+		return err
+	}
+	patch, err := io.ReadAll(ec.Request().Body)
+	if err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+
+	course, err := a.courseManagerSvc.Get(ec.Request().Context(), request.UUID)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		// The patch has been applied to this version, so it must not overwrite concurrent changes.
+		version = course.Version
+	}
+	document, err := json.Marshal(course.CourseMeta)
+	if err != nil {
+		return err
+	}
+	document, err = mergePatch(document, patch)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "the request body must be a JSON merge patch").SetInternal(err)
+	}
+	var courseMeta models.CourseMeta
+	if err = json.Unmarshal(document, &courseMeta); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "the request body must be a JSON merge patch of a course").SetInternal(err)
+	}
+	courseMeta.Uuid = request.UUID
+
+	course, err = a.courseManagerSvc.UpdateMeta(ec.Request().Context(), courseMeta, version)
+	if err != nil {
+		return err
+	}
+	ec.Response().Header().Set(headerETag, etag(course.Version))
+	return ec.JSON(http.StatusOK, course)
+}
+
+// CourseHistory lists the audit events of the course, oldest first.
+func (a *ApiV1) CourseHistory(ec echo.Context) error {
+	request := new(CourseByUUID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	events, err := a.courseManagerSvc.History(ec.Request().Context(), request.UUID)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, events)
+}
+
+// etag returns the entity tag of the given course version.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatchVersion returns the course version required by the If-Match header of the request.
+// It returns 0, matching any version, for "If-Match: *".
+// The header is required, so that clients cannot overwrite changes they have not seen.
+func ifMatchVersion(ec echo.Context) (int, error) {
+	ifMatch := strings.TrimSpace(ec.Request().Header.Get(headerIfMatch))
+	if ifMatch == "" {
+		return 0, echo.NewHTTPError(http.StatusPreconditionRequired, "the If-Match header is required")
+	}
+	if ifMatch == "*" {
+		return 0, nil
+	}
+	tag, err := strconv.Unquote(ifMatch)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "the If-Match header must be a single entity tag")
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, echo.NewHTTPError(http.StatusPreconditionFailed, "the course has been modified, fetch it again")
+	}
+	return version, nil
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/apikeys"
+	db_memory "github.com/tomasdembelli/course-manager/db-memory"
+	"github.com/tomasdembelli/course-manager/publishers"
+	"github.com/tomasdembelli/course-manager/services"
+	"github.com/tomasdembelli/course-manager/webhooks"
+)
+
+// newTestServer returns an echo server of an ApiV1 of the services of an empty in-memory repo.
+// The requests are made on behalf of the principal of their principalHeader, if any.
+func newTestServer(t *testing.T, opts ...services.Option) *echo.Echo {
+	t.Helper()
+	repo := db_memory.NewRepo(nil, nil, nil)
+	courseManager, err := services.NewCourseManager(repo, nil, opts...)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	tutorManager, err := services.NewTutorManager(repo, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	studentManager, err := services.NewStudentManager(repo, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	webhookManager, err := services.NewWebhookManager(webhooks.NewMemoryStore(), func(url, secret string) services.Publisher {
+		return publishers.NewLogger(log.Default())
+	}, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	apiKeyManager, err := services.NewAPIKeyManager(apikeys.NewMemoryStore(), nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	apiV1, err := NewApiV1(&courseManager, &tutorManager, &studentManager, &webhookManager, &apiKeyManager)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	apiV1.Attach(e.Group(pathV1, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if raw := ec.Request().Header.Get(principalHeader); raw != "" {
+				var principal services.Principal
+				if err := json.Unmarshal([]byte(raw), &principal); err != nil {
+					return err
+				}
+				ec.SetRequest(ec.Request().WithContext(services.ContextWithPrincipal(ec.Request().Context(), principal)))
+			}
+			return next(ec)
+		}
+	}))
+	return e
+}
+
+// principalHeader is the header of the JSON services.Principal making the requests to a newTestServer.
+const principalHeader = "X-Test-Principal"
+
+// serve returns the response of the given server to a request with the given JSON body, if any,
+// on behalf of the given principal, unless it is nil.
+func serve(t *testing.T, e *echo.Echo, principal *services.Principal, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	request := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if principal != nil {
+		raw, err := json.Marshal(principal)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		request.Header.Set(principalHeader, string(raw))
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// problemCode returns the code of the problem of the given response, if any.
+func problemCode(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+	var problem Problem
+	_ = json.Unmarshal(recorder.Body.Bytes(), &problem)
+	return problem.Code
+}
+
+func TestApiV1_createDuplicates(t *testing.T) {
+	e := newTestServer(t)
+	tutorUUID, studentUUID, courseUUID := uuid.New(), uuid.New(), uuid.New()
+	tests := []struct {
+		name string
+		path string
+		body interface{}
+	}{
+		{
+			name: "tutor",
+			path: "/v1/tutors",
+			body: map[string]interface{}{"tutor": map[string]interface{}{"uuid": tutorUUID, "name": "John", "lastname": "Stone"}},
+		},
+		{
+			name: "student",
+			path: "/v1/students",
+			body: map[string]interface{}{"student": map[string]interface{}{"uuid": studentUUID, "name": "Alice", "lastname": "Smith"}},
+		},
+		{
+			name: "course",
+			path: "/v1/createCourse",
+			body: map[string]interface{}{"course": map[string]interface{}{"uuid": courseUUID, "name": "Go", "tutorUUID": tutorUUID}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(t, e, nil, http.MethodPost, tt.path, tt.body); got.Code != http.StatusCreated {
+				t.Fatalf("POST %v status = %v, want %v: %v", tt.path, got.Code, http.StatusCreated, got.Body)
+			}
+			got := serve(t, e, nil, http.MethodPost, tt.path, tt.body)
+			if got.Code != http.StatusConflict || problemCode(t, got) != codeAlreadyExists {
+				t.Errorf("POST %v again status = %v, want %v %v: %v", tt.path, got.Code, http.StatusConflict, codeAlreadyExists, got.Body)
+			}
+		})
+	}
+}
+
+func TestApiV1_courseHistory(t *testing.T) {
+	e := newTestServer(t, services.WithAuthorizer(services.RoleBasedAuthorizer{}))
+	tutorUUID, courseUUID := uuid.New(), uuid.New()
+	admin := &services.Principal{Subject: "admin", Roles: []services.Role{services.RoleAdmin}}
+	requests := []struct {
+		path string
+		body interface{}
+	}{
+		{path: "/v1/tutors", body: map[string]interface{}{"tutor": map[string]interface{}{"uuid": tutorUUID, "name": "John", "lastname": "Stone"}}},
+		{path: "/v1/createCourse", body: map[string]interface{}{"course": map[string]interface{}{"uuid": courseUUID, "name": "Go", "tutorUUID": tutorUUID}}},
+	}
+	for _, request := range requests {
+		if got := serve(t, e, admin, http.MethodPost, request.path, request.body); got.Code != http.StatusCreated {
+			t.Fatalf("POST %v status = %v, want %v: %v", request.path, got.Code, http.StatusCreated, got.Body)
+		}
+	}
+
+	path := "/v1/courseHistory/" + courseUUID.String()
+	tests := []struct {
+		name      string
+		principal *services.Principal
+		want      int
+	}{
+		{name: "unauthenticated", want: http.StatusForbidden},
+		{name: "another tutor", principal: &services.Principal{Subject: uuid.NewString(), Roles: []services.Role{services.RoleTutor}}, want: http.StatusForbidden},
+		{name: "student", principal: &services.Principal{Subject: uuid.NewString(), Roles: []services.Role{services.RoleStudent}}, want: http.StatusForbidden},
+		{name: "tutor of the course", principal: &services.Principal{Subject: tutorUUID.String(), Roles: []services.Role{services.RoleTutor}}, want: http.StatusOK},
+		{name: "admin", principal: admin, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(t, e, tt.principal, http.MethodGet, path, nil); got.Code != tt.want {
+				t.Errorf("GET %v status = %v, want %v: %v", path, got.Code, tt.want, got.Body)
+			}
+		})
+	}
+}
+
+func TestApiV1_deleteCourse(t *testing.T) {
+	e := newTestServer(t)
+	tutorUUID, courseUUID := uuid.New(), uuid.New()
+	requests := []struct {
+		path string
+		body interface{}
+	}{
+		{path: "/v1/tutors", body: map[string]interface{}{"tutor": map[string]interface{}{"uuid": tutorUUID, "name": "John", "lastname": "Stone"}}},
+		{path: "/v1/createCourse", body: map[string]interface{}{"course": map[string]interface{}{"uuid": courseUUID, "name": "Go", "tutorUUID": tutorUUID}}},
+	}
+	for _, request := range requests {
+		if got := serve(t, e, nil, http.MethodPost, request.path, request.body); got.Code != http.StatusCreated {
+			t.Fatalf("POST %v status = %v, want %v: %v", request.path, got.Code, http.StatusCreated, got.Body)
+		}
+	}
+
+	path := "/v1/deleteCourse/" + courseUUID.String()
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+	}{
+		{name: "without If-Match", want: http.StatusPreconditionRequired},
+		{name: "stale version", ifMatch: `"2"`, want: http.StatusPreconditionFailed},
+		{name: "current version", ifMatch: `"1"`, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodDelete, path, nil)
+			if tt.ifMatch != "" {
+				request.Header.Set(headerIfMatch, tt.ifMatch)
+			}
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("DELETE %v with If-Match %v status = %v, want %v: %v", path, tt.ifMatch, recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestApiV1_authorization(t *testing.T) {
+	e := newTestServer(t, services.WithAuthorizer(services.RoleBasedAuthorizer{}))
+	admin := &services.Principal{Subject: "admin", Roles: []services.Role{services.RoleAdmin}}
+	registrar := &services.Principal{Subject: "registrar", Roles: []services.Role{services.RoleRegistrar}}
+	tutorUUID, studentUUID, courseUUID := uuid.New(), uuid.New(), uuid.New()
+	tutor := &services.Principal{Subject: tutorUUID.String(), Roles: []services.Role{services.RoleTutor}}
+	student := &services.Principal{Subject: studentUUID.String(), Roles: []services.Role{services.RoleStudent}}
+	otherTutorUUID := uuid.New()
+	setup := []struct {
+		path string
+		body interface{}
+	}{
+		{path: "/v1/tutors", body: map[string]interface{}{"tutor": map[string]interface{}{"uuid": tutorUUID, "name": "John", "lastname": "Stone"}}},
+		{path: "/v1/tutors", body: map[string]interface{}{"tutor": map[string]interface{}{"uuid": otherTutorUUID, "name": "Jane", "lastname": "Stone"}}},
+		{path: "/v1/students", body: map[string]interface{}{"student": map[string]interface{}{"uuid": studentUUID, "name": "Alice", "lastname": "Smith"}}},
+		{path: "/v1/createCourse", body: map[string]interface{}{"course": map[string]interface{}{"uuid": courseUUID, "name": "Go", "tutorUUID": tutorUUID}}},
+	}
+	for _, request := range setup {
+		if got := serve(t, e, admin, http.MethodPost, request.path, request.body); got.Code != http.StatusCreated {
+			t.Fatalf("POST %v status = %v, want %v: %v", request.path, got.Code, http.StatusCreated, got.Body)
+		}
+	}
+
+	webhook := map[string]interface{}{"webhook": map[string]interface{}{"url": "https://example.com/events", "eventTypes": []string{"CourseCreated"}}}
+	tests := []struct {
+		name       string
+		principal  *services.Principal
+		method     string
+		path       string
+		body       interface{}
+		forbidden  bool
+		wantStatus int
+	}{
+		{name: "tutor creating a tutor", principal: tutor, method: http.MethodPost, path: "/v1/tutors",
+			body: map[string]interface{}{"tutor": map[string]interface{}{"name": "Eve", "lastname": "Stone"}}, forbidden: true},
+		{name: "tutor updating themselves", principal: tutor, method: http.MethodPut, path: "/v1/tutors/" + tutorUUID.String(),
+			body: map[string]interface{}{"tutor": map[string]interface{}{"name": "Johnny", "lastname": "Stone"}}, forbidden: true},
+		{name: "registrar deleting a tutor", principal: registrar, method: http.MethodDelete, path: "/v1/tutors/" + otherTutorUUID.String(), forbidden: true},
+		{name: "student creating a student", principal: student, method: http.MethodPost, path: "/v1/students",
+			body: map[string]interface{}{"student": map[string]interface{}{"name": "Eve", "lastname": "Smith"}}, forbidden: true},
+		{name: "student updating themselves", principal: student, method: http.MethodPut, path: "/v1/students/" + studentUUID.String(),
+			body: map[string]interface{}{"student": map[string]interface{}{"name": "Alicia", "lastname": "Smith"}}, forbidden: true},
+		{name: "tutor deleting a student", principal: tutor, method: http.MethodDelete, path: "/v1/students/" + studentUUID.String(), forbidden: true},
+		{name: "student creating a webhook", principal: student, method: http.MethodPost, path: "/v1/webhooks", body: webhook, forbidden: true},
+		{name: "tutor listing the webhooks", principal: tutor, method: http.MethodGet, path: "/v1/webhooks", forbidden: true},
+		{name: "student listing the tutors", principal: student, method: http.MethodGet, path: "/v1/tutors", wantStatus: http.StatusOK},
+		{name: "registrar creating a webhook", principal: registrar, method: http.MethodPost, path: "/v1/webhooks", body: webhook, wantStatus: http.StatusCreated},
+		{name: "admin deleting a tutor", principal: admin, method: http.MethodDelete, path: "/v1/tutors/" + otherTutorUUID.String(), wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serve(t, e, tt.principal, tt.method, tt.path, tt.body)
+			if tt.forbidden {
+				if got.Code != http.StatusForbidden || problemCode(t, got) != codeForbidden {
+					t.Errorf("%v %v status = %v, want %v %v: %v", tt.method, tt.path, got.Code, http.StatusForbidden, codeForbidden, got.Body)
+				}
+				return
+			}
+			if got.Code != tt.wantStatus {
+				t.Errorf("%v %v status = %v, want %v: %v", tt.method, tt.path, got.Code, tt.wantStatus, got.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import "encoding/json"
+
+// mergePatch applies the given JSON Merge Patch (RFC 7386) to the given JSON document.
+func mergePatch(document, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue returns the target merged with the given patch. Null members of the patch remove the member of the target,
+// and patches which are not objects replace the target.
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{}, len(patchObject))
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergeValue(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
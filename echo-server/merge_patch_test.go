@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_mergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "replace a member", document: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add a member", document: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove a member", document: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "replace an array", document: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "merge nested objects", document: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"d":null,"f":1}}`, want: `{"a":{"b":"c","f":1}}`},
+		{name: "replace a non-object", document: `{"a":"foo"}`, patch: `["c"]`, want: `["c"]`},
+		{name: "empty patch", document: `{"a":"b"}`, patch: `{}`, want: `{"a":"b"}`},
+		{name: "invalid patch", document: `{"a":"b"}`, patch: `{"a":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergePatch([]byte(tt.document), []byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergePatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var gotValue, wantValue interface{}
+			if err = json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if err = json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("mergePatch() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
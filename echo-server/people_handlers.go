@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (a *ApiV1) ListTutors(ec echo.Context) error {
+	tutors, err := a.tutorManagerSvc.List(ec.Request().Context())
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, tutors)
+}
+
+func (a *ApiV1) GetTutor(ec echo.Context) error {
+	request := new(TutorByUUID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	tutor, err := a.tutorManagerSvc.Get(ec.Request().Context(), request.UUID)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, tutor)
+}
+
+func (a *ApiV1) CreateTutor(ec echo.Context) error {
+	request := new(SaveTutor)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	tutor, err := a.tutorManagerSvc.Create(ec.Request().Context(), request.Tutor)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusCreated, tutor)
+}
+
+func (a *ApiV1) UpdateTutor(ec echo.Context) error {
+	request := new(SaveTutor)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	request.Tutor.Uuid = request.UUID
+	tutor, err := a.tutorManagerSvc.Update(ec.Request().Context(), request.Tutor)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, tutor)
+}
+
+func (a *ApiV1) DeleteTutor(ec echo.Context) error {
+	request := new(TutorByUUID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	if err := a.tutorManagerSvc.Delete(ec.Request().Context(), request.UUID); err != nil {
+		return err
+	}
+	return ec.NoContent(http.StatusNoContent)
+}
+
+func (a *ApiV1) ListStudents(ec echo.Context) error {
+	students, err := a.studentManagerSvc.List(ec.Request().Context())
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, students)
+}
+
+func (a *ApiV1) GetStudent(ec echo.Context) error {
+	request := new(StudentByUUID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	student, err := a.studentManagerSvc.Get(ec.Request().Context(), request.UUID)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, student)
+}
+
+func (a *ApiV1) CreateStudent(ec echo.Context) error {
+	request := new(SaveStudent)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	student, err := a.studentManagerSvc.Create(ec.Request().Context(), request.Student)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusCreated, student)
+}
+
+func (a *ApiV1) UpdateStudent(ec echo.Context) error {
+	request := new(SaveStudent)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	request.Student.Uuid = request.UUID
+	student, err := a.studentManagerSvc.Update(ec.Request().Context(), request.Student)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, student)
+}
+
+func (a *ApiV1) DeleteStudent(ec echo.Context) error {
+	request := new(StudentByUUID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	if err := a.studentManagerSvc.Delete(ec.Request().Context(), request.UUID); err != nil {
+		return err
+	}
+	return ec.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+)
+
+// RouteGroup is a group of routes sharing a rate limit.
+type RouteGroup string
+
+const (
+	// RouteGroupRead groups the routes retrieving resources with GET or HEAD.
+	RouteGroupRead RouteGroup = "read"
+	// RouteGroupEnrollment groups the routes registering and unregistering students, and removing them from waitlists.
+	RouteGroupEnrollment RouteGroup = "enrollment"
+	// RouteGroupWrite groups the other routes, which modify resources.
+	RouteGroupWrite RouteGroup = "write"
+)
+
+// RouteGroups lists every RouteGroup.
+var RouteGroups = []RouteGroup{RouteGroupRead, RouteGroupEnrollment, RouteGroupWrite}
+
+// RateLimiter limits the rate of the requests of every client to the routes of each RouteGroup with a token bucket.
+// The clients are identified by their API key or the subject of their bearer token if they are authenticated,
+// and by their IP address otherwise.
+// It may also limit the rate of every IP address to any route before the requests are authenticated.
+type RateLimiter struct {
+	store   services.RateLimitStore
+	limits  map[RouteGroup]services.RateLimit
+	ipLimit *services.RateLimit
+	now     func() time.Time
+}
+
+// RateLimiterOption configures a RateLimiter.
+type RateLimiterOption func(r *RateLimiter)
+
+// WithIPRateLimit makes the IPMiddleware of the RateLimiter limit the requests of every IP address to the given limit,
+// which should be higher than the limits of the RouteGroups, as several clients may share an address.
+// Without it, the IPMiddleware does not limit the requests.
+func WithIPRateLimit(limit services.RateLimit) RateLimiterOption {
+	return func(r *RateLimiter) {
+		r.ipLimit = &limit
+	}
+}
+
+// NewRateLimiter returns a RateLimiter keeping the token buckets in the given store, and enforcing the given limits.
+// The routes of the groups without a limit are not limited. It returns an error if a limit is invalid.
+func NewRateLimiter(store services.RateLimitStore, limits map[RouteGroup]services.RateLimit, opts ...RateLimiterOption) (*RateLimiter, error) {
+	if store == nil {
+		return nil, services.NewNilErr("store")
+	}
+	for group, limit := range limits {
+		if err := limit.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid limit of the %v routes: %w", group, err)
+		}
+	}
+	r := &RateLimiter{store: store, limits: limits, now: time.Now}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.ipLimit != nil {
+		if err := r.ipLimit.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid limit of the IP addresses: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// IPMiddleware returns the echo middleware taking a token from the bucket of the IP address of every request,
+// as the Middleware does for its client. It must be used before the authentication middleware, so that the requests
+// which fail to authenticate, e.g. guessing API keys, are limited too.
+// The IP address is the one of echo.Context.RealIP, so the echo.IPExtractor should only trust the known proxies.
+func (r *RateLimiter) IPMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if r.ipLimit == nil {
+				return next(ec)
+			}
+			return r.take(ec, next, "ip "+ec.RealIP(), *r.ipLimit, "the rate limit of an IP address")
+		}
+	}
+}
+
+// Middleware returns the echo middleware taking a token from the bucket of the client of every request for the group
+// of its route. The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers tell the state of the bucket,
+// and requests finding it empty are answered with 429 Too Many Requests and a Retry-After header.
+// It must be used after the authentication middleware, to identify the authenticated clients.
+// Requests are not limited if the store fails.
+func (r *RateLimiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			group := routeGroup(ec.Request().Method, ec.Path())
+			limit, ok := r.limits[group]
+			if !ok {
+				return next(ec)
+			}
+			return r.take(ec, next, string(group)+" "+clientKey(ec), limit, fmt.Sprintf("the rate limit of the %v routes", group))
+		}
+	}
+}
+
+// take takes a token from the bucket of the given key, and calls next unless the bucket is empty.
+// The given description of the limit tells it in the problem of the rejected requests.
+func (r *RateLimiter) take(ec echo.Context, next echo.HandlerFunc, key string, limit services.RateLimit, description string) error {
+	decision, err := r.store.Take(ec.Request().Context(), key, limit, r.now())
+	if err != nil {
+		ec.Logger().Errorf("unable to limit the rate of %v: %v", key, err)
+		return next(ec)
+	}
+	header := ec.Response().Header()
+	header.Set(headerRateLimitLimit, strconv.Itoa(limit.Requests))
+	header.Set(headerRateLimitRemaining, strconv.Itoa(decision.Remaining))
+	header.Set(headerRateLimitReset, seconds(decision.Reset))
+	if !decision.Allowed {
+		header.Set(headerRetryAfter, seconds(decision.RetryAfter))
+		return echo.NewHTTPError(http.StatusTooManyRequests,
+			fmt.Sprintf("%v is %v requests per %v", description, limit.Requests, limit.Period))
+	}
+	return next(ec)
+}
+
+// routeGroup returns the RouteGroup of a request of the given method to the route of the given path.
+func routeGroup(method, path string) RouteGroup {
+	switch {
+	case enrollmentRoutes[strings.TrimPrefix(path, pathV1)]:
+		return RouteGroupEnrollment
+	case method == http.MethodGet || method == http.MethodHead:
+		return RouteGroupRead
+	default:
+		return RouteGroupWrite
+	}
+}
+
+// clientKey identifies the client of a request by the subject of its principal, which is "apikey:" followed by the ID
+// of its API key for the machine clients, or by its IP address if it is not authenticated.
+func clientKey(ec echo.Context) string {
+	if principal, ok := services.PrincipalFromContext(ec.Request().Context()); ok {
+		return "principal:" + principal.Subject
+	}
+	return "ip:" + ec.RealIP()
+}
+
+// seconds returns the given delay as a header value in whole seconds, rounded up.
+func seconds(delay time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10)
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tomasdembelli/course-manager/ratelimit"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+// testRateLimits are the limits of the RouteGroups of the tests.
+var testRateLimits = map[RouteGroup]services.RateLimit{
+	RouteGroupRead:       {Requests: 600, Period: time.Minute},
+	RouteGroupEnrollment: {Requests: 30, Period: time.Minute},
+	RouteGroupWrite:      {Requests: 120, Period: time.Minute},
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   services.RateLimitStore
+		limits  map[RouteGroup]services.RateLimit
+		opts    []RateLimiterOption
+		wantErr bool
+	}{
+		{name: "nil store", limits: testRateLimits, wantErr: true},
+		{
+			name:    "invalid limit",
+			store:   ratelimit.NewMemoryStore(),
+			limits:  map[RouteGroup]services.RateLimit{RouteGroupRead: {Requests: 0, Period: time.Minute}},
+			wantErr: true,
+		},
+		{name: "default limits", store: ratelimit.NewMemoryStore(), limits: testRateLimits},
+		{name: "no limits", store: ratelimit.NewMemoryStore()},
+		{
+			name:    "invalid IP limit",
+			store:   ratelimit.NewMemoryStore(),
+			opts:    []RateLimiterOption{WithIPRateLimit(services.RateLimit{Requests: 1})},
+			wantErr: true,
+		},
+		{name: "IP limit", store: ratelimit.NewMemoryStore(), opts: []RateLimiterOption{WithIPRateLimit(services.RateLimit{Requests: 1200, Period: time.Minute})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRateLimiter(tt.store, tt.limits, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRateLimiter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	limiter, err := NewRateLimiter(ratelimit.NewMemoryStore(), map[RouteGroup]services.RateLimit{
+		RouteGroupEnrollment: {Requests: 2, Period: time.Minute},
+		RouteGroupRead:       {Requests: 100, Period: time.Minute},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	handler := func(ec echo.Context) error {
+		return ec.NoContent(http.StatusNoContent)
+	}
+	// The principal is set as the authentication middleware does.
+	authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if subject := ec.Request().Header.Get("X-Subject"); subject != "" {
+				ctx := services.ContextWithPrincipal(ec.Request().Context(), services.Principal{Subject: subject})
+				ec.SetRequest(ec.Request().WithContext(ctx))
+			}
+			return next(ec)
+		}
+	}
+	v1 := e.Group(pathV1, authenticate, limiter.Middleware())
+	v1.PUT(routeRegisterStudent, handler)
+	v1.GET("/getCourse/:courseUUID", handler)
+	v1.DELETE("/deleteCourse/:courseUUID", handler)
+
+	type response struct {
+		status                  int
+		limit, remaining, reset string
+		retryAfter              string
+	}
+	do := func(method, path, subject, ip string, want response) {
+		t.Helper()
+		request := httptest.NewRequest(method, path, nil)
+		request.Header.Set(echo.HeaderXRealIP, ip)
+		if subject != "" {
+			request.Header.Set("X-Subject", subject)
+		}
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		header := recorder.Header()
+		got := response{
+			status:     recorder.Code,
+			limit:      header.Get(headerRateLimitLimit),
+			remaining:  header.Get(headerRateLimitRemaining),
+			reset:      header.Get(headerRateLimitReset),
+			retryAfter: header.Get(headerRetryAfter),
+		}
+		if got != want {
+			t.Errorf("%v %v by %q from %v got %+v, want %+v", method, path, subject, ip, got, want)
+		}
+	}
+
+	register := "/v1/registerStudent/5d61cbc8-9ccd-4348-a623-d61dd7658dd7"
+	do(http.MethodPut, register, "", "10.0.0.1", response{status: http.StatusNoContent, limit: "2", remaining: "1", reset: "30"})
+	do(http.MethodPut, register, "", "10.0.0.1", response{status: http.StatusNoContent, limit: "2", remaining: "0", reset: "60"})
+	do(http.MethodPut, register, "", "10.0.0.1",
+		response{status: http.StatusTooManyRequests, limit: "2", remaining: "0", reset: "60", retryAfter: "30"})
+
+	// The clients are limited separately, by principal or IP address, and so are the route groups.
+	do(http.MethodPut, register, "", "10.0.0.2", response{status: http.StatusNoContent, limit: "2", remaining: "1", reset: "30"})
+	do(http.MethodPut, register, "apikey:lms", "10.0.0.1", response{status: http.StatusNoContent, limit: "2", remaining: "1", reset: "30"})
+	do(http.MethodGet, "/v1/getCourse/5d61cbc8-9ccd-4348-a623-d61dd7658dd7", "", "10.0.0.1",
+		response{status: http.StatusNoContent, limit: "100", remaining: "99", reset: "1"})
+	do(http.MethodDelete, "/v1/deleteCourse/5d61cbc8-9ccd-4348-a623-d61dd7658dd7", "", "10.0.0.1",
+		response{status: http.StatusNoContent})
+
+	now = now.Add(30 * time.Second)
+	do(http.MethodPut, register, "", "10.0.0.1", response{status: http.StatusNoContent, limit: "2", remaining: "0", reset: "60"})
+}
+
+func TestRateLimiter_IPMiddleware(t *testing.T) {
+	limiter, err := NewRateLimiter(ratelimit.NewMemoryStore(), testRateLimits,
+		WithIPRateLimit(services.RateLimit{Requests: 2, Period: time.Minute}))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.IPExtractor = echo.ExtractIPDirect()
+	// The requests are rejected by the authentication, after the IP address is limited.
+	unauthorized := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid API key")
+		}
+	}
+	e.Group(pathV1, limiter.IPMiddleware(), unauthorized, limiter.Middleware()).GET("/listCourses", func(ec echo.Context) error {
+		return ec.NoContent(http.StatusNoContent)
+	})
+
+	do := func(remoteAddr, forwardedFor string) int {
+		request := httptest.NewRequest(http.MethodGet, "/v1/listCourses", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	// Forging the forwarded address does not escape the limit of the address of the connection.
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if got := do("10.0.0.1:1234", fmt.Sprintf("203.0.113.%d", i)); got != want {
+			t.Errorf("request %d from 10.0.0.1 status = %v, want %v", i+1, got, want)
+		}
+	}
+	if got := do("10.0.0.2:1234", ""); got != http.StatusUnauthorized {
+		t.Errorf("request from 10.0.0.2 status = %v, want %v", got, http.StatusUnauthorized)
+	}
+}
+
+func TestRouteGroup(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         RouteGroup
+	}{
+		{method: http.MethodGet, path: "/v1/listCourses", want: RouteGroupRead},
+		{method: http.MethodHead, path: "/v1/getCourse/:courseUUID", want: RouteGroupRead},
+		{method: http.MethodPut, path: "/v1" + routeRegisterStudent, want: RouteGroupEnrollment},
+		{method: http.MethodPut, path: "/v1" + routeLeaveWaitlist, want: RouteGroupEnrollment},
+		{method: http.MethodPost, path: "/v1/createCourse", want: RouteGroupWrite},
+		{method: http.MethodPut, path: "/v1/tutors/:tutorUUID", want: RouteGroupWrite},
+	}
+	for _, tt := range tests {
+		if got := routeGroup(tt.method, tt.path); got != tt.want {
+			t.Errorf("routeGroup(%v, %v) = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"strings"
+
 	"github.com/google/uuid"
 	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
 )
 
 // CreateCourse should be used at the HTTP endpoint for creating a course.
@@ -10,15 +13,43 @@ type CreateCourse struct {
 	Course models.CourseMeta `form:"course"`
 }
 
-// CourseByUUID should be used at the HTTP endpoint for querying an individual course by its UUID.
+// ListCourses should be used at the HTTP endpoint listing courses.
+// Sort is a models.CourseSort, prefixed with a "-" for the descending order.
+type ListCourses struct {
+	TutorUUID    uuid.UUID `query:"tutorUUID"`
+	Faculty      string    `query:"faculty"`
+	Name         string    `query:"name"`
+	HasFreeSeats bool      `query:"hasFreeSeats"`
+	Sort         string    `query:"sort"`
+	Limit        int       `query:"limit"`
+	Cursor       string    `query:"cursor"`
+}
+
+// Query returns the models.CourseQuery of the request.
+func (l ListCourses) Query() models.CourseQuery {
+	return models.CourseQuery{
+		CourseFilter: models.CourseFilter{
+			TutorUUID:    l.TutorUUID,
+			Faculty:      l.Faculty,
+			NameContains: l.Name,
+			HasFreeSeats: l.HasFreeSeats,
+		},
+		Sort:       models.CourseSort(strings.TrimPrefix(l.Sort, "-")),
+		Descending: strings.HasPrefix(l.Sort, "-"),
+		Limit:      l.Limit,
+		Cursor:     l.Cursor,
+	}
+}
+
+// CourseByUUID should be used at the HTTP endpoints querying an individual course or its history by its UUID.
 type CourseByUUID struct {
 	UUID uuid.UUID `param:"courseUUID"`
 }
 
 // RegisterStudent should be used at the HTTP endpoint registering a student to a given course.
 type RegisterStudent struct {
-	CourseUUID uuid.UUID      `param:"courseUUID"`
-	Student    models.Student `form:"student"`
+	CourseUUID  uuid.UUID `param:"courseUUID"`
+	StudentUUID uuid.UUID `form:"studentUUID"`
 }
 
 // UnregisterStudent should be used at the HTTP endpoint unregistering a student from a given course.
@@ -26,3 +57,64 @@ type UnregisterStudent struct {
 	CourseUUID  uuid.UUID `param:"courseUUID"`
 	StudentUUID uuid.UUID `form:"studentUUID"`
 }
+
+// WaitlistPosition should be used at the HTTP endpoint querying the waitlist position of a student.
+type WaitlistPosition struct {
+	CourseUUID  uuid.UUID `param:"courseUUID"`
+	StudentUUID uuid.UUID `param:"studentUUID"`
+}
+
+// LeaveWaitlist should be used at the HTTP endpoint removing a student from the waitlist of a given course.
+type LeaveWaitlist struct {
+	CourseUUID  uuid.UUID `param:"courseUUID"`
+	StudentUUID uuid.UUID `form:"studentUUID"`
+}
+
+// Waitlisted is the response of the HTTP endpoints telling the waitlist position of a student.
+type Waitlisted struct {
+	Status   services.RegistrationStatus `json:"status,omitempty"`
+	Position int                         `json:"position"`
+}
+
+// TutorByUUID should be used at the HTTP endpoints querying or deleting an individual tutor by its UUID.
+type TutorByUUID struct {
+	UUID uuid.UUID `param:"tutorUUID"`
+}
+
+// SaveTutor should be used at the HTTP endpoints creating or updating a tutor.
+type SaveTutor struct {
+	UUID  uuid.UUID    `param:"tutorUUID"`
+	Tutor models.Tutor `form:"tutor"`
+}
+
+// StudentByUUID should be used at the HTTP endpoints querying or deleting an individual student by its UUID.
+type StudentByUUID struct {
+	UUID uuid.UUID `param:"studentUUID"`
+}
+
+// SaveStudent should be used at the HTTP endpoints creating or updating a student.
+type SaveStudent struct {
+	UUID    uuid.UUID      `param:"studentUUID"`
+	Student models.Student `form:"student"`
+}
+
+// WebhookByID should be used at the HTTP endpoints querying or deleting an individual webhook by its ID.
+type WebhookByID struct {
+	ID uuid.UUID `param:"webhookID"`
+}
+
+// SaveWebhook should be used at the HTTP endpoints creating or updating a webhook.
+type SaveWebhook struct {
+	ID      uuid.UUID          `param:"webhookID"`
+	Webhook models.WebhookMeta `form:"webhook"`
+}
+
+// APIKeyByID should be used at the HTTP endpoints revoking an individual API key by its ID.
+type APIKeyByID struct {
+	ID uuid.UUID `param:"apiKeyID"`
+}
+
+// CreateAPIKey should be used at the HTTP endpoints creating an API key.
+type CreateAPIKey struct {
+	APIKey models.APIKeyMeta `form:"apiKey"`
+}
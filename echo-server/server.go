@@ -4,32 +4,106 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/tomasdembelli/course-manager/services"
 	"log"
+	"net"
 	"strconv"
+	"time"
 
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	gommonLog "github.com/labstack/gommon/log"
 )
 
+// logLevels are the levels of the echo logger, by the name of the log levels of the configuration.
+var logLevels = map[string]gommonLog.Lvl{
+	"debug": gommonLog.DEBUG,
+	"info":  gommonLog.INFO,
+	"warn":  gommonLog.WARN,
+	"error": gommonLog.ERROR,
+	"off":   gommonLog.OFF,
+}
+
 type Config struct {
-	Port             int
-	CourseManagerSvc *services.CourseManager
+	Port              int
+	CourseManagerSvc  *services.CourseManager
+	TutorManagerSvc   *services.TutorManager
+	StudentManagerSvc *services.StudentManager
+	WebhookManagerSvc *services.WebhookManager
+	APIKeyManagerSvc  *services.APIKeyManager
+	// Authenticator authenticates the requests to the API. Requests are not authenticated if it is nil,
+	// which should only be the case in development.
+	Authenticator *Authenticator
+	// RateLimiter limits the rate of the requests of every IP address before they are authenticated, and of every client
+	// after. Requests are not limited if it is nil.
+	RateLimiter *RateLimiter
+	// TrustedProxies are the networks of the reverse proxies whose X-Forwarded-For header tells the IP address
+	// of the clients. Without any, the IP address of the clients is the one of the connection.
+	TrustedProxies []*net.IPNet
+	// LogLevel is the level of the logs of the server: debug, info, warn, error or off. It defaults to info,
+	// and the requests are only logged at the debug and info levels.
+	LogLevel string
+	// CORSAllowOrigins are the origins allowed to call the API from a browser. It defaults to any origin.
+	CORSAllowOrigins []string
+	// ReadTimeout, WriteTimeout and IdleTimeout are the timeouts of the HTTP server. Zero means no timeout.
+	ReadTimeout, WriteTimeout, IdleTimeout time.Duration
 }
 
 func StartServer(config *Config) {
-	apiV1, err := NewApiV1(config.CourseManagerSvc)
+	apiV1, err := NewApiV1(config.CourseManagerSvc, config.TutorManagerSvc, config.StudentManagerSvc, config.WebhookManagerSvc,
+		config.APIKeyManagerSvc)
 	if err != nil {
 		log.Fatal("unable to start apiV1", err)
 	}
 
+	level, ok := logLevels[config.LogLevel]
+	if !ok {
+		level = gommonLog.INFO
+	}
+	allowOrigins := config.CORSAllowOrigins
+	if len(allowOrigins) == 0 {
+		allowOrigins = []string{"*"}
+	}
+
 	e := echo.New()
-	e.Use(echoMiddleware.Logger())
+	e.Logger.SetLevel(level)
+	e.Server.ReadTimeout = config.ReadTimeout
+	e.Server.WriteTimeout = config.WriteTimeout
+	e.Server.IdleTimeout = config.IdleTimeout
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.IPExtractor = ipExtractor(config.TrustedProxies)
+	if level <= gommonLog.INFO {
+		e.Use(echoMiddleware.Logger())
+	}
 	e.Use(echoMiddleware.Recover())
 	e.Use(echoMiddleware.CORSWithConfig(echoMiddleware.CORSConfig{
-		AllowOrigins:     []string{"*"},
+		AllowOrigins:     allowOrigins,
 		AllowMethods:     echoMiddleware.DefaultCORSConfig.AllowMethods,
 		AllowCredentials: true,
 	}))
-	v1 := e.Group("/v1")
+	v1 := e.Group(pathV1)
+	if config.RateLimiter != nil {
+		v1.Use(config.RateLimiter.IPMiddleware())
+	}
+	if config.Authenticator != nil {
+		v1.Use(config.Authenticator.Middleware())
+	}
+	if config.RateLimiter != nil {
+		v1.Use(config.RateLimiter.Middleware())
+	}
 	apiV1.Attach(v1)
 
 	e.Logger.Fatal(e.Start(":" + strconv.Itoa(config.Port)))
 }
+
+// ipExtractor returns the echo.IPExtractor of the IP address of the clients: the one of the connection,
+// or the one forwarded by the given trusted proxies in the X-Forwarded-For header. Unlike the echo defaults,
+// the proxies on the loopback, link-local and private networks are only trusted if they are given,
+// so that the clients cannot forge their address, e.g. to evade the rate limits.
+func ipExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
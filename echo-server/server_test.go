@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestIPExtractor(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.1.0.0/16")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	tests := []struct {
+		name           string
+		trustedProxies []*net.IPNet
+		remoteAddr     string
+		forwardedFor   string
+		want           string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:1234", want: "203.0.113.7"},
+		{name: "forwarded without trusted proxies", remoteAddr: "10.1.0.1:1234", forwardedFor: "203.0.113.7", want: "10.1.0.1"},
+		{name: "forwarded by a trusted proxy", trustedProxies: []*net.IPNet{proxies}, remoteAddr: "10.1.0.1:1234",
+			forwardedFor: "203.0.113.7", want: "203.0.113.7"},
+		{name: "forged before a trusted proxy", trustedProxies: []*net.IPNet{proxies}, remoteAddr: "10.1.0.1:1234",
+			forwardedFor: "198.51.100.1, 203.0.113.7", want: "203.0.113.7"},
+		{name: "forwarded by an untrusted private proxy", trustedProxies: []*net.IPNet{proxies}, remoteAddr: "10.2.0.1:1234",
+			forwardedFor: "203.0.113.7", want: "10.2.0.1"},
+		{name: "forwarded by an untrusted loopback proxy", trustedProxies: []*net.IPNet{proxies}, remoteAddr: "127.0.0.1:1234",
+			forwardedFor: "203.0.113.7", want: "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				request.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+			}
+			if got := ipExtractor(tt.trustedProxies)(request); got != tt.want {
+				t.Errorf("ipExtractor() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (a *ApiV1) ListWebhooks(ec echo.Context) error {
+	webhooks, err := a.webhookManagerSvc.List(ec.Request().Context())
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook subscribes a webhook, and returns it with the secret of its signatures, which is only returned once.
+func (a *ApiV1) CreateWebhook(ec echo.Context) error {
+	request := new(SaveWebhook)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	webhook, err := a.webhookManagerSvc.Create(ec.Request().Context(), request.Webhook)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusCreated, webhook)
+}
+
+func (a *ApiV1) GetWebhook(ec echo.Context) error {
+	request := new(WebhookByID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	webhook, err := a.webhookManagerSvc.Get(ec.Request().Context(), request.ID)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, webhook)
+}
+
+func (a *ApiV1) UpdateWebhook(ec echo.Context) error {
+	request := new(SaveWebhook)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	webhook, err := a.webhookManagerSvc.Update(ec.Request().Context(), request.ID, request.Webhook)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, webhook)
+}
+
+func (a *ApiV1) DeleteWebhook(ec echo.Context) error {
+	request := new(WebhookByID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	if err := a.webhookManagerSvc.Delete(ec.Request().Context(), request.ID); err != nil {
+		return err
+	}
+	return ec.NoContent(http.StatusNoContent)
+}
+
+// WebhookDeliveries lists the latest deliveries of the webhook and their attempts, oldest first.
+func (a *ApiV1) WebhookDeliveries(ec echo.Context) error {
+	request := new(WebhookByID)
+	if err := ec.Bind(request); err != nil {
+		ec.Logger().Error(err)
+		return err
+	}
+	deliveries, err := a.webhookManagerSvc.Deliveries(ec.Request().Context(), request.ID)
+	if err != nil {
+		return err
+	}
+	return ec.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,238 @@
+// Package migrations applies the versioned schema migrations of the SQL repos.
+//
+// Migrations are SQL scripts embedded per dialect, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, e.g. postgres/0001_initial.up.sql.
+// The applied versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var scripts embed.FS
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+// Migration is a versioned schema change with the scripts applying and reverting it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a Migration has been applied, and when.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load returns the migrations of the given dialect ordered by version.
+func Load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(scripts, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var suffix string
+		switch {
+		case strings.HasSuffix(fileName, upSuffix):
+			suffix = upSuffix
+		case strings.HasSuffix(fileName, downSuffix):
+			suffix = downSuffix
+		default:
+			return nil, fmt.Errorf("unexpected migration file %v", fileName)
+		}
+		parts := strings.SplitN(strings.TrimSuffix(fileName, suffix), "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 || version <= 0 {
+			return nil, fmt.Errorf("migration file %v must be named <version>_<name>%v", fileName, suffix)
+		}
+		script, err := fs.ReadFile(scripts, path.Join(dialect, fileName))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has conflicting names %v and %v", version, migration.Name, parts[1])
+		}
+		if suffix == upSuffix {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%v must have both up and down scripts", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts the migrations of a dialect on a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the given database speaking the given dialect.
+func NewMigrator(db *sql.DB, dialect string) (*Migrator, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction.
+// It returns the migrations which have been applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+		err = m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, status.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+				status.Version, status.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("unable to apply migration %d_%v: %w", status.Version, status.Name, err)
+		}
+		applied = append(applied, status.Migration)
+	}
+	return applied, nil
+}
+
+// Down reverts the latest applied migration and returns it.
+// It returns nil if no migration has been applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(statuses) - 1; i >= 0; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+		err = m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, status.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, status.Version)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to revert migration %d_%v: %w", status.Version, status.Name, err)
+		}
+		return &status.Migration, nil
+	}
+	return nil, nil
+}
+
+// Status returns the status of every known migration ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the migrations table: %w", err)
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the applied migrations: %w", err)
+	}
+	defer rows.Close()
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("unable to scan the applied migration: %w", err)
+		}
+		appliedAt[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query the applied migrations: %w", err)
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		at, applied := appliedAt[migration.Version]
+		statuses[i] = Status{
+			Migration: migration,
+			Applied:   applied,
+			AppliedAt: at,
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations which have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func newSQLiteMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "migrations.db"))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrator, err := NewMigrator(db, "sqlite")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	return migrator, db
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		wantErr bool
+	}{
+		{name: "postgres migrations", dialect: "postgres"},
+		{name: "sqlite migrations", dialect: "sqlite"},
+		{name: "unknown dialect", dialect: "oracle", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.dialect)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for i, migration := range got {
+				if migration.Version != i+1 {
+					t.Errorf("Load() migration %v has version %d, want %d", migration.Name, migration.Version, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_sameVersionsAcrossDialects(t *testing.T) {
+	postgres, err := Load("postgres")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	sqlite, err := Load("sqlite")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("postgres has %d migrations, sqlite has %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("postgres migration %d_%v does not match sqlite migration %d_%v",
+				postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	ctx := context.TODO()
+	migrator, db := newSQLiteMigrator(t)
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(pending) != len(migrator.migrations) {
+		t.Errorf("Pending() got %d migrations, want %d", len(pending), len(migrator.migrations))
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Errorf("Up() applied %d migrations, want %d", len(applied), len(migrator.migrations))
+	}
+	if _, err = db.ExecContext(ctx, `SELECT count(*) FROM courses`); err != nil {
+		t.Errorf("expected the courses table to exist: %v", err)
+	}
+
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Up() applied %d migrations twice", len(applied))
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Errorf("Status() migration %d_%v is not applied", status.Version, status.Name)
+		}
+	}
+
+	for i := len(migrator.migrations) - 1; i >= 0; i-- {
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if reverted == nil || reverted.Version != migrator.migrations[i].Version {
+			t.Fatalf("Down() reverted %v, want version %d", reverted, migrator.migrations[i].Version)
+		}
+	}
+	reverted, err := migrator.Down(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if reverted != nil {
+		t.Errorf("Down() reverted %v with no applied migrations", reverted)
+	}
+	if _, err = db.ExecContext(ctx, `SELECT count(*) FROM courses`); err == nil {
+		t.Errorf("expected the courses table to be dropped")
+	}
+}
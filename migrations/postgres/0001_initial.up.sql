@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS tutors (
+    uuid        UUID PRIMARY KEY,
+    name        TEXT NOT NULL DEFAULT '',
+    lastname    TEXT NOT NULL DEFAULT '',
+    faculty     TEXT NOT NULL DEFAULT '',
+    lecturer_of TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS students (
+    uuid     UUID PRIMARY KEY,
+    name     TEXT NOT NULL DEFAULT '',
+    lastname TEXT NOT NULL DEFAULT '',
+    faculty  TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS courses (
+    uuid       UUID PRIMARY KEY,
+    name       TEXT NOT NULL DEFAULT '',
+    tutor_uuid UUID REFERENCES tutors (uuid)
+);
+
+CREATE INDEX IF NOT EXISTS courses_tutor_uuid_idx ON courses (tutor_uuid);
+
+CREATE TABLE IF NOT EXISTS enrollments (
+    course_uuid  UUID NOT NULL REFERENCES courses (uuid) ON DELETE CASCADE,
+    student_uuid UUID NOT NULL REFERENCES students (uuid),
+    PRIMARY KEY (course_uuid, student_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS enrollments_student_uuid_idx ON enrollments (student_uuid);
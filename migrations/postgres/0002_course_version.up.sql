@@ -0,0 +1 @@
+ALTER TABLE courses ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
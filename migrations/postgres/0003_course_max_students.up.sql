@@ -0,0 +1 @@
+ALTER TABLE courses ADD COLUMN max_students INTEGER NOT NULL DEFAULT 0;
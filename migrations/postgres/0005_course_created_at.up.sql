@@ -0,0 +1,4 @@
+ALTER TABLE courses ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+CREATE INDEX IF NOT EXISTS courses_name_idx ON courses (name, uuid);
+CREATE INDEX IF NOT EXISTS courses_created_at_idx ON courses (created_at, uuid);
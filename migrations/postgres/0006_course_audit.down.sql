@@ -0,0 +1,4 @@
+ALTER TABLE enrollments DROP COLUMN enrolled_at;
+ALTER TABLE courses DROP COLUMN updated_by;
+ALTER TABLE courses DROP COLUMN updated_at;
+ALTER TABLE courses DROP COLUMN created_by;
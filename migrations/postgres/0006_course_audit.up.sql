@@ -0,0 +1,6 @@
+ALTER TABLE courses ADD COLUMN created_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE courses ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE courses ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE enrollments ADD COLUMN enrolled_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+UPDATE courses SET updated_at = created_at;
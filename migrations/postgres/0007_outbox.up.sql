@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    position BIGSERIAL PRIMARY KEY,
+    id       UUID NOT NULL UNIQUE,
+    payload  TEXT NOT NULL
+);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS waitlist_student_uuid_idx ON waitlist (student_uuid);
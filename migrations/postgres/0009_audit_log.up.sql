@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    position    BIGSERIAL PRIMARY KEY,
+    course_uuid UUID NOT NULL,
+    payload     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_course_uuid_idx ON audit_log (course_uuid, position);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    position BIGSERIAL PRIMARY KEY,
+    id       UUID NOT NULL UNIQUE,
+    payload  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    position        BIGSERIAL PRIMARY KEY,
+    id              UUID NOT NULL UNIQUE,
+    webhook_id      UUID NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        UUID NOT NULL,
+    status          TEXT NOT NULL,
+    next_attempt_at BIGINT,
+    payload         TEXT NOT NULL,
+    UNIQUE (webhook_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
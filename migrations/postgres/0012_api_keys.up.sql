@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    position BIGSERIAL PRIMARY KEY,
+    id       UUID NOT NULL UNIQUE,
+    hash     TEXT NOT NULL UNIQUE,
+    payload  TEXT NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS waitlist (
+    course_uuid  TEXT NOT NULL REFERENCES courses (uuid) ON DELETE CASCADE,
+    student_uuid TEXT NOT NULL REFERENCES students (uuid),
+    position     INTEGER NOT NULL,
+    PRIMARY KEY (course_uuid, student_uuid)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS courses_created_at_idx;
+DROP INDEX IF EXISTS courses_name_idx;
+ALTER TABLE courses DROP COLUMN created_at;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS waitlist_student_uuid_idx;
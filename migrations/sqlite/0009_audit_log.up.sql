@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    position    INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_uuid TEXT NOT NULL,
+    payload     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_course_uuid_idx ON audit_log (course_uuid, position);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS outbox_published (
+    consumer TEXT NOT NULL,
+    event_id TEXT NOT NULL REFERENCES outbox (id) ON DELETE CASCADE,
+    PRIMARY KEY (consumer, event_id)
+);
+CREATE INDEX IF NOT EXISTS outbox_published_event_id_idx ON outbox_published (event_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    position INTEGER PRIMARY KEY AUTOINCREMENT,
+    id       TEXT NOT NULL UNIQUE,
+    hash     TEXT NOT NULL UNIQUE,
+    payload  TEXT NOT NULL
+);
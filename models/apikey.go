@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const unknownScopeFmt = "%s must only contain known scopes"
+
+// Scope grants an APIKey access to a part of the API.
+type Scope string
+
+const (
+	// ScopeReadOnly grants the retrieval of any resource.
+	ScopeReadOnly Scope = "read-only"
+	// ScopeEnrollmentWrite grants the retrieval of any resource, and the registration and unregistration
+	// of any student.
+	ScopeEnrollmentWrite Scope = "enrollment-write"
+	// ScopeAdmin grants any request.
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes lists every Scope, from the narrowest to the broadest.
+var Scopes = []Scope{ScopeReadOnly, ScopeEnrollmentWrite, ScopeAdmin}
+
+// Known reports whether the scope is one of Scopes.
+func (s Scope) Known() bool {
+	for _, scope := range Scopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyMeta defines the attributes of an API key which are chosen by the admin creating it.
+type APIKeyMeta struct {
+	// Name tells what the key is used for, e.g. "LMS sync".
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+// APIKey authenticates a machine client of the API, which is granted the scopes of the key.
+type APIKey struct {
+	APIKeyMeta
+	ID uuid.UUID `json:"id"`
+	// Prefix is the beginning of the plaintext key, telling the keys apart without disclosing them.
+	Prefix string `json:"prefix"`
+	// Key is the plaintext key. It is only returned on creation, as only its Hash is stored.
+	Key string `json:"key,omitempty"`
+	// Hash is the hex encoded SHA-256 hash of the plaintext key.
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Validate returns a *ValidationErr listing the invalid fields of the API key metadata.
+func (k APIKeyMeta) Validate() error {
+	validationErr := &ValidationErr{}
+	validationErr.requireString("name", k.Name)
+	if len(k.Scopes) == 0 {
+		validationErr.add("scopes", canNotBeEmptyFmt)
+	}
+	for _, scope := range k.Scopes {
+		if !scope.Known() {
+			validationErr.add("scopes", unknownScopeFmt)
+			break
+		}
+	}
+	return validationErr.orNil()
+}
+
+// HasScope reports whether the key has been granted the given scope.
+func (k APIKeyMeta) HasScope(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoked reports whether the key has been revoked, and cannot authenticate anymore.
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction is the kind of modification of a course recorded by an AuditEvent.
+type AuditAction string
+
+const (
+	// CourseCreated is recorded when a course is created.
+	CourseCreated AuditAction = "course.created"
+	// CourseUpdated is recorded when the metadata of a course is modified.
+	CourseUpdated AuditAction = "course.updated"
+	// CourseDeleted is recorded when a course is deleted.
+	CourseDeleted AuditAction = "course.deleted"
+	// StudentRegistered is recorded when a student is enrolled to a course or put on its waitlist.
+	StudentRegistered AuditAction = "student.registered"
+	// StudentUnregistered is recorded when a student is removed from a course.
+	StudentUnregistered AuditAction = "student.unregistered"
+	// StudentLeftWaitlist is recorded when a student is removed from the waitlist of a course.
+	StudentLeftWaitlist AuditAction = "student.leftWaitlist"
+)
+
+// AuditEvent records a modification of a course, telling when, by whom and how it has been modified.
+// Before is nil for a created course and After is nil for a deleted one.
+type AuditEvent struct {
+	Time       time.Time   `json:"time"`
+	Actor      string      `json:"actor,omitempty"`
+	Action     AuditAction `json:"action"`
+	CourseUUID uuid.UUID   `json:"courseUUID"`
+	// StudentUUID references the student registered or unregistered by the modification, if any.
+	StudentUUID *uuid.UUID `json:"studentUUID,omitempty"`
+	Before      *Course    `json:"before,omitempty"`
+	After       *Course    `json:"after,omitempty"`
+}
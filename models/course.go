@@ -1,15 +1,67 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const belowEnrollmentsFmt = "%s cannot be lower than the %d enrolled students"
 
 type CourseMeta struct {
-	Uuid  uuid.UUID `json:"uuid,omitempty"`
-	Name  string    `json:"name"`
-	Tutor *Tutor    `json:"tutor"`
+	Uuid uuid.UUID `json:"uuid,omitempty"`
+	Name string    `json:"name"`
+	// TutorUUID references the Tutor facilitating the course.
+	TutorUUID uuid.UUID `json:"tutorUUID"`
+	// MaxStudents overrides the maximum number of students of the deployment policy for the course, unless it is 0.
+	MaxStudents int `json:"maxStudents,omitempty"`
+}
+
+// Validate returns a *ValidationErr listing the invalid fields of the course metadata.
+func (c CourseMeta) Validate() error {
+	validationErr := &ValidationErr{}
+	validationErr.requireUUID("uuid", c.Uuid)
+	validationErr.requireString("name", c.Name)
+	validationErr.requireUUID("tutorUUID", c.TutorUUID)
+	if c.MaxStudents < 0 {
+		validationErr.add("maxStudents", canNotBeNegativeFmt)
+	}
+	return validationErr.orNil()
 }
 
 // Course defines a course.
+// Students who register once the course is full are queued in its Waitlist, in order of registration.
+// Its Version is incremented on every update, so that stale updates can be rejected.
 type Course struct {
 	CourseMeta
-	Students map[uuid.UUID]Student `json:"students"`
+	Students map[uuid.UUID]Enrollment `json:"students"`
+	Waitlist []uuid.UUID              `json:"waitlist,omitempty"`
+	Version  int                      `json:"version"`
+	// CreatedAt and CreatedBy tell when and by whom the course has been created,
+	// UpdatedAt and UpdatedBy when and by whom it has been modified last. Times are in UTC.
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+}
+
+// ValidateCapacity returns a *ValidationErr if the given capacity of the course, e.g. a new MaxStudents,
+// is lower than the number of its enrolled students.
+func (c Course) ValidateCapacity(capacity int) error {
+	validationErr := &ValidationErr{}
+	if capacity < len(c.Students) {
+		validationErr.Fields = append(validationErr.Fields, FieldError{
+			Field:   "maxStudents",
+			Message: fmt.Sprintf(belowEnrollmentsFmt, "maxStudents", len(c.Students)),
+		})
+	}
+	return validationErr.orNil()
+}
+
+// Enrollment references a Student registered to a course.
+type Enrollment struct {
+	StudentUUID uuid.UUID `json:"studentUUID"`
+	// EnrolledAt is the time the student has got their seat at, in UTC.
+	EnrolledAt time.Time `json:"enrolledAt"`
 }
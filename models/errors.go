@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	versionConflictFmt = "course with UUID = %v is at version %d, not %d"
+	notFoundFmt        = "%v with UUID = %v not found"
+	alreadyExistsFmt   = "%v with UUID = %v already exists"
+	notOnWaitlistFmt   = "Student with UUID = %v is not on the waitlist of course with UUID = %v"
+)
+
+// The kinds of the errors returned by the services and the repos.
+// Errors carrying more context wrap or match them, so that callers can branch with errors.Is,
+// and get the context with errors.As.
+var (
+	// ErrNotFound is the kind of the errors telling that a course, tutor or student does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is the kind of the errors telling that an input is missing or invalid.
+	ErrInvalid = errors.New("invalid input")
+	// ErrConstraint is the kind of the errors telling that an operation would violate a constraint,
+	// e.g. on the number of courses of a tutor.
+	ErrConstraint = errors.New("constraint violated")
+	// ErrConflict is the kind of the errors telling that a course has been modified concurrently.
+	ErrConflict = errors.New("version conflict")
+	// ErrAlreadyExists is the kind of the errors telling that a course, tutor, student, webhook or API key with the same UUID exists.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrForbidden is the kind of the errors telling that the caller is not allowed to make an operation.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// VersionConflictErr is returned when a course is modified based on a stale version of it.
+type VersionConflictErr struct {
+	CourseUUID uuid.UUID
+	// Expected is the version the modification is based on.
+	Expected int
+	// Actual is the current version of the course.
+	Actual int
+}
+
+// NewVersionConflictErr returns a VersionConflictErr for the given course.
+func NewVersionConflictErr(courseUUID uuid.UUID, expected, actual int) *VersionConflictErr {
+	return &VersionConflictErr{
+		CourseUUID: courseUUID,
+		Expected:   expected,
+		Actual:     actual,
+	}
+}
+
+// Error implements error.
+func (e *VersionConflictErr) Error() string {
+	return fmt.Sprintf(versionConflictFmt, e.CourseUUID, e.Actual, e.Expected)
+}
+
+// Is reports whether the target is ErrConflict.
+func (e *VersionConflictErr) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Resources which can be missing, as reported by NotFoundError.Resource.
+const (
+	ResourceCourse        = "Course"
+	ResourceTutor         = "Tutor"
+	ResourceStudent       = "Student"
+	ResourceWaitlistEntry = "Waitlist entry"
+	ResourceWebhook       = "Webhook"
+	ResourceAPIKey        = "API key"
+)
+
+// NotFoundError is returned when a course, tutor, student, webhook or API key does not exist. It matches ErrNotFound.
+type NotFoundError struct {
+	// Resource is the kind of the missing resource, e.g. ResourceCourse.
+	Resource string
+	// UUID identifies the missing resource. It is the UUID of the student for a ResourceWaitlistEntry.
+	UUID uuid.UUID
+	// CourseUUID is the course whose waitlist the student is not on, for a ResourceWaitlistEntry.
+	CourseUUID uuid.UUID
+}
+
+// NewNotFoundErr returns a NotFoundError for the given resource.
+func NewNotFoundErr(resource string, resourceUUID uuid.UUID) *NotFoundError {
+	return &NotFoundError{Resource: resource, UUID: resourceUUID}
+}
+
+// NewCourseNotFoundErr returns a NotFoundError for the given course.
+func NewCourseNotFoundErr(courseUUID uuid.UUID) *NotFoundError {
+	return NewNotFoundErr(ResourceCourse, courseUUID)
+}
+
+// NewTutorNotFoundErr returns a NotFoundError for the given tutor.
+func NewTutorNotFoundErr(tutorUUID uuid.UUID) *NotFoundError {
+	return NewNotFoundErr(ResourceTutor, tutorUUID)
+}
+
+// NewStudentNotFoundErr returns a NotFoundError for the given student.
+func NewStudentNotFoundErr(studentUUID uuid.UUID) *NotFoundError {
+	return NewNotFoundErr(ResourceStudent, studentUUID)
+}
+
+// NewWebhookNotFoundErr returns a NotFoundError for the given webhook.
+func NewWebhookNotFoundErr(webhookID uuid.UUID) *NotFoundError {
+	return NewNotFoundErr(ResourceWebhook, webhookID)
+}
+
+// NewAPIKeyNotFoundErr returns a NotFoundError for the given API key.
+func NewAPIKeyNotFoundErr(apiKeyID uuid.UUID) *NotFoundError {
+	return NewNotFoundErr(ResourceAPIKey, apiKeyID)
+}
+
+// NewNotOnWaitlistErr returns a NotFoundError for a student who is not on the waitlist of the given course.
+func NewNotOnWaitlistErr(courseUUID, studentUUID uuid.UUID) *NotFoundError {
+	return &NotFoundError{Resource: ResourceWaitlistEntry, UUID: studentUUID, CourseUUID: courseUUID}
+}
+
+// Error implements error.
+func (e *NotFoundError) Error() string {
+	if e.Resource == ResourceWaitlistEntry {
+		return fmt.Sprintf(notOnWaitlistFmt, e.UUID, e.CourseUUID)
+	}
+	return fmt.Sprintf(notFoundFmt, e.Resource, e.UUID)
+}
+
+// Is reports whether the target is ErrNotFound, or a NotFoundError for the same resource.
+func (e *NotFoundError) Is(target error) bool {
+	if target == ErrNotFound {
+		return true
+	}
+	t, ok := target.(*NotFoundError)
+	return ok && *t == *e
+}
+
+// AlreadyExistsError is returned when a course, tutor, student, webhook or API key is created with the UUID of an existing one.
+// It matches ErrAlreadyExists.
+type AlreadyExistsError struct {
+	// Resource is the kind of the existing resource, e.g. ResourceCourse.
+	Resource string
+	// UUID identifies the existing resource.
+	UUID uuid.UUID
+}
+
+// NewAlreadyExistsErr returns an AlreadyExistsError for the given resource.
+func NewAlreadyExistsErr(resource string, resourceUUID uuid.UUID) *AlreadyExistsError {
+	return &AlreadyExistsError{Resource: resource, UUID: resourceUUID}
+}
+
+// Error implements error.
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf(alreadyExistsFmt, e.Resource, e.UUID)
+}
+
+// Is reports whether the target is ErrAlreadyExists, or an AlreadyExistsError for the same resource.
+func (e *AlreadyExistsError) Is(target error) bool {
+	if target == ErrAlreadyExists {
+		return true
+	}
+	t, ok := target.(*AlreadyExistsError)
+	return ok && *t == *e
+}
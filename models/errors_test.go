@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var courseUUID = uuid.MustParse("5d61cbc8-9ccd-4348-a623-d61dd7658dd7")
+
+func TestNewNotFoundErr(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *NotFoundError
+		want *NotFoundError
+	}{
+		{
+			name: "course",
+			got:  NewCourseNotFoundErr(courseUUID),
+			want: &NotFoundError{Resource: ResourceCourse, UUID: courseUUID},
+		},
+		{
+			name: "tutor",
+			got:  NewTutorNotFoundErr(courseUUID),
+			want: &NotFoundError{Resource: ResourceTutor, UUID: courseUUID},
+		},
+		{
+			name: "student",
+			got:  NewStudentNotFoundErr(courseUUID),
+			want: NewNotFoundErr(ResourceStudent, courseUUID),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("NewNotFoundErr() = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotFoundError_Error(t *testing.T) {
+	studentUuid := uuid.MustParse("c46358be-a216-4083-8bc2-0c4eda703b4a")
+	tests := []struct {
+		name string
+		err  *NotFoundError
+		want string
+	}{
+		{
+			name: "course",
+			err:  NewCourseNotFoundErr(courseUUID),
+			want: "Course with UUID = 5d61cbc8-9ccd-4348-a623-d61dd7658dd7 not found",
+		},
+		{
+			name: "waitlist entry",
+			err:  NewNotOnWaitlistErr(courseUUID, studentUuid),
+			want: "Student with UUID = c46358be-a216-4083-8bc2-0c4eda703b4a is not on the waitlist of course with UUID = 5d61cbc8-9ccd-4348-a623-d61dd7658dd7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotFoundError_Is(t *testing.T) {
+	err := fmt.Errorf("unable to register the student: %w", NewCourseNotFoundErr(courseUUID))
+	tests := []struct {
+		name   string
+		target error
+		want   bool
+	}{
+		{name: "kind of the error", target: ErrNotFound, want: true},
+		{name: "same course", target: NewCourseNotFoundErr(courseUUID), want: true},
+		{name: "other course", target: NewCourseNotFoundErr(uuid.New()), want: false},
+		{name: "other resource", target: NewTutorNotFoundErr(courseUUID), want: false},
+		{name: "other kind", target: ErrConstraint, want: false},
+		{name: "same message", target: errors.New(NewCourseNotFoundErr(courseUUID).Error()), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlreadyExistsError_Is(t *testing.T) {
+	err := fmt.Errorf("unable to create the course: %w", NewAlreadyExistsErr(ResourceCourse, courseUUID))
+	tests := []struct {
+		name   string
+		target error
+		want   bool
+	}{
+		{name: "kind of the error", target: ErrAlreadyExists, want: true},
+		{name: "same course", target: NewAlreadyExistsErr(ResourceCourse, courseUUID), want: true},
+		{name: "other course", target: NewAlreadyExistsErr(ResourceCourse, uuid.New()), want: false},
+		{name: "other resource", target: NewAlreadyExistsErr(ResourceTutor, courseUUID), want: false},
+		{name: "other kind", target: ErrConflict, want: false},
+		{name: "not found", target: NewCourseNotFoundErr(courseUUID), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType is the kind of change of a course told by an Event.
+type EventType string
+
+const (
+	// CourseCreatedEvent tells that a course has been created.
+	CourseCreatedEvent EventType = "CourseCreated"
+	// CourseUpdatedEvent tells that the metadata of a course has been modified.
+	CourseUpdatedEvent EventType = "CourseUpdated"
+	// CourseDeletedEvent tells that a course has been deleted.
+	CourseDeletedEvent EventType = "CourseDeleted"
+	// StudentRegisteredEvent tells that a student has been enrolled to a course or put on its waitlist.
+	StudentRegisteredEvent EventType = "StudentRegistered"
+	// StudentUnregisteredEvent tells that a student has been removed from a course.
+	StudentUnregisteredEvent EventType = "StudentUnregistered"
+	// StudentPromotedEvent tells that a waitlisted student has been enrolled to a course.
+	StudentPromotedEvent EventType = "StudentPromoted"
+	// StudentLeftWaitlistEvent tells that a student has been removed from the waitlist of a course.
+	StudentLeftWaitlistEvent EventType = "StudentLeftWaitlist"
+)
+
+// Event is a domain event telling downstream systems that a course has changed.
+// Events may be delivered more than once, so consumers should deduplicate them by ID.
+type Event struct {
+	ID         uuid.UUID `json:"id"`
+	Type       EventType `json:"type"`
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor,omitempty"`
+	CourseUUID uuid.UUID `json:"courseUUID"`
+	// StudentUUID references the student of the change, if any.
+	StudentUUID *uuid.UUID `json:"studentUUID,omitempty"`
+	// Status tells whether the student of a StudentRegisteredEvent has been enrolled or waitlisted.
+	Status string `json:"status,omitempty"`
+	// Course is the course after the change, or nil if it has been deleted.
+	Course *Course `json:"course,omitempty"`
+}
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxCourseLimit is the maximum number of courses of a CoursePage.
+	MaxCourseLimit = 100
+
+	outOfRangeFmt    = "%s must be between 0 and %d"
+	unknownSortFmt   = "%s must be one of name, createdAt and enrollments"
+	invalidCursorFmt = "%s is not a cursor of this sort order"
+)
+
+// CourseSort is an order of the courses listed by a CourseQuery.
+// Courses with the same sort key are ordered by UUID.
+type CourseSort string
+
+const (
+	// SortByName orders the courses by name.
+	SortByName CourseSort = "name"
+	// SortByCreatedAt orders the courses by creation time.
+	SortByCreatedAt CourseSort = "createdAt"
+	// SortByEnrollments orders the courses by number of enrolled students.
+	SortByEnrollments CourseSort = "enrollments"
+)
+
+// CourseFilter selects the courses listed by a CourseQuery. Its zero value selects every course.
+type CourseFilter struct {
+	// TutorUUID selects the courses facilitated by the tutor, unless it is uuid.Nil.
+	TutorUUID uuid.UUID
+	// Faculty selects the courses whose tutor is of the faculty, unless it is empty.
+	Faculty string
+	// NameContains selects the courses whose name contains it, ignoring the case.
+	NameContains string
+	// HasFreeSeats selects the courses whose number of enrolled students is below their capacity.
+	HasFreeSeats bool
+	// DefaultCapacity is the capacity of the courses which do not override it with CourseMeta.MaxStudents.
+	DefaultCapacity int
+}
+
+// Match reports whether the filter selects the given course, facilitated by a tutor of the given faculty.
+func (f CourseFilter) Match(course Course, tutorFaculty string) bool {
+	if f.TutorUUID != uuid.Nil && course.TutorUUID != f.TutorUUID {
+		return false
+	}
+	if f.Faculty != "" && tutorFaculty != f.Faculty {
+		return false
+	}
+	if !strings.Contains(strings.ToLower(course.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	capacity := f.DefaultCapacity
+	if course.MaxStudents > 0 {
+		capacity = course.MaxStudents
+	}
+	return !f.HasFreeSeats || len(course.Students) < capacity
+}
+
+// CourseQuery selects a page of the courses matching its CourseFilter, in the order of its Sort.
+type CourseQuery struct {
+	CourseFilter
+	// Sort is the order of the courses, SortByName if it is empty.
+	Sort CourseSort
+	// Descending reverses the order of the courses.
+	Descending bool
+	// Limit is the maximum number of courses of the page. Every course is listed if it is 0.
+	Limit int
+	// Cursor is the CoursePage.NextCursor of the previous page, or empty for the first page.
+	Cursor string
+}
+
+// CoursePage is a page of the courses listed by a CourseQuery.
+type CoursePage struct {
+	Courses []Course `json:"courses"`
+	// NextCursor is the cursor of the next page, or empty if this is the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// CourseKey is the position of a course in the orders of the CourseQuery.
+type CourseKey struct {
+	Name        string    `json:"n,omitempty"`
+	CreatedAt   time.Time `json:"c"`
+	Enrollments int       `json:"e,omitempty"`
+	Uuid        uuid.UUID `json:"u"`
+}
+
+// NewCourseKey returns the position of the given course.
+func NewCourseKey(course Course) CourseKey {
+	return CourseKey{
+		Name:        course.Name,
+		CreatedAt:   course.CreatedAt,
+		Enrollments: len(course.Students),
+		Uuid:        course.Uuid,
+	}
+}
+
+// cursor is the content of CourseQuery.Cursor. It is tied to the order of the query which has returned it.
+type cursor struct {
+	Sort       CourseSort `json:"s"`
+	Descending bool       `json:"d,omitempty"`
+	After      CourseKey  `json:"a"`
+}
+
+// Validate returns a *ValidationErr listing the invalid fields of the query.
+func (q CourseQuery) Validate() error {
+	validationErr := &ValidationErr{}
+	switch q.Sort {
+	case "", SortByName, SortByCreatedAt, SortByEnrollments:
+	default:
+		validationErr.add("sort", unknownSortFmt)
+	}
+	if q.Limit < 0 || q.Limit > MaxCourseLimit {
+		validationErr.Fields = append(validationErr.Fields, FieldError{
+			Field:   "limit",
+			Message: fmt.Sprintf(outOfRangeFmt, "limit", MaxCourseLimit),
+		})
+	}
+	if _, err := q.After(); err != nil {
+		validationErr.add("cursor", invalidCursorFmt)
+	}
+	return validationErr.orNil()
+}
+
+// After returns the position of the last course of the previous page, or nil for the first page.
+// It returns an error if the Cursor has not been returned by a query of the same order.
+func (q CourseQuery) After() (*CourseKey, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the cursor: %w", err)
+	}
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("unable to decode the cursor: %w", err)
+	}
+	if c.Sort != q.sort() || c.Descending != q.Descending {
+		return nil, fmt.Errorf("the cursor is not of the %v order", q.sort())
+	}
+	return &c.After, nil
+}
+
+// NextCursor returns the cursor of the page following the given last course of a page.
+func (q CourseQuery) NextCursor(last Course) string {
+	data, _ := json.Marshal(cursor{Sort: q.sort(), Descending: q.Descending, After: NewCourseKey(last)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Less reports whether the course at position a comes before the course at position b in the order of the query.
+func (q CourseQuery) Less(a, b CourseKey) bool {
+	if q.Descending {
+		a, b = b, a
+	}
+	switch q.sort() {
+	case SortByCreatedAt:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	case SortByEnrollments:
+		if a.Enrollments != b.Enrollments {
+			return a.Enrollments < b.Enrollments
+		}
+	default:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	}
+	return a.Uuid.String() < b.Uuid.String()
+}
+
+// Page returns the page of the given courses selected by the query, for repos filtering courses in memory.
+// The courses must match the CourseFilter of the query already.
+func (q CourseQuery) Page(courses []Course) (*CoursePage, error) {
+	after, err := q.After()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(courses, func(i, j int) bool {
+		return q.Less(NewCourseKey(courses[i]), NewCourseKey(courses[j]))
+	})
+	if after != nil {
+		courses = courses[sort.Search(len(courses), func(i int) bool {
+			return q.Less(*after, NewCourseKey(courses[i]))
+		}):]
+	}
+	page := &CoursePage{Courses: courses}
+	if q.Limit > 0 && len(courses) > q.Limit {
+		page.Courses = courses[:q.Limit]
+		page.NextCursor = q.NextCursor(page.Courses[q.Limit-1])
+	}
+	return page, nil
+}
+
+func (q CourseQuery) sort() CourseSort {
+	if q.Sort == "" {
+		return SortByName
+	}
+	return q.Sort
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCourseQuery_Validate(t *testing.T) {
+	byName := CourseQuery{Sort: SortByName}
+	tests := []struct {
+		name  string
+		query CourseQuery
+		want  []FieldError
+	}{
+		{
+			name: "default query",
+		},
+		{
+			name:  "next page",
+			query: CourseQuery{Sort: SortByName, Limit: MaxCourseLimit, Cursor: byName.NextCursor(Course{})},
+		},
+		{
+			name:  "default sort of the cursor",
+			query: CourseQuery{Cursor: byName.NextCursor(Course{})},
+		},
+		{
+			name:  "unknown sort and negative limit",
+			query: CourseQuery{Sort: "tutor", Limit: -1},
+			want: []FieldError{
+				{Field: "sort", Message: "sort must be one of name, createdAt and enrollments"},
+				{Field: "limit", Message: "limit must be between 0 and 100"},
+			},
+		},
+		{
+			name:  "limit above the maximum",
+			query: CourseQuery{Limit: MaxCourseLimit + 1},
+			want:  []FieldError{{Field: "limit", Message: "limit must be between 0 and 100"}},
+		},
+		{
+			name:  "malformed cursor",
+			query: CourseQuery{Cursor: "not a cursor"},
+			want:  []FieldError{{Field: "cursor", Message: "cursor is not a cursor of this sort order"}},
+		},
+		{
+			name:  "cursor of another sort",
+			query: CourseQuery{Sort: SortByCreatedAt, Cursor: byName.NextCursor(Course{})},
+			want:  []FieldError{{Field: "cursor", Message: "cursor is not a cursor of this sort order"}},
+		},
+		{
+			name:  "cursor of another direction",
+			query: CourseQuery{Descending: true, Cursor: byName.NextCursor(Course{})},
+			want:  []FieldError{{Field: "cursor", Message: "cursor is not a cursor of this sort order"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testValidate(t, tt.query.Validate(), tt.want)
+		})
+	}
+}
+
+func TestCourseQuery_After(t *testing.T) {
+	course := Course{
+		CourseMeta: CourseMeta{Uuid: uuid.New(), Name: "Golang"},
+		Students:   map[uuid.UUID]Enrollment{uuid.New(): {}},
+		CreatedAt:  time.Date(2022, 3, 1, 10, 30, 0, 123000, time.UTC),
+	}
+	query := CourseQuery{Sort: SortByCreatedAt, Descending: true}
+	query.Cursor = query.NextCursor(course)
+
+	got, err := query.After()
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := NewCourseKey(course); !reflect.DeepEqual(*got, want) {
+		t.Errorf("After() got = %v, want %v", *got, want)
+	}
+
+	if got, err = (CourseQuery{}).After(); got != nil || err != nil {
+		t.Errorf("After() of the first page got = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestCourseQuery_Less(t *testing.T) {
+	first, second := uuid.MustParse("00000000-0000-0000-0000-000000000001"), uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	now := time.Now()
+	tests := []struct {
+		name  string
+		query CourseQuery
+		a, b  CourseKey
+		want  bool
+	}{
+		{
+			name: "by name",
+			a:    CourseKey{Name: "algebra", Uuid: second},
+			b:    CourseKey{Name: "biology", Uuid: first},
+			want: true,
+		},
+		{
+			name:  "by name descending",
+			query: CourseQuery{Descending: true},
+			a:     CourseKey{Name: "algebra", Uuid: second},
+			b:     CourseKey{Name: "biology", Uuid: first},
+			want:  false,
+		},
+		{
+			name:  "by creation time",
+			query: CourseQuery{Sort: SortByCreatedAt},
+			a:     CourseKey{Name: "biology", CreatedAt: now},
+			b:     CourseKey{Name: "algebra", CreatedAt: now.Add(time.Microsecond)},
+			want:  true,
+		},
+		{
+			name:  "by enrollments",
+			query: CourseQuery{Sort: SortByEnrollments},
+			a:     CourseKey{Enrollments: 3, Uuid: first},
+			b:     CourseKey{Enrollments: 2, Uuid: second},
+			want:  false,
+		},
+		{
+			name:  "same key ordered by uuid",
+			query: CourseQuery{Sort: SortByEnrollments},
+			a:     CourseKey{Enrollments: 2, Uuid: first},
+			b:     CourseKey{Enrollments: 2, Uuid: second},
+			want:  true,
+		},
+		{
+			name:  "same key ordered by uuid descending",
+			query: CourseQuery{Sort: SortByEnrollments, Descending: true},
+			a:     CourseKey{Enrollments: 2, Uuid: first},
+			b:     CourseKey{Enrollments: 2, Uuid: second},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.Less(tt.a, tt.b); got != tt.want {
+				t.Errorf("Less() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCourseQuery_Page(t *testing.T) {
+	var courses []Course
+	for _, name := range []string{"delta", "alpha", "charlie", "bravo", "echo"} {
+		courses = append(courses, Course{CourseMeta: CourseMeta{Uuid: uuid.New(), Name: name}})
+	}
+	query := CourseQuery{Limit: 2}
+	var got []string
+	for pages := 1; ; pages++ {
+		page, err := query.Page(append([]Course(nil), courses...))
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		for _, course := range page.Courses {
+			got = append(got, course.Name)
+		}
+		if page.NextCursor == "" {
+			if pages != 3 {
+				t.Errorf("Page() returned %d pages, want 3", pages)
+			}
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if want := []string{"alpha", "bravo", "charlie", "delta", "echo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Page() got = %v, want %v", got, want)
+	}
+
+	query.Sort = SortByCreatedAt
+	if _, err := query.Page(courses); err == nil {
+		t.Errorf("expected error paging with a cursor of another sort, but none raised")
+	}
+}
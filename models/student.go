@@ -6,3 +6,10 @@ type Student struct {
 	User
 	Faculty string `json:"faculty"`
 }
+
+// Validate returns a *ValidationErr listing the invalid fields of the student.
+func (s Student) Validate() error {
+	validationErr := &ValidationErr{}
+	s.User.validate(validationErr)
+	return validationErr.orNil()
+}
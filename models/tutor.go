@@ -7,3 +7,10 @@ type Tutor struct {
 	Faculty    string `json:"faculty"`
 	LecturerOf string `json:"lecturerOf"`
 }
+
+// Validate returns a *ValidationErr listing the invalid fields of the tutor.
+func (t Tutor) Validate() error {
+	validationErr := &ValidationErr{}
+	t.User.validate(validationErr)
+	return validationErr.orNil()
+}
@@ -12,3 +12,16 @@ type User struct {
 	Name     string    `json:"name"`
 	Lastname string    `json:"lastname"`
 }
+
+// Validate returns a *ValidationErr listing the fields of the user which are empty.
+func (u User) Validate() error {
+	validationErr := &ValidationErr{}
+	u.validate(validationErr)
+	return validationErr.orNil()
+}
+
+func (u User) validate(validationErr *ValidationErr) {
+	validationErr.requireUUID("uuid", u.Uuid)
+	validationErr.requireString("name", u.Name)
+	validationErr.requireString("lastname", u.Lastname)
+}
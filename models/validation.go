@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	validationErrFmt    = "validation failed: %s"
+	canNotBeNegativeFmt = "%s cannot be negative"
+)
+
+// FieldError tells why the value of a field is invalid. Field is the JSON name of the field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErr is returned when a model has invalid fields. It lists every invalid field.
+type ValidationErr struct {
+	Fields []FieldError
+}
+
+// Error implements error.
+func (e *ValidationErr) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return fmt.Sprintf(validationErrFmt, strings.Join(messages, ", "))
+}
+
+// Is reports whether the target is ErrInvalid.
+func (e *ValidationErr) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// add records that the given field is invalid for the given reason, formatted with the field name.
+func (e *ValidationErr) add(field, messageFmt string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(messageFmt, field)})
+}
+
+// requireUUID records the given field unless its value is set.
+func (e *ValidationErr) requireUUID(field string, value uuid.UUID) {
+	if value == uuid.Nil {
+		e.add(field, canNotBeEmptyFmt)
+	}
+}
+
+// requireString records the given field unless its value has non-whitespace characters.
+func (e *ValidationErr) requireString(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		e.add(field, canNotBeEmptyFmt)
+	}
+}
+
+// orNil returns the ValidationErr if it has invalid fields, or nil.
+func (e *ValidationErr) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCourseMeta_Validate(t *testing.T) {
+	courseUUID, tutorUUID := uuid.New(), uuid.New()
+	tests := []struct {
+		name       string
+		courseMeta CourseMeta
+		want       []FieldError
+	}{
+		{
+			name:       "valid course",
+			courseMeta: CourseMeta{Uuid: courseUUID, Name: "Golang", TutorUUID: tutorUUID, MaxStudents: 10},
+		},
+		{
+			name:       "empty course",
+			courseMeta: CourseMeta{},
+			want: []FieldError{
+				{Field: "uuid", Message: "uuid cannot be empty"},
+				{Field: "name", Message: "name cannot be empty"},
+				{Field: "tutorUUID", Message: "tutorUUID cannot be empty"},
+			},
+		},
+		{
+			name:       "blank name and negative capacity",
+			courseMeta: CourseMeta{Uuid: courseUUID, Name: " \t", TutorUUID: tutorUUID, MaxStudents: -1},
+			want: []FieldError{
+				{Field: "name", Message: "name cannot be empty"},
+				{Field: "maxStudents", Message: "maxStudents cannot be negative"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testValidate(t, tt.courseMeta.Validate(), tt.want)
+		})
+	}
+}
+
+func TestStudent_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		student Student
+		want    []FieldError
+	}{
+		{
+			name:    "valid student",
+			student: Student{User: User{Uuid: uuid.New(), Name: "John", Lastname: "Doe"}},
+		},
+		{
+			name:    "blank names",
+			student: Student{User: User{Uuid: uuid.New(), Lastname: " "}, Faculty: "Electronic"},
+			want: []FieldError{
+				{Field: "name", Message: "name cannot be empty"},
+				{Field: "lastname", Message: "lastname cannot be empty"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testValidate(t, tt.student.Validate(), tt.want)
+		})
+	}
+}
+
+func TestTutor_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		tutor Tutor
+		want  []FieldError
+	}{
+		{
+			name:  "valid tutor",
+			tutor: Tutor{User: User{Uuid: uuid.New(), Name: "John", Lastname: "Doe"}},
+		},
+		{
+			name:  "nil UUID",
+			tutor: Tutor{User: User{Name: "John", Lastname: "Doe"}, LecturerOf: "Applied math"},
+			want:  []FieldError{{Field: "uuid", Message: "uuid cannot be empty"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testValidate(t, tt.tutor.Validate(), tt.want)
+		})
+	}
+}
+
+// testValidate checks that err is nil if no field errors are wanted, or a *ValidationErr listing them.
+func testValidate(t *testing.T, err error, want []FieldError) {
+	t.Helper()
+	if want == nil {
+		if err != nil {
+			t.Errorf("Validate() error = %v, want nil", err)
+		}
+		return
+	}
+	var validationErr *ValidationErr
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() error = %v, want a *ValidationErr", err)
+	}
+	if !reflect.DeepEqual(validationErr.Fields, want) {
+		t.Errorf("Validate() fields = %v, want %v", validationErr.Fields, want)
+	}
+}
+
+func TestAPIKeyMeta_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKeyMeta APIKeyMeta
+		want       []FieldError
+	}{
+		{
+			name:       "valid API key",
+			apiKeyMeta: APIKeyMeta{Name: "LMS sync", Scopes: []Scope{ScopeReadOnly, ScopeEnrollmentWrite}},
+		},
+		{
+			name:       "empty API key",
+			apiKeyMeta: APIKeyMeta{},
+			want: []FieldError{
+				{Field: "name", Message: "name cannot be empty"},
+				{Field: "scopes", Message: "scopes cannot be empty"},
+			},
+		},
+		{
+			name:       "unknown scope",
+			apiKeyMeta: APIKeyMeta{Name: "reporting", Scopes: []Scope{ScopeReadOnly, "write", "delete"}},
+			want:       []FieldError{{Field: "scopes", Message: "scopes must only contain known scopes"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testValidate(t, tt.apiKeyMeta.Validate(), tt.want)
+		})
+	}
+}
+
+func TestWebhookMeta_ValidatePublic(t *testing.T) {
+	private := []FieldError{{Field: "url", Message: "url must target a public address"}}
+	tests := []struct {
+		url  string
+		want []FieldError
+	}{
+		{url: "https://example.com/hooks"},
+		{url: "https://93.184.216.34/hooks"},
+		{url: "https://[2606:2800:220:1:248:1893:25c8:1946]/hooks"},
+		{url: "http://localhost:8080/hooks", want: private},
+		{url: "http://api.LOCALHOST./hooks", want: private},
+		{url: "http://127.0.0.1/hooks", want: private},
+		{url: "http://[::1]/hooks", want: private},
+		{url: "http://0.0.0.0/hooks", want: private},
+		{url: "http://10.0.0.8/hooks", want: private},
+		{url: "http://172.16.4.2/hooks", want: private},
+		{url: "http://192.168.1.1/hooks", want: private},
+		{url: "http://[fd00::1]/hooks", want: private},
+		{url: "http://169.254.169.254/latest/meta-data", want: private},
+		{url: "http://[fe80::1]/hooks", want: private},
+		{url: "http://[::ffff:127.0.0.1]/hooks", want: private},
+		{url: "http://100.64.0.1/hooks", want: private},
+		{url: "http://0.1.2.3/hooks", want: private},
+		{url: "http://198.18.0.1/hooks", want: private},
+		{url: "http://255.255.255.255/hooks", want: private},
+		{url: "http://[64:ff9b::a00:1]/hooks", want: private},
+		{url: "http://[2002:a00:1::1]/hooks", want: private},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			testValidate(t, WebhookMeta{URL: tt.url}.ValidatePublic(), tt.want)
+		})
+	}
+}
+
+func TestValidationErr_Error(t *testing.T) {
+	err := &ValidationErr{Fields: []FieldError{
+		{Field: "name", Message: "name cannot be empty"},
+		{Field: "tutorUUID", Message: "tutorUUID cannot be empty"},
+	}}
+	want := "validation failed: name cannot be empty, tutorUUID cannot be empty"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	invalidURLFmt       = "%s must be an absolute http or https URL"
+	unknownEventTypeFmt = "%s must only contain known event types"
+	privateURLFmt       = "%s must target a public address"
+)
+
+// EventTypes lists every EventType, in the order they are documented.
+var EventTypes = []EventType{
+	CourseCreatedEvent,
+	CourseUpdatedEvent,
+	CourseDeletedEvent,
+	StudentRegisteredEvent,
+	StudentUnregisteredEvent,
+	StudentPromotedEvent,
+	StudentLeftWaitlistEvent,
+}
+
+// WebhookMeta defines the attributes of a webhook which are chosen by its tenant.
+type WebhookMeta struct {
+	URL string `json:"url"`
+	// EventTypes are the types of the events POSTed to the URL.
+	EventTypes []EventType `json:"eventTypes"`
+	// Enabled tells whether events are POSTed to the URL. Webhooks are disabled after repeated failures,
+	// and can be enabled again by their tenant.
+	Enabled bool `json:"enabled"`
+}
+
+// Webhook is a subscription of a URL to the events of some types.
+type Webhook struct {
+	WebhookMeta
+	ID uuid.UUID `json:"id"`
+	// Owner is the subject of the principal who has created the webhook, who alone may see and manage it
+	// besides the admins. It is empty for a webhook created by an unauthenticated call.
+	Owner string `json:"owner,omitempty"`
+	// Secret is the key of the HMAC-SHA256 signatures of the payloads. It is only returned on creation.
+	Secret string `json:"secret,omitempty"`
+	// Failures is the number of consecutive failed delivery attempts.
+	Failures   int        `json:"failures"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+}
+
+// Validate returns a *ValidationErr listing the invalid fields of the webhook metadata.
+func (w WebhookMeta) Validate() error {
+	validationErr := &ValidationErr{}
+	if parsed, err := url.Parse(w.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		validationErr.add("url", invalidURLFmt)
+	}
+	if len(w.EventTypes) == 0 {
+		validationErr.add("eventTypes", canNotBeEmptyFmt)
+	}
+	for _, eventType := range w.EventTypes {
+		if !eventType.Known() {
+			validationErr.add("eventTypes", unknownEventTypeFmt)
+			break
+		}
+	}
+	return validationErr.orNil()
+}
+
+// ValidatePublic returns a *ValidationErr if the URL of the webhook metadata targets the local host,
+// or an IP address which is not a PublicIP, e.g. the cloud metadata endpoint.
+// The addresses of other host names can only be checked once they are resolved, see PublicIP.
+func (w WebhookMeta) ValidatePublic() error {
+	validationErr := &ValidationErr{}
+	if parsed, err := url.Parse(w.URL); err == nil {
+		host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+		if ip := net.ParseIP(host); (ip != nil && !PublicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			validationErr.add("url", privateURLFmt)
+		}
+	}
+	return validationErr.orNil()
+}
+
+// nonPublicPrefixes are the networks the webhooks may not target: the special-purpose networks which are not
+// globally reachable, and the NAT64 and 6to4 networks, whose addresses embed IPv4 addresses, e.g. private ones.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // This network.
+	netip.MustParsePrefix("10.0.0.0/8"),      // Private.
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT.
+	netip.MustParsePrefix("127.0.0.0/8"),     // Loopback.
+	netip.MustParsePrefix("169.254.0.0/16"),  // Link-local, e.g. the cloud metadata endpoints.
+	netip.MustParsePrefix("172.16.0.0/12"),   // Private.
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments.
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation.
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast.
+	netip.MustParsePrefix("192.168.0.0/16"),  // Private.
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking.
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation.
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation.
+	netip.MustParsePrefix("224.0.0.0/4"),     // Multicast.
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, and the broadcast address.
+	netip.MustParsePrefix("::/96"),           // Unspecified, loopback and IPv4-compatible.
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64.
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local NAT64.
+	netip.MustParsePrefix("100::/64"),        // Discard.
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation.
+	netip.MustParsePrefix("2002::/16"),       // 6to4.
+	netip.MustParsePrefix("fc00::/7"),        // Unique local.
+	netip.MustParsePrefix("fe80::/10"),       // Link-local.
+	netip.MustParsePrefix("fec0::/10"),       // Site-local.
+	netip.MustParsePrefix("ff00::/8"),        // Multicast.
+}
+
+// PublicIP reports whether the given IP address may be the target of a webhook: it is not in one of the
+// special-purpose networks, e.g. loopback, private, carrier-grade NAT, link-local, multicast or NAT64 ones.
+// IPv4-mapped IPv6 addresses are checked as the IPv4 addresses they map.
+func PublicIP(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscribed reports whether the webhook is enabled and subscribed to the events of the given type.
+func (w WebhookMeta) Subscribed(eventType EventType) bool {
+	if !w.Enabled {
+		return false
+	}
+	for _, subscribed := range w.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Known reports whether the event type is one of EventTypes.
+func (t EventType) Known() bool {
+	for _, eventType := range EventTypes {
+		if eventType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus is the state of a WebhookDelivery.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is the status of a delivery which will be attempted at its NextAttemptAt.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySucceeded is the status of a delivery which the webhook has acknowledged.
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed is the status of a delivery which will not be attempted anymore.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is the delivery of an event to a webhook, and the record of its attempts.
+type WebhookDelivery struct {
+	ID        uuid.UUID         `json:"id"`
+	WebhookID uuid.UUID         `json:"webhookID"`
+	Event     Event             `json:"event"`
+	Status    DeliveryStatus    `json:"status"`
+	Attempts  []DeliveryAttempt `json:"attempts"`
+	// NextAttemptAt is when the delivery will be attempted, if it is pending.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+}
+
+// DeliveryAttempt records an attempt of a WebhookDelivery.
+type DeliveryAttempt struct {
+	Time time.Time `json:"time"`
+	// Error tells why the attempt has failed. It is empty for a successful attempt.
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,34 @@
+// Package publishers provides implementations of services.Publisher delivering the domain events of the courses.
+package publishers
+
+import (
+	"context"
+
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// Channel is a services.Publisher delivering the events to in-process consumers through a Go channel.
+type Channel struct {
+	events chan models.Event
+}
+
+// NewChannel returns a Channel buffering up to the given number of events which have not been consumed yet.
+func NewChannel(buffer int) *Channel {
+	return &Channel{events: make(chan models.Event, buffer)}
+}
+
+// Publish sends the given event to the channel. It blocks until the event is buffered or consumed,
+// or until the given context is done.
+func (c *Channel) Publish(ctx context.Context, event models.Event) error {
+	select {
+	case c.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Events returns the channel receiving the published events.
+func (c *Channel) Events() <-chan models.Event {
+	return c.events
+}
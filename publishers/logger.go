@@ -0,0 +1,32 @@
+package publishers
+
+import (
+	"context"
+	"log"
+
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// Logger is a services.Publisher writing the events to a log, for environments without downstream systems.
+type Logger struct {
+	logger *log.Logger
+}
+
+// NewLogger returns a Logger writing to the given logger, or to the standard logger if it is nil.
+func NewLogger(logger *log.Logger) *Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Logger{logger: logger}
+}
+
+// Publish writes the given event to the log.
+func (l *Logger) Publish(_ context.Context, event models.Event) error {
+	if event.StudentUUID != nil {
+		l.logger.Printf("event %v: %v of the student %v in the course %v by %q",
+			event.ID, event.Type, *event.StudentUUID, event.CourseUUID, event.Actor)
+		return nil
+	}
+	l.logger.Printf("event %v: %v of the course %v by %q", event.ID, event.Type, event.CourseUUID, event.Actor)
+	return nil
+}
@@ -0,0 +1,224 @@
+package publishers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func newEvent() models.Event {
+	studentUUID := uuid.New()
+	return models.Event{
+		ID:          uuid.New(),
+		Type:        models.StudentRegisteredEvent,
+		Time:        time.Date(2022, 3, 1, 10, 30, 0, 123000, time.UTC),
+		Actor:       "alice",
+		CourseUUID:  uuid.New(),
+		StudentUUID: &studentUUID,
+		Status:      "enrolled",
+	}
+}
+
+func TestChannel(t *testing.T) {
+	channel := NewChannel(1)
+	event := newEvent()
+	if err := channel.Publish(context.TODO(), event); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	if err := channel.Publish(ctx, newEvent()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Publish() to a full channel error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if got := <-channel.Events(); !reflect.DeepEqual(got, event) {
+		t.Errorf("Events() got = %v, want %v", got, event)
+	}
+	select {
+	case got := <-channel.Events():
+		t.Errorf("Events() got = %v, want no more events", got)
+	default:
+	}
+}
+
+func TestLogger_Publish(t *testing.T) {
+	var buf bytes.Buffer
+	event := newEvent()
+	if err := NewLogger(log.New(&buf, "", 0)).Publish(context.TODO(), event); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for _, want := range []string{event.ID.String(), string(event.Type), event.StudentUUID.String(), event.CourseUUID.String()} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Publish() logged %q, want it to contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestWebhook_Publish(t *testing.T) {
+	event := newEvent()
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "delivered", status: http.StatusOK},
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "rejected", status: http.StatusBadRequest, wantErr: true},
+		{name: "failed", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.Event
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("got a %v request of %v, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+				}
+				if r.Header.Get("X-Event-Id") != event.ID.String() || r.Header.Get("X-Event-Type") != string(event.Type) {
+					t.Errorf("got the event headers %v, want the ID and type of the event", r.Header)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("unable to decode the event: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewWebhook(server.URL, server.Client()).Publish(context.TODO(), event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, event) {
+				t.Errorf("the webhook got = %v, want %v", got, event)
+			}
+		})
+	}
+}
+
+func TestWebhook_Publish_signed(t *testing.T) {
+	const secret = "s3cr3t"
+	var signature, timestamp string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(HeaderSignature)
+		timestamp = r.Header.Get(HeaderSignatureTimestamp)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	if err := NewWebhook(server.URL, nil).Publish(context.TODO(), newEvent()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if signature != "" || timestamp != "" {
+		t.Errorf("an unsigned webhook sent the signature %q at %q", signature, timestamp)
+	}
+
+	signedAt := time.Unix(1700000000, 0)
+	webhook := NewSignedWebhook(server.URL, secret, nil)
+	webhook.now = func() time.Time { return signedAt }
+	if err := webhook.Publish(context.TODO(), newEvent()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1700000000."))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want || timestamp != "1700000000" {
+		t.Errorf("got the signature %q at %q, want %q at 1700000000", signature, timestamp, want)
+	}
+	if err := Verify(secret, signature, timestamp, body, signedAt.Add(SignatureTolerance)); err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "s3cr3t"
+	payload := []byte(`{"type":"CourseCreated"}`)
+	signedAt := time.Unix(1700000000, 0)
+	signature := Sign(secret, signedAt.Unix(), payload)
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		payload   []byte
+		now       time.Time
+		wantErr   error
+	}{
+		{name: "valid", secret: secret, signature: signature, timestamp: "1700000000", payload: payload, now: signedAt},
+		{name: "valid before the timestamp", secret: secret, signature: signature, timestamp: "1700000000",
+			payload: payload, now: signedAt.Add(-SignatureTolerance)},
+		{name: "expired", secret: secret, signature: signature, timestamp: "1700000000", payload: payload,
+			now: signedAt.Add(SignatureTolerance + time.Second), wantErr: ErrSignatureExpired},
+		{name: "from the future", secret: secret, signature: signature, timestamp: "1700000000", payload: payload,
+			now: signedAt.Add(-SignatureTolerance - time.Second), wantErr: ErrSignatureExpired},
+		{name: "other timestamp", secret: secret, signature: signature, timestamp: "1700000001", payload: payload,
+			now: signedAt, wantErr: ErrInvalidSignature},
+		{name: "other payload", secret: secret, signature: signature, timestamp: "1700000000",
+			payload: []byte(`{"type":"CourseDeleted"}`), now: signedAt, wantErr: ErrInvalidSignature},
+		{name: "other secret", secret: "other", signature: signature, timestamp: "1700000000", payload: payload,
+			now: signedAt, wantErr: ErrInvalidSignature},
+		{name: "malformed timestamp", secret: secret, signature: signature, timestamp: "now", payload: payload,
+			now: signedAt, wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.signature, tt.timestamp, tt.payload, tt.now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhook_Publish_unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	if err := NewWebhook(server.URL, nil).Publish(context.TODO(), newEvent()); err == nil {
+		t.Errorf("expected error publishing to a closed server, but none raised")
+	}
+}
+
+func TestNewPublicClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("the public client has connected to the local server")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "loopback", url: server.URL},
+		{name: "local host name", url: strings.Replace(server.URL, "127.0.0.1", "localhost", 1)},
+		{name: "this network", url: "http://0.0.0.1/hooks"},
+		{name: "private", url: "http://10.0.0.1/hooks"},
+		{name: "carrier-grade NAT", url: "http://100.64.0.1/hooks"},
+		{name: "link-local", url: "http://169.254.169.254/latest/meta-data"},
+		{name: "benchmarking", url: "http://198.18.0.1/hooks"},
+		{name: "IPv4-mapped private", url: "http://[::ffff:10.0.0.1]/hooks"},
+		{name: "NAT64 of a private address", url: "http://[64:ff9b::a00:1]/hooks"},
+		{name: "unique local", url: "http://[fd00::1]/hooks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewWebhook(tt.url, NewPublicClient(time.Second)).Publish(context.TODO(), newEvent())
+			if !errors.Is(err, ErrPrivateAddress) {
+				t.Errorf("Publish() to %v error = %v, want %v", tt.url, err, ErrPrivateAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package publishers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+const (
+	// HeaderSignature is the header of the HMAC-SHA256 signature of the payloads of a signed Webhook.
+	HeaderSignature = "X-Signature-256"
+	// HeaderSignatureTimestamp is the header of the time a payload of a signed Webhook has been signed at,
+	// in seconds since the Unix epoch.
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+	// SignatureTolerance is how far the timestamp of a signature may be from the time it is verified at,
+	// so that receivers refuse the requests replayed later on.
+	SignatureTolerance = 5 * time.Minute
+
+	headerEventID   = "X-Event-Id"
+	headerEventType = "X-Event-Type"
+	signaturePrefix = "sha256="
+)
+
+// ErrPrivateAddress is the error of the clients returned by NewPublicClient which refuse to connect to an address
+// which is not a models.PublicIP.
+var ErrPrivateAddress = errors.New("the address is not public")
+
+var (
+	// ErrInvalidSignature is the error of Verify for a signature which does not match the payload and timestamp.
+	ErrInvalidSignature = errors.New("the signature is invalid")
+	// ErrSignatureExpired is the error of Verify for a timestamp further than SignatureTolerance from now.
+	ErrSignatureExpired = errors.New("the signature timestamp is outside the tolerance window")
+)
+
+// NewPublicClient returns an HTTP client timing out after the given timeout, which only connects to public addresses,
+// as checked by models.PublicIP once the host names are resolved, so that the webhooks of the tenants cannot reach
+// the internal services, including through DNS names or redirects. It ignores the proxy of the environment,
+// which would hide the addresses.
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   controlPublic,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// controlPublic refuses the connections to the resolved addresses which are not public, before they are made.
+func controlPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !models.PublicIP(ip) {
+		return fmt.Errorf("unable to connect to %v: %w", address, ErrPrivateAddress)
+	}
+	return nil
+}
+
+// Webhook is a services.Publisher POSTing the events as JSON to a URL.
+// An event is delivered once the URL answers it with a 2xx status.
+type Webhook struct {
+	url    string
+	secret string
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhook returns a Webhook POSTing the events to the given URL with the given HTTP client, which should time out,
+// or with http.DefaultClient if it is nil.
+func NewWebhook(url string, client *http.Client) *Webhook {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Webhook{url: url, client: client, now: time.Now}
+}
+
+// NewSignedWebhook returns a Webhook like NewWebhook, which also signs the payloads with the given secret.
+func NewSignedWebhook(url, secret string, client *http.Client) *Webhook {
+	webhook := NewWebhook(url, client)
+	webhook.secret = secret
+	return webhook
+}
+
+// Sign returns the signature of the given payload signed at the given time, in seconds since the Unix epoch,
+// with the given secret, as sent in the HeaderSignature: "sha256=" followed by the hex-encoded HMAC-SHA256
+// of the timestamp, a dot and the payload. Receivers should check it with Verify.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the given signature and timestamp, as received in the HeaderSignature and HeaderSignatureTimestamp,
+// against the raw request body with the given secret. It returns ErrSignatureExpired if the timestamp is further
+// than SignatureTolerance from now, and ErrInvalidSignature if the signature does not match.
+func Verify(secret, signature, timestamp string, payload []byte, now time.Time) error {
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("unable to parse the signature timestamp %q: %w", timestamp, ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(signedAt, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return ErrSignatureExpired
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, signedAt, payload))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Publish POSTs the given event to the URL of the Webhook.
+// The ID and type of the event are also sent in the X-Event-Id and X-Event-Type headers,
+// and the signature of the payload and its timestamp in the HeaderSignature and HeaderSignatureTimestamp
+// if the Webhook is signed.
+func (w *Webhook) Publish(ctx context.Context, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("unable to encode the event: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create the webhook request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(headerEventID, event.ID.String())
+	request.Header.Set(headerEventType, string(event.Type))
+	if w.secret != "" {
+		timestamp := w.now().Unix()
+		request.Header.Set(HeaderSignatureTimestamp, strconv.FormatInt(timestamp, 10))
+		request.Header.Set(HeaderSignature, Sign(w.secret, timestamp, body))
+	}
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to call the webhook: %w", err)
+	}
+	defer response.Body.Close()
+	// Draining the body lets the connection be reused.
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("the webhook answered with the status %d", response.StatusCode)
+	}
+	return nil
+}
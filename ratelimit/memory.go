@@ -0,0 +1,92 @@
+// Package ratelimit provides implementations of services.RateLimitStore keeping the token buckets of the clients.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+// sweepInterval is the number of Take calls after which a MemoryStore discards the full buckets,
+// which are the same as missing ones.
+const sweepInterval = 1000
+
+// bucket is the state of a token bucket at the time it has last been taken from.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   services.RateLimit
+}
+
+// MemoryStore is a services.RateLimitStore keeping the token buckets in memory, so the limits are enforced
+// per instance of the service. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+// NewMemoryStore returns a MemoryStore whose buckets are all full.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take implements services.RateLimitStore.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit services.RateLimit, now time.Time) (services.RateLimitDecision, error) {
+	if err := ctx.Err(); err != nil {
+		return services.RateLimitDecision{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.takes++
+	if s.takes%sweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+	decision := services.RateLimitDecision{}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = b.delay(1 - b.tokens)
+	}
+	decision.Remaining = int(math.Floor(b.tokens))
+	decision.Reset = b.delay(float64(limit.Requests) - b.tokens)
+	return decision, nil
+}
+
+// sweep discards the buckets which are full at the given time. It must be called with s.mu held.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.limit.Period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// refill adds the tokens refilled since the bucket has last been updated, up to its capacity.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Requests), b.tokens+elapsed.Seconds()*b.rate())
+		b.updated = now
+	}
+}
+
+// delay returns the time it takes to refill the given number of tokens.
+func (b *bucket) delay(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate() * float64(time.Second))
+}
+
+// rate returns the number of tokens refilled per second.
+func (b *bucket) rate() float64 {
+	return float64(b.limit.Requests) / b.limit.Period.Seconds()
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+var _ services.RateLimitStore = (*MemoryStore)(nil)
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.TODO()
+	store := NewMemoryStore()
+	limit := services.RateLimit{Requests: 2, Period: time.Minute}
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	take := func(key string, want services.RateLimitDecision) {
+		t.Helper()
+		got, err := store.Take(ctx, key, limit, now)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("Take(%q) got = %+v, %v, want %+v", key, got, err, want)
+		}
+	}
+
+	// A burst of Requests requests is allowed, and the bucket is refilled at the rate of a token every 30s.
+	take("client", services.RateLimitDecision{Allowed: true, Remaining: 1, Reset: 30 * time.Second})
+	take("client", services.RateLimitDecision{Allowed: true, Remaining: 0, Reset: time.Minute})
+	take("client", services.RateLimitDecision{RetryAfter: 30 * time.Second, Reset: time.Minute})
+	take("other", services.RateLimitDecision{Allowed: true, Remaining: 1, Reset: 30 * time.Second})
+
+	now = now.Add(15 * time.Second)
+	take("client", services.RateLimitDecision{RetryAfter: 15 * time.Second, Reset: 45 * time.Second})
+	now = now.Add(15 * time.Second)
+	take("client", services.RateLimitDecision{Allowed: true, Remaining: 0, Reset: time.Minute})
+
+	// The bucket does not hold more than Requests tokens.
+	now = now.Add(time.Hour)
+	take("client", services.RateLimitDecision{Allowed: true, Remaining: 1, Reset: 30 * time.Second})
+
+	// Another limit starts a full bucket.
+	limit = services.RateLimit{Requests: 10, Period: time.Second}
+	take("client", services.RateLimitDecision{Allowed: true, Remaining: 9, Reset: 100 * time.Millisecond})
+}
+
+func TestMemoryStore_sweep(t *testing.T) {
+	ctx := context.TODO()
+	store := NewMemoryStore()
+	limit := services.RateLimit{Requests: 1, Period: time.Minute}
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	if _, err := store.Take(ctx, "idle", limit, now); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	now = now.Add(time.Minute)
+	for i := 1; i < sweepInterval; i++ {
+		if _, err := store.Take(ctx, "busy", limit, now); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	if _, ok := store.buckets["idle"]; ok || len(store.buckets) != 1 {
+		t.Errorf("expected only the bucket of the busy client to be kept, got %v", store.buckets)
+	}
+}
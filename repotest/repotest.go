@@ -0,0 +1,909 @@
+// Package repotest provides a conformance test suite for the implementations of services.Repo.
+// Every implementation should pass it, e.g.
+//
+//	func TestRepo_conformance(t *testing.T) {
+//		repotest.Run(t, func() services.Repo { return NewRepo(nil, nil, nil) })
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+)
+
+// concurrentUpdates is the number of units of work updating the same course at the same time.
+const concurrentUpdates = 10
+
+// Run checks that the Repos returned by newRepo honour the contract of services.Repo.
+// newRepo is called once per subtest, and must return an empty Repo.
+func Run(t *testing.T, newRepo func() services.Repo) {
+	t.Run("courses", func(t *testing.T) {
+		testCourses(t, newRepo())
+	})
+	t.Run("tutors", func(t *testing.T) {
+		testTutors(t, newRepo())
+	})
+	t.Run("students", func(t *testing.T) {
+		testStudents(t, newRepo())
+	})
+	t.Run("students in courses", func(t *testing.T) {
+		testStudentsInCourses(t, newRepo())
+	})
+	t.Run("ByTutor and ByStudent", func(t *testing.T) {
+		testByTutorAndStudent(t, newRepo())
+	})
+	t.Run("List", func(t *testing.T) {
+		testList(t, newRepo())
+	})
+	t.Run("outbox", func(t *testing.T) {
+		testOutbox(t, newRepo())
+	})
+	t.Run("audit log", func(t *testing.T) {
+		testAuditLog(t, newRepo())
+	})
+	t.Run("not found", func(t *testing.T) {
+		testNotFound(t, newRepo)
+	})
+	t.Run("already exists", func(t *testing.T) {
+		testAlreadyExists(t, newRepo())
+	})
+	t.Run("idempotent delete", func(t *testing.T) {
+		testDelete(t, newRepo())
+	})
+	t.Run("concurrent updates", func(t *testing.T) {
+		testConcurrentUpdates(t, newRepo())
+	})
+	t.Run("context cancellation", func(t *testing.T) {
+		testContextCancellation(t, newRepo())
+	})
+}
+
+// testCourses exercises a round-trip of a course, including its enrollments, waitlist, version and audit metadata.
+func testCourses(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	tutor := newTutor(t, repo)
+	course := newCourse(t, repo, tutor.Uuid, 2)
+	course.Waitlist = []uuid.UUID{newStudent(t, repo).Uuid}
+	createdAt := time.Date(2022, 3, 1, 10, 30, 0, 123000, time.UTC)
+	course.CreatedAt, course.CreatedBy = createdAt, "alice"
+	course.UpdatedAt, course.UpdatedBy = createdAt, "alice"
+	for studentUUID := range course.Students {
+		course.Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID, EnrolledAt: createdAt}
+	}
+	if err := repo.Create(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course.Version = 1
+	checkCourse(t, repo, course)
+
+	page, err := repo.List(ctx, models.CourseQuery{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got := courseUUIDs(page.Courses); !reflect.DeepEqual(got, []uuid.UUID{course.Uuid}) {
+		t.Errorf("List() got = %v, want %v", got, course.Uuid)
+	}
+
+	updated := models.Course{
+		CourseMeta: models.CourseMeta{
+			Uuid:        course.Uuid,
+			Name:        "renamed course",
+			TutorUUID:   newTutor(t, repo).Uuid,
+			MaxStudents: 10,
+		},
+		Students:  make(map[uuid.UUID]models.Enrollment),
+		Waitlist:  []uuid.UUID{newStudent(t, repo).Uuid, course.Waitlist[0]},
+		Version:   1,
+		UpdatedAt: createdAt.Add(time.Hour),
+		UpdatedBy: "bob",
+	}
+	student := newStudent(t, repo)
+	updated.Students[student.Uuid] = models.Enrollment{StudentUUID: student.Uuid, EnrolledAt: updated.UpdatedAt}
+	if err = repo.Update(ctx, updated); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	// The creation time and creator are kept.
+	updated.Version, updated.CreatedAt, updated.CreatedBy = 2, createdAt, "alice"
+	checkCourse(t, repo, updated)
+
+	var conflictErr *models.VersionConflictErr
+	if err = repo.Update(ctx, course); !errors.As(err, &conflictErr) {
+		t.Errorf("Update() error = %v, want a version conflict", err)
+	} else if want := models.NewVersionConflictErr(course.Uuid, 1, 2); *conflictErr != *want {
+		t.Errorf("Update() conflict = %+v, want %+v", *conflictErr, *want)
+	}
+	checkCourse(t, repo, updated)
+
+	if err = repo.Delete(ctx, course.Uuid); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	page, err = repo.List(ctx, models.CourseQuery{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(page.Courses) != 0 {
+		t.Errorf("List() got = %v, want no courses", page.Courses)
+	}
+}
+
+// testTutors exercises a round-trip of a tutor.
+func testTutors(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	tutor := newTutor(t, repo)
+	if err := repo.CreateTutor(ctx, tutor); err == nil {
+		t.Errorf("expected error creating a tutor twice, but none raised")
+	}
+
+	tutor.Lastname = "Brown"
+	tutor.LecturerOf = "Rust"
+	if err := repo.UpdateTutor(ctx, tutor); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err := repo.TutorById(ctx, tutor.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, tutor) {
+		t.Errorf("TutorById() got = %v, want %v", *got, tutor)
+	}
+	tutors, err := repo.ListTutors(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(tutors, []models.Tutor{tutor}) {
+		t.Errorf("ListTutors() got = %v, want %v", tutors, tutor)
+	}
+
+	if err = repo.DeleteTutor(ctx, tutor.Uuid); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err = repo.TutorById(ctx, tutor.Uuid)
+	checkNotFound(t, "TutorById() after DeleteTutor()", got, err, models.NewTutorNotFoundErr(tutor.Uuid))
+}
+
+// testStudents exercises a round-trip of a student.
+func testStudents(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	student := newStudent(t, repo)
+	if err := repo.CreateStudent(ctx, student); err == nil {
+		t.Errorf("expected error creating a student twice, but none raised")
+	}
+
+	student.Lastname = "Jones"
+	student.Faculty = "Physics"
+	if err := repo.UpdateStudent(ctx, student); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err := repo.StudentById(ctx, student.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, student) {
+		t.Errorf("StudentById() got = %v, want %v", *got, student)
+	}
+	students, err := repo.ListStudents(ctx)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(students, []models.Student{student}) {
+		t.Errorf("ListStudents() got = %v, want %v", students, student)
+	}
+
+	if err = repo.DeleteStudent(ctx, student.Uuid); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err = repo.StudentById(ctx, student.Uuid)
+	checkNotFound(t, "StudentById() after DeleteStudent()", got, err, models.NewStudentNotFoundErr(student.Uuid))
+}
+
+// testStudentsInCourses checks that the courses a student is registered to or waitlisted for are counted,
+// and that such a student cannot be deleted.
+func testStudentsInCourses(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	tutor := newTutor(t, repo)
+	student := newStudent(t, repo).Uuid
+	enrolled := newCourse(t, repo, tutor.Uuid, 0)
+	enrolled.Students[student] = models.Enrollment{StudentUUID: student}
+	waitlisted := newCourse(t, repo, tutor.Uuid, 0)
+	waitlisted.Waitlist = []uuid.UUID{student}
+	other := newCourse(t, repo, tutor.Uuid, 1)
+	for _, course := range []models.Course{enrolled, waitlisted, other} {
+		if err := repo.Create(ctx, course); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	if got, err := repo.CountStudentCourses(ctx, student); err != nil || got != 2 {
+		t.Errorf("CountStudentCourses() got = %v, %v, want 2", got, err)
+	}
+	if err := repo.DeleteStudent(ctx, student); !errors.Is(err, models.ErrConstraint) {
+		t.Errorf("DeleteStudent() error = %v, want %v", err, models.ErrConstraint)
+	}
+	if _, err := repo.StudentById(ctx, student); err != nil {
+		t.Errorf("StudentById() after a failed DeleteStudent() error = %v, want nil", err)
+	}
+
+	for _, course := range []models.Course{enrolled, waitlisted} {
+		if err := repo.Delete(ctx, course.Uuid); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	if got, err := repo.CountStudentCourses(ctx, student); err != nil || got != 0 {
+		t.Errorf("CountStudentCourses() got = %v, %v, want 0", got, err)
+	}
+	if err := repo.DeleteStudent(ctx, student); err != nil {
+		t.Errorf("DeleteStudent() error = %v, want nil", err)
+	}
+}
+
+// testByTutorAndStudent checks that ByTutor and ByStudent return the courses facilitated by a tutor,
+// and the courses a student is enrolled in, regardless of the waitlists.
+func testByTutorAndStudent(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	tutorA, tutorB := newTutor(t, repo), newTutor(t, repo)
+	studentA, studentB, studentC := newStudent(t, repo), newStudent(t, repo), newStudent(t, repo)
+
+	course1 := newCourse(t, repo, tutorA.Uuid, 0)
+	course1.Students[studentA.Uuid] = models.Enrollment{StudentUUID: studentA.Uuid}
+	course1.Students[studentB.Uuid] = models.Enrollment{StudentUUID: studentB.Uuid}
+	course2 := newCourse(t, repo, tutorA.Uuid, 0)
+	course2.Students[studentB.Uuid] = models.Enrollment{StudentUUID: studentB.Uuid}
+	course3 := newCourse(t, repo, tutorB.Uuid, 0)
+	course3.Waitlist = []uuid.UUID{studentC.Uuid}
+	for _, course := range []models.Course{course1, course2, course3} {
+		if err := repo.Create(ctx, course); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	byTutor := []struct {
+		tutorUUID uuid.UUID
+		want      []uuid.UUID
+	}{
+		{tutorUUID: tutorA.Uuid, want: sortedUUIDs(course1.Uuid, course2.Uuid)},
+		{tutorUUID: tutorB.Uuid, want: sortedUUIDs(course3.Uuid)},
+		{tutorUUID: uuid.New(), want: nil},
+	}
+	for _, tt := range byTutor {
+		courses, err := repo.ByTutor(ctx, tt.tutorUUID)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if got := courseUUIDs(courses); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ByTutor(%v) got = %v, want %v", tt.tutorUUID, got, tt.want)
+		}
+	}
+
+	course1.Version = 1
+	delete(course1.Students, studentB.Uuid)
+	if err := repo.Update(ctx, course1); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	byStudent := []struct {
+		studentUUID uuid.UUID
+		want        []uuid.UUID
+	}{
+		{studentUUID: studentA.Uuid, want: sortedUUIDs(course1.Uuid)},
+		{studentUUID: studentB.Uuid, want: sortedUUIDs(course2.Uuid)},
+		{studentUUID: studentC.Uuid, want: nil},
+	}
+	for _, tt := range byStudent {
+		courses, err := repo.ByStudent(ctx, tt.studentUUID)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if got := courseUUIDs(courses); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ByStudent(%v) got = %v, want %v", tt.studentUUID, got, tt.want)
+		}
+	}
+}
+
+// testList checks the filters, the orders and the pagination of the courses listed by a models.CourseQuery.
+func testList(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	tutorA := newTutor(t, repo)
+	tutorB := models.Tutor{User: models.User{Uuid: uuid.New(), Name: "Marie", Lastname: "Curie"}, Faculty: "Physics"}
+	if err := repo.CreateTutor(ctx, tutorB); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	createdAt := time.Date(2022, 3, 1, 10, 30, 0, 123000, time.UTC)
+	course := func(name string, tutorUUID uuid.UUID, numberOfStudents, maxStudents int, age time.Duration) models.Course {
+		course := newCourse(t, repo, tutorUUID, numberOfStudents)
+		course.Name = name
+		course.MaxStudents = maxStudents
+		course.CreatedAt = createdAt.Add(-age)
+		course.Version = 1
+		return course
+	}
+	algebra := course("algebra", tutorA.Uuid, 1, 1, 3*time.Hour)
+	biology := course("biology", tutorB.Uuid, 0, 0, 2*time.Hour)
+	chemistry := course("chemistry", tutorA.Uuid, 2, 0, time.Hour)
+	microbiology := course("microbiology", tutorB.Uuid, 3, 5, 4*time.Hour)
+	zoology := course("zoology", tutorB.Uuid, 0, 0, 0)
+	// biology and zoology have the same number of enrollments, hence they are ordered by UUID.
+	if zoology.Uuid.String() < biology.Uuid.String() {
+		biology.Uuid, zoology.Uuid = zoology.Uuid, biology.Uuid
+	}
+	for _, course := range []models.Course{algebra, biology, chemistry, microbiology, zoology} {
+		if err := repo.Create(ctx, course); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	page, err := repo.List(ctx, models.CourseQuery{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := (&models.CoursePage{Courses: []models.Course{algebra, biology, chemistry, microbiology, zoology}}); !reflect.DeepEqual(page, want) {
+		t.Errorf("List() got = %v, want %v", page, want)
+	}
+
+	tests := []struct {
+		name  string
+		query models.CourseQuery
+		want  []string
+	}{
+		{
+			name:  "by tutor",
+			query: models.CourseQuery{CourseFilter: models.CourseFilter{TutorUUID: tutorA.Uuid}},
+			want:  []string{"algebra", "chemistry"},
+		},
+		{
+			name:  "by faculty",
+			query: models.CourseQuery{CourseFilter: models.CourseFilter{Faculty: "Physics"}},
+			want:  []string{"biology", "microbiology", "zoology"},
+		},
+		{
+			name:  "by name ignoring the case",
+			query: models.CourseQuery{CourseFilter: models.CourseFilter{NameContains: "BIO"}},
+			want:  []string{"biology", "microbiology"},
+		},
+		{
+			name:  "by name with wildcards",
+			query: models.CourseQuery{CourseFilter: models.CourseFilter{NameContains: "o_o"}},
+		},
+		{
+			name:  "with free seats",
+			query: models.CourseQuery{CourseFilter: models.CourseFilter{HasFreeSeats: true, DefaultCapacity: 2}},
+			want:  []string{"biology", "microbiology", "zoology"},
+		},
+		{
+			name: "by faculty with free seats",
+			query: models.CourseQuery{CourseFilter: models.CourseFilter{
+				Faculty: "Computer Science", HasFreeSeats: true, DefaultCapacity: 3,
+			}},
+			want: []string{"chemistry"},
+		},
+		{
+			name:  "by name descending",
+			query: models.CourseQuery{Sort: models.SortByName, Descending: true},
+			want:  []string{"zoology", "microbiology", "chemistry", "biology", "algebra"},
+		},
+		{
+			name:  "by creation time",
+			query: models.CourseQuery{Sort: models.SortByCreatedAt},
+			want:  []string{"microbiology", "algebra", "biology", "chemistry", "zoology"},
+		},
+		{
+			name:  "by creation time descending",
+			query: models.CourseQuery{Sort: models.SortByCreatedAt, Descending: true},
+			want:  []string{"zoology", "chemistry", "biology", "algebra", "microbiology"},
+		},
+		{
+			name:  "by enrollments",
+			query: models.CourseQuery{Sort: models.SortByEnrollments},
+			want:  []string{"biology", "zoology", "algebra", "chemistry", "microbiology"},
+		},
+		{
+			name:  "by enrollments descending",
+			query: models.CourseQuery{Sort: models.SortByEnrollments, Descending: true},
+			want:  []string{"microbiology", "chemistry", "algebra", "zoology", "biology"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.List(ctx, tt.query)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if got := courseNames(page.Courses); !reflect.DeepEqual(got, tt.want) || page.NextCursor != "" {
+				t.Errorf("List() got = %v and cursor %q, want %v and no cursor", got, page.NextCursor, tt.want)
+			}
+
+			// Every course is listed once by the pages of 2 courses.
+			query, got, pages := tt.query, []string(nil), 0
+			query.Limit = 2
+			for {
+				page, err := repo.List(ctx, query)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				got, pages = append(got, courseNames(page.Courses)...), pages+1
+				if page.NextCursor == "" {
+					break
+				}
+				if pages > len(tt.want) {
+					t.Fatalf("List() does not stop paging, got %v", got)
+				}
+				query.Cursor = page.NextCursor
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() pages got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("cursor of another order", func(t *testing.T) {
+		page, err := repo.List(ctx, models.CourseQuery{Limit: 2})
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		query := models.CourseQuery{Sort: models.SortByCreatedAt, Limit: 2, Cursor: page.NextCursor}
+		if _, err = repo.List(ctx, query); err == nil {
+			t.Errorf("expected error listing with the cursor of another order, but none raised")
+		}
+	})
+}
+
+// testOutbox checks that the events of the outbox are kept in order until they are published,
+// and only if the unit of work storing them succeeds.
+func testOutbox(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	studentUUID := newStudent(t, repo).Uuid
+	course := newCourse(t, repo, newTutor(t, repo).Uuid, 0)
+	course.Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID}
+	course.CreatedAt = time.Date(2022, 3, 1, 10, 30, 0, 123000, time.UTC)
+	course.Version = 1
+	var events []models.Event
+	for i := 0; i < 5; i++ {
+		events = append(events, models.Event{
+			ID:         uuid.New(),
+			Type:       models.CourseUpdatedEvent,
+			Time:       course.CreatedAt.Add(time.Duration(i) * time.Second),
+			Actor:      "alice",
+			CourseUUID: course.Uuid,
+			Course:     &course,
+		})
+	}
+	events[1].Type, events[1].StudentUUID, events[1].Status = models.StudentRegisteredEvent, &studentUUID, "enrolled"
+	events[4].Type, events[4].Course = models.CourseDeletedEvent, nil
+
+	if err := repo.AddToOutbox(ctx, events[:3]...); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	errRollback := errors.New("rollback")
+	err := repo.WithTx(ctx, func(ctx context.Context) error {
+		if err := repo.AddToOutbox(ctx, models.Event{ID: uuid.New(), Type: models.CourseDeletedEvent}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errRollback)
+	}
+	err = repo.WithTx(ctx, func(ctx context.Context) error {
+		return repo.AddToOutbox(ctx, events[3:]...)
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	got, err := repo.PendingEvents(ctx, "first", 10)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(got, events) {
+		t.Errorf("PendingEvents() got = %v, want %v", got, events)
+	}
+	if got, err = repo.PendingEvents(ctx, "first", 2); err != nil || !reflect.DeepEqual(got, events[:2]) {
+		t.Errorf("PendingEvents() with a limit got = %v, %v, want %v", got, err, events[:2])
+	}
+
+	// Every consumer publishes the events on its own.
+	if err = repo.MarkPublished(ctx, "first", events[0].ID, events[2].ID, uuid.New()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err = repo.MarkPublished(ctx, "first", events[0].ID); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err = repo.MarkPublished(ctx, "second", events[0].ID, events[1].ID); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err = repo.PendingEvents(ctx, "first", 10)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if want := []models.Event{events[1], events[3], events[4]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PendingEvents() after MarkPublished() got = %v, want %v", got, want)
+	}
+	if got, err = repo.PendingEvents(ctx, "second", 2); err != nil || !reflect.DeepEqual(got, events[2:4]) {
+		t.Errorf("PendingEvents() of another consumer got = %v, %v, want %v", got, err, events[2:4])
+	}
+
+	// Only the events published by every consumer are removed.
+	if err = repo.RemovePublished(ctx, "first", "second"); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got, err = repo.PendingEvents(ctx, "third", 10); err != nil || !reflect.DeepEqual(got, events[1:]) {
+		t.Errorf("PendingEvents() after RemovePublished() got = %v, %v, want %v", got, err, events[1:])
+	}
+	if got, err = repo.PendingEvents(ctx, "second", 10); err != nil || !reflect.DeepEqual(got, events[2:]) {
+		t.Errorf("PendingEvents() of another consumer after RemovePublished() got = %v, %v, want %v", got, err, events[2:])
+	}
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err = repo.AddToOutbox(cancelledCtx, models.Event{ID: uuid.New()}); !errors.Is(err, context.Canceled) {
+		t.Errorf("AddToOutbox() error = %v, want %v", err, context.Canceled)
+	}
+	if got, err = repo.PendingEvents(ctx, "third", 10); err != nil || len(got) != 4 {
+		t.Errorf("PendingEvents() after a cancelled AddToOutbox() got = %v, %v, want 4 events", got, err)
+	}
+}
+
+// testAuditLog checks that the Repos implementing services.AuditSink record the events within their units of work.
+func testAuditLog(t *testing.T, repo services.Repo) {
+	sink, ok := repo.(services.AuditSink)
+	if !ok {
+		t.Skip("the repo is not an AuditSink")
+	}
+	ctx := context.TODO()
+	studentUUID := newStudent(t, repo).Uuid
+	course := newCourse(t, repo, newTutor(t, repo).Uuid, 0)
+	course.CreatedAt = time.Date(2022, 3, 1, 10, 30, 0, 123000, time.UTC)
+	course.Version = 1
+	registered := course
+	registered.Students = map[uuid.UUID]models.Enrollment{studentUUID: {StudentUUID: studentUUID, EnrolledAt: course.CreatedAt}}
+	registered.Version = 2
+	events := []models.AuditEvent{
+		{Time: course.CreatedAt, Actor: "alice", Action: models.CourseCreated, CourseUUID: course.Uuid, After: &course},
+		{
+			Time:        course.CreatedAt.Add(time.Minute),
+			Actor:       "bob",
+			Action:      models.StudentRegistered,
+			CourseUUID:  course.Uuid,
+			StudentUUID: &studentUUID,
+			Before:      &course,
+			After:       &registered,
+		},
+	}
+
+	if err := sink.Record(ctx, events[0]); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := sink.Record(ctx, models.AuditEvent{Time: course.CreatedAt, Action: models.CourseCreated, CourseUUID: uuid.New()}); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	errRollback := errors.New("rollback")
+	err := repo.WithTx(ctx, func(ctx context.Context) error {
+		if err := sink.Record(ctx, models.AuditEvent{Time: course.CreatedAt, Action: models.CourseDeleted, CourseUUID: course.Uuid}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errRollback)
+	}
+	err = repo.WithTx(ctx, func(ctx context.Context) error {
+		return sink.Record(ctx, events[1])
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	got, err := sink.History(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(got, events) {
+		t.Errorf("History() got = %v, want %v", got, events)
+	}
+	if got, err = sink.History(ctx, uuid.New()); err != nil || len(got) != 0 {
+		t.Errorf("History() of an unknown course got = %v, %v, want no events", got, err)
+	}
+}
+
+// testNotFound checks that missing courses, tutors and students are reported with a *models.NotFoundError.
+func testNotFound(t *testing.T, newRepo func() services.Repo) {
+	ctx := context.TODO()
+	unknownUUID := uuid.New()
+
+	t.Run("ById", func(t *testing.T) {
+		course, err := newRepo().ById(ctx, unknownUUID)
+		checkNotFound(t, "ById()", course, err, models.NewCourseNotFoundErr(unknownUUID))
+	})
+	t.Run("TutorById", func(t *testing.T) {
+		tutor, err := newRepo().TutorById(ctx, unknownUUID)
+		checkNotFound(t, "TutorById()", tutor, err, models.NewTutorNotFoundErr(unknownUUID))
+	})
+	t.Run("StudentById", func(t *testing.T) {
+		student, err := newRepo().StudentById(ctx, unknownUUID)
+		checkNotFound(t, "StudentById()", student, err, models.NewStudentNotFoundErr(unknownUUID))
+	})
+	t.Run("ById within a transaction", func(t *testing.T) {
+		repo := newRepo()
+		err := repo.WithTx(ctx, func(ctx context.Context) error {
+			_, err := repo.ById(ctx, unknownUUID)
+			return err
+		})
+		checkNotFound(t, "WithTx()", nil, err, models.NewCourseNotFoundErr(unknownUUID))
+	})
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo()
+		course := models.Course{
+			CourseMeta: models.CourseMeta{Uuid: unknownUUID, Name: "test course"},
+			Students:   make(map[uuid.UUID]models.Enrollment),
+			Version:    1,
+		}
+		err := repo.Update(ctx, course)
+		checkNotFound(t, "Update()", nil, err, models.NewCourseNotFoundErr(unknownUUID))
+		got, err := repo.ById(ctx, unknownUUID)
+		checkNotFound(t, "ById() after Update()", got, err, models.NewCourseNotFoundErr(unknownUUID))
+	})
+	t.Run("UpdateTutor", func(t *testing.T) {
+		tutor := models.Tutor{User: models.User{Uuid: unknownUUID, Name: "John", Lastname: "Stone"}}
+		err := newRepo().UpdateTutor(ctx, tutor)
+		checkNotFound(t, "UpdateTutor()", nil, err, models.NewTutorNotFoundErr(unknownUUID))
+	})
+	t.Run("UpdateStudent", func(t *testing.T) {
+		student := models.Student{User: models.User{Uuid: unknownUUID, Name: "Alice", Lastname: "Smith"}}
+		err := newRepo().UpdateStudent(ctx, student)
+		checkNotFound(t, "UpdateStudent()", nil, err, models.NewStudentNotFoundErr(unknownUUID))
+	})
+}
+
+// testAlreadyExists checks that creating a course, a tutor or a student with the UUID of an existing one
+// is reported with a *models.AlreadyExistsError, without changing the existing one.
+func testAlreadyExists(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	tutor := newTutor(t, repo)
+	student := newStudent(t, repo)
+	course := newCourse(t, repo, tutor.Uuid, 0)
+	if err := repo.Create(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course.Version = 1
+
+	duplicate := course
+	duplicate.Name = "duplicated course"
+	err := repo.Create(ctx, duplicate)
+	if want := models.NewAlreadyExistsErr(models.ResourceCourse, course.Uuid); !errors.Is(err, want) {
+		t.Errorf("Create() error = %v, want %v", err, want)
+	}
+	checkCourse(t, repo, course)
+	err = repo.CreateTutor(ctx, tutor)
+	if want := models.NewAlreadyExistsErr(models.ResourceTutor, tutor.Uuid); !errors.Is(err, want) {
+		t.Errorf("CreateTutor() error = %v, want %v", err, want)
+	}
+	err = repo.CreateStudent(ctx, student)
+	if want := models.NewAlreadyExistsErr(models.ResourceStudent, student.Uuid); !errors.Is(err, want) {
+		t.Errorf("CreateStudent() error = %v, want %v", err, want)
+	}
+}
+
+// testDelete checks that deleting a course, a tutor or a student is a no-op once they are deleted.
+func testDelete(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	tutor := newTutor(t, repo)
+	student := newStudent(t, repo)
+	course := newCourse(t, repo, tutor.Uuid, 1)
+	if err := repo.Create(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repo.Delete(ctx, course.Uuid); err != nil {
+			t.Errorf("Delete() error = %v, want nil", err)
+		}
+		if err := repo.DeleteTutor(ctx, tutor.Uuid); err != nil {
+			t.Errorf("DeleteTutor() error = %v, want nil", err)
+		}
+		if err := repo.DeleteStudent(ctx, student.Uuid); err != nil {
+			t.Errorf("DeleteStudent() error = %v, want nil", err)
+		}
+	}
+	got, err := repo.ById(ctx, course.Uuid)
+	checkNotFound(t, "ById() after Delete()", got, err, models.NewCourseNotFoundErr(course.Uuid))
+}
+
+// testConcurrentUpdates checks that the read-modify-writes of concurrent units of work on the same course
+// do not overwrite each other.
+func testConcurrentUpdates(t *testing.T, repo services.Repo) {
+	ctx := context.TODO()
+	course := newCourse(t, repo, newTutor(t, repo).Uuid, 0)
+	if err := repo.Create(ctx, course); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	students := make([]models.Student, concurrentUpdates)
+	for i := range students {
+		students[i] = newStudent(t, repo)
+	}
+
+	var wg sync.WaitGroup
+	for _, student := range students {
+		wg.Add(1)
+		go func(studentUUID uuid.UUID) {
+			defer wg.Done()
+			err := repo.WithTx(ctx, func(ctx context.Context) error {
+				got, err := repo.ById(ctx, course.Uuid)
+				if err != nil {
+					return err
+				}
+				got.Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID}
+				return repo.Update(ctx, *got)
+			})
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}(student.Uuid)
+	}
+	wg.Wait()
+
+	got, err := repo.ById(ctx, course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(got.Students) != concurrentUpdates {
+		t.Errorf("expected %d students, got %d", concurrentUpdates, len(got.Students))
+	}
+	if got.Version != concurrentUpdates+1 {
+		t.Errorf("expected version %d, got %d", concurrentUpdates+1, got.Version)
+	}
+}
+
+// testContextCancellation checks that the calls made with a cancelled context fail with context.Canceled,
+// and do not change the Repo.
+func testContextCancellation(t *testing.T, repo services.Repo) {
+	tutor := newTutor(t, repo)
+	course := newCourse(t, repo, tutor.Uuid, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := repo.TutorById(ctx, tutor.Uuid); !errors.Is(err, context.Canceled) {
+		t.Errorf("TutorById() error = %v, want %v", err, context.Canceled)
+	}
+	if err := repo.Create(ctx, course); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create() error = %v, want %v", err, context.Canceled)
+	}
+	var called bool
+	err := repo.WithTx(ctx, func(ctx context.Context) error {
+		called = true
+		return repo.Create(ctx, course)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WithTx() error = %v, want %v", err, context.Canceled)
+	}
+	if called {
+		t.Errorf("WithTx() ran the unit of work of a cancelled context")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	err = repo.WithTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, course); err != nil {
+			return err
+		}
+		cancel()
+		_, err := repo.ById(ctx, course.Uuid)
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WithTx() error = %v, want %v", err, context.Canceled)
+	}
+
+	got, err := repo.ById(context.TODO(), course.Uuid)
+	checkNotFound(t, "ById() after a cancelled Create()", got, err, models.NewCourseNotFoundErr(course.Uuid))
+}
+
+// newTutor creates a tutor in the given repo.
+func newTutor(t *testing.T, repo services.Repo) models.Tutor {
+	t.Helper()
+	tutor := models.Tutor{
+		User:       models.User{Uuid: uuid.New(), Name: "John", Lastname: "Stone"},
+		Faculty:    "Computer Science",
+		LecturerOf: "Golang",
+	}
+	if err := repo.CreateTutor(context.TODO(), tutor); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	return tutor
+}
+
+// newStudent creates a student in the given repo.
+func newStudent(t *testing.T, repo services.Repo) models.Student {
+	t.Helper()
+	student := models.Student{
+		User:    models.User{Uuid: uuid.New(), Name: "Alice", Lastname: "Smith"},
+		Faculty: "Computer Science",
+	}
+	if err := repo.CreateStudent(context.TODO(), student); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	return student
+}
+
+// newCourse returns a course of the given tutor which has not been created yet,
+// after creating its students in the given repo.
+func newCourse(t *testing.T, repo services.Repo, tutorUUID uuid.UUID, numberOfStudents int) models.Course {
+	t.Helper()
+	course := models.Course{
+		CourseMeta: models.CourseMeta{
+			Uuid:      uuid.New(),
+			Name:      "test course",
+			TutorUUID: tutorUUID,
+		},
+		Students: make(map[uuid.UUID]models.Enrollment),
+	}
+	for i := 0; i < numberOfStudents; i++ {
+		student := newStudent(t, repo)
+		course.Students[student.Uuid] = models.Enrollment{StudentUUID: student.Uuid}
+	}
+	return course
+}
+
+// checkCourse reports an error unless the given course is stored in the given repo.
+func checkCourse(t *testing.T, repo services.Repo, want models.Course) {
+	t.Helper()
+	got, err := repo.ById(context.TODO(), want.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("ById() got = %v, want %v", *got, want)
+	}
+}
+
+// checkNotFound reports an error unless the call named by method returned a nil value and the wanted error.
+func checkNotFound(t *testing.T, method string, got interface{}, err error, want *models.NotFoundError) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%v error = %v, want %v", method, err, want)
+	}
+	switch v := got.(type) {
+	case *models.Course:
+		if v != nil {
+			t.Errorf("%v got = %v, want nil", method, v)
+		}
+	case *models.Tutor:
+		if v != nil {
+			t.Errorf("%v got = %v, want nil", method, v)
+		}
+	case *models.Student:
+		if v != nil {
+			t.Errorf("%v got = %v, want nil", method, v)
+		}
+	}
+}
+
+// courseUUIDs returns the sorted UUIDs of the given courses, or nil if there are none.
+func courseUUIDs(courses []models.Course) []uuid.UUID {
+	var uuids []uuid.UUID
+	for _, course := range courses {
+		uuids = append(uuids, course.Uuid)
+	}
+	return sortedUUIDs(uuids...)
+}
+
+// courseNames returns the names of the given courses in their order, or nil if there are none.
+func courseNames(courses []models.Course) []string {
+	var names []string
+	for _, course := range courses {
+		names = append(names, course.Name)
+	}
+	return names
+}
+
+func sortedUUIDs(uuids ...uuid.UUID) []uuid.UUID {
+	sort.Slice(uuids, func(i, j int) bool {
+		return uuids[i].String() < uuids[j].String()
+	})
+	return uuids
+}
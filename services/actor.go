@@ -0,0 +1,18 @@
+package services
+
+import "context"
+
+// actorKey is the context key of the actor of the calls made with a context.
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx telling that the calls made with it are made by the given actor,
+// e.g. the authenticated user of a request. The CourseManager records who creates and modifies courses.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of the calls made with the given context, or an empty string if it is unknown.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
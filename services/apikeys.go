@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+const (
+	// APIKeyPrefix begins every API key, telling them apart from other secrets, e.g. in secret scanners.
+	APIKeyPrefix = "cm_"
+
+	// apiKeySize is the number of random bytes of the API keys.
+	apiKeySize = 32
+	// apiKeyPrefixLength is the number of characters of the API keys kept in their models.APIKey.Prefix.
+	apiKeyPrefixLength = len(APIKeyPrefix) + 8
+	// apiKeySubjectPrefix begins the Principal.Subject of the API keys, followed by their ID.
+	apiKeySubjectPrefix = "apikey:"
+)
+
+// ErrInvalidAPIKey is returned by APIKeyManager.Authenticate when the key is unknown or revoked.
+var ErrInvalidAPIKey = errors.New("the API key is unknown or revoked")
+
+// APIKeyStore is the interface that defines the methods for persisting the API keys.
+// The apikeys package provides implementations of it.
+type APIKeyStore interface {
+	// CreateAPIKey stores the key without its plaintext.
+	// It returns a *models.AlreadyExistsError if a key with the same ID or hash exists.
+	CreateAPIKey(ctx context.Context, apiKey models.APIKey) error
+	// APIKeyByID returns a *models.NotFoundError if there is no key for the given ID.
+	APIKeyByID(ctx context.Context, apiKeyID uuid.UUID) (*models.APIKey, error)
+	// APIKeyByHash returns an error matching models.ErrNotFound if there is no key for the given hash.
+	APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	// ListAPIKeys returns the keys in the order they have been created.
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	// UpdateAPIKey returns a *models.NotFoundError if the key does not exist.
+	UpdateAPIKey(ctx context.Context, apiKey models.APIKey) error
+}
+
+// APIKeyManager is the service for managing the API keys of the machine clients, and authenticating them.
+// Only the hashes of the keys are stored, so a key cannot be retrieved after its creation.
+type APIKeyManager struct {
+	store  APIKeyStore
+	logger *log.Logger
+	now    func() time.Time
+	// mu serializes the revocations of the keys, which are read-modify-writes.
+	mu *sync.Mutex
+}
+
+// APIKeyOption configures an APIKeyManager.
+type APIKeyOption func(a *APIKeyManager)
+
+// WithAPIKeyClock makes the APIKeyManager timestamp the keys with the given clock instead of time.Now.
+func WithAPIKeyClock(now func() time.Time) APIKeyOption {
+	return func(a *APIKeyManager) {
+		a.now = now
+	}
+}
+
+// NewAPIKeyManager initiates a new APIKeyManager service with the given store and options.
+func NewAPIKeyManager(store APIKeyStore, logger *log.Logger, opts ...APIKeyOption) (APIKeyManager, error) {
+	if store == nil {
+		return APIKeyManager{}, NewNilErr("store")
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	apiKeyManager := APIKeyManager{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+		mu:     &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(&apiKeyManager)
+	}
+	return apiKeyManager, nil
+}
+
+// Create generates a new API key granted the scopes of the given metadata. It is returned with its plaintext,
+// which cannot be retrieved anymore. It returns a *models.ValidationErr if the metadata is invalid.
+func (a APIKeyManager) Create(ctx context.Context, apiKeyMeta models.APIKeyMeta) (*models.APIKey, error) {
+	if err := apiKeyMeta.Validate(); err != nil {
+		return nil, err
+	}
+	random := make([]byte, apiKeySize)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("unable to generate the API key: %w", err)
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+	apiKey := models.APIKey{
+		APIKeyMeta: apiKeyMeta,
+		ID:         uuid.New(),
+		Prefix:     key[:apiKeyPrefixLength],
+		Hash:       hashAPIKey(key),
+		CreatedAt:  a.now().UTC(),
+	}
+	if err := a.store.CreateAPIKey(ctx, apiKey); err != nil {
+		return nil, fmt.Errorf("unable to create the API key: %w", err)
+	}
+	apiKey.Key = key
+	return &apiKey, nil
+}
+
+// List returns all API keys, including the revoked ones.
+func (a APIKeyManager) List(ctx context.Context) ([]models.APIKey, error) {
+	apiKeys, err := a.store.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the API keys: %w", err)
+	}
+	if apiKeys == nil {
+		apiKeys = []models.APIKey{}
+	}
+	return apiKeys, nil
+}
+
+// Revoke revokes the API key with the given ID, which cannot authenticate anymore. Revoking a revoked key is a no-op.
+// It returns a *models.NotFoundError if the key does not exist.
+func (a APIKeyManager) Revoke(ctx context.Context, apiKeyID uuid.UUID) (*models.APIKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	apiKey, err := a.store.APIKeyByID(ctx, apiKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the API key: %w", err)
+	}
+	if apiKey.Revoked() {
+		return apiKey, nil
+	}
+	now := a.now().UTC()
+	apiKey.RevokedAt = &now
+	if err = a.store.UpdateAPIKey(ctx, *apiKey); err != nil {
+		return nil, fmt.Errorf("unable to revoke the API key: %w", err)
+	}
+	a.logger.Printf("API key %v (%v) has been revoked", apiKey.ID, apiKey.Name)
+	return apiKey, nil
+}
+
+// Authenticate returns the API key of the given plaintext.
+// It returns ErrInvalidAPIKey if the key is unknown or revoked.
+func (a APIKeyManager) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	apiKey, err := a.store.APIKeyByHash(ctx, hashAPIKey(key))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the API key: %w", err)
+	}
+	if apiKey.Revoked() {
+		return nil, ErrInvalidAPIKey
+	}
+	return apiKey, nil
+}
+
+// APIKeyPrincipal returns the Principal authenticated by the given API key. Its subject is "apikey:" followed by the
+// ID of the key, and its roles are granted by the scopes of the key: models.ScopeAdmin grants RoleAdmin,
+// and models.ScopeEnrollmentWrite grants RoleRegistrar.
+func APIKeyPrincipal(apiKey models.APIKey) Principal {
+	principal := Principal{Subject: apiKeySubjectPrefix + apiKey.ID.String()}
+	if apiKey.HasScope(models.ScopeAdmin) {
+		principal.Roles = append(principal.Roles, RoleAdmin)
+	}
+	if apiKey.HasScope(models.ScopeEnrollmentWrite) {
+		principal.Roles = append(principal.Roles, RoleRegistrar)
+	}
+	return principal
+}
+
+// hashAPIKey returns the hex encoded SHA-256 hash of the given plaintext key. A salt or a slow hash is not needed,
+// as the keys are random.
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/apikeys"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func TestNewAPIKeyManager(t *testing.T) {
+	if _, err := NewAPIKeyManager(nil, nil); err == nil {
+		t.Errorf("expected error for a nil store, but none raised")
+	}
+	if _, err := NewAPIKeyManager(apikeys.NewMemoryStore(), nil); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestAPIKeyManager(t *testing.T) {
+	ctx := context.TODO()
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	a, err := NewAPIKeyManager(apikeys.NewMemoryStore(), nil, WithAPIKeyClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	_, err = a.Create(ctx, models.APIKeyMeta{Name: " ", Scopes: []models.Scope{"write"}})
+	var validationErr *models.ValidationErr
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 2 {
+		t.Errorf("Create() of an invalid API key error = %v, want a validation error of the name and scopes", err)
+	}
+
+	meta := models.APIKeyMeta{Name: "LMS sync", Scopes: []models.Scope{models.ScopeEnrollmentWrite}}
+	created, err := a.Create(ctx, meta)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) || !strings.HasPrefix(created.Prefix, APIKeyPrefix) ||
+		created.Hash != hashAPIKey(created.Key) || !created.CreatedAt.Equal(now) {
+		t.Errorf("Create() got = %+v, want a key with its plaintext, prefix and hash", created)
+	}
+	other, err := a.Create(ctx, meta)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if other.Key == created.Key {
+		t.Errorf("Create() returned the same key twice")
+	}
+
+	got, err := a.Authenticate(ctx, created.Key)
+	want := *created
+	want.Key = ""
+	if err != nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("Authenticate() got = %+v, %v, want %+v", got, err, want)
+	}
+	for _, key := range []string{"", "cm_unknown", strings.TrimPrefix(created.Key, APIKeyPrefix)} {
+		if _, err = a.Authenticate(ctx, key); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Authenticate(%q) error = %v, want %v", key, err, ErrInvalidAPIKey)
+		}
+	}
+
+	list, err := a.List(ctx)
+	if err != nil || len(list) != 2 || list[0].Key != "" || list[0].ID != created.ID {
+		t.Errorf("List() got = %+v, %v, want both keys without their plaintext", list, err)
+	}
+
+	revoked, err := a.Revoke(ctx, created.ID)
+	if err != nil || revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(now) {
+		t.Fatalf("Revoke() got = %+v, %v, want the key revoked now", revoked, err)
+	}
+	now = now.Add(time.Hour)
+	if revoked, err = a.Revoke(ctx, created.ID); err != nil || !revoked.RevokedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Revoke() of a revoked key got = %+v, %v, want it unchanged", revoked, err)
+	}
+	if _, err = a.Authenticate(ctx, created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() of a revoked key error = %v, want %v", err, ErrInvalidAPIKey)
+	}
+	if _, err = a.Authenticate(ctx, other.Key); err != nil {
+		t.Errorf("Authenticate() of the other key error = %v", err)
+	}
+	unknown := models.NewAPIKeyNotFoundErr(fixedUuid)
+	if _, err = a.Revoke(ctx, fixedUuid); !errors.Is(err, unknown) {
+		t.Errorf("Revoke() of an unknown key error = %v, want %v", err, unknown)
+	}
+}
+
+func TestAPIKeyPrincipal(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []models.Scope
+		want   []Role
+	}{
+		{name: "read-only", scopes: []models.Scope{models.ScopeReadOnly}},
+		{name: "enrollment-write", scopes: []models.Scope{models.ScopeReadOnly, models.ScopeEnrollmentWrite}, want: []Role{RoleRegistrar}},
+		{name: "admin", scopes: []models.Scope{models.ScopeAdmin}, want: []Role{RoleAdmin}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiKey := models.APIKey{APIKeyMeta: models.APIKeyMeta{Name: tt.name, Scopes: tt.scopes}, ID: fixedUuid}
+			got := APIKeyPrincipal(apiKey)
+			if got.Subject != "apikey:"+fixedUuid.String() || !reflect.DeepEqual(got.Roles, tt.want) {
+				t.Errorf("APIKeyPrincipal() = %+v, want the roles %v", got, tt.want)
+			}
+		})
+	}
+}
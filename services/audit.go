@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// AuditSink is the interface that defines the methods for keeping the audit log of the courses.
+// The audit package provides implementations of it, and so do the Repos which record the events
+// within their units of work, with the modification they tell about.
+type AuditSink interface {
+	// Record appends the given event to the audit log. If the AuditSink is the Repo of the CourseManager,
+	// it is called within Repo.WithTx, and should record the event within the unit of work of the context.
+	// Otherwise, it is called once the unit of work of the modification has committed.
+	Record(ctx context.Context, event models.AuditEvent) error
+	// History returns the events of the given course in the order they have been recorded.
+	History(ctx context.Context, courseUUID uuid.UUID) ([]models.AuditEvent, error)
+}
+
+// WithAuditSink makes the CourseManager record every modification of the courses into the given AuditSink.
+// Without one, modifications are not audited. The events are recorded with the modifications if the AuditSink
+// is the Repo of the CourseManager, and after them otherwise, so that the audit log of another AuditSink
+// may miss the modifications whose events fail to be recorded, but never tells about discarded ones.
+func WithAuditSink(sink AuditSink) Option {
+	return func(c *CourseManager) {
+		c.auditSink = sink
+	}
+}
+
+// History returns the audit events of the given course, oldest first, including those of a deleted course.
+// It returns no events if the CourseManager has no AuditSink.
+// The history of a deleted course is authorized as the history of a course without a tutor.
+func (c CourseManager) History(ctx context.Context, courseUUID uuid.UUID) ([]models.AuditEvent, error) {
+	courseMeta := models.CourseMeta{Uuid: courseUUID}
+	course, err := c.repo.ById(ctx, courseUUID)
+	switch {
+	case err == nil:
+		courseMeta = course.CourseMeta
+	case !errors.Is(err, models.ErrNotFound):
+		return nil, fmt.Errorf("unable to retrieve the course: %w", err)
+	}
+	if err = c.authorize(ctx, ActionReadHistory, courseMeta, uuid.Nil); err != nil {
+		return nil, err
+	}
+	if c.auditSink == nil {
+		return []models.AuditEvent{}, nil
+	}
+	events, err := c.auditSink.History(ctx, courseUUID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the history of the course: %w", err)
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+	return events, nil
+}
+
+// auditBufferKey is the context key of the audit events of the ongoing unit of work of CourseManager.withTx,
+// which are recorded once it has committed.
+type auditBufferKey struct{}
+
+// withTx runs fn as a single unit of work of the Repo, see Repo.WithTx. Unless the AuditSink is the Repo,
+// the events audited by fn are recorded once the unit of work has committed, so that the units of work which
+// are discarded, or run again by the Repo, do not record any. The events failing to be recorded are logged,
+// as the modifications they tell about have been committed.
+func (c CourseManager) withTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.auditSink == nil || c.auditInTx || ctx.Value(auditBufferKey{}) != nil {
+		return c.repo.WithTx(ctx, fn)
+	}
+	var committed []models.AuditEvent
+	err := c.repo.WithTx(ctx, func(ctx context.Context) error {
+		var events []models.AuditEvent
+		if err := fn(context.WithValue(ctx, auditBufferKey{}, &events)); err != nil {
+			return err
+		}
+		committed = events
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range committed {
+		// The modification is committed even if the caller has gone away in the meantime.
+		if err = c.auditSink.Record(context.Background(), event); err != nil {
+			c.logger.Printf("unable to record the audit event %v of the course %v: %v", event.Action, event.CourseUUID, err)
+		}
+	}
+	return nil
+}
+
+// audit records that the actor of the context has modified the given course, whose before and after states
+// are given, with the given action on the given student. It must be called within the unit of work
+// of CourseManager.withTx of the modification, which fails with it if the event cannot be recorded
+// by the Repo, or buffers the event until it has committed.
+func (c CourseManager) audit(ctx context.Context, action models.AuditAction, courseUUID, studentUUID uuid.UUID,
+	before, after *models.Course) error {
+	if c.auditSink == nil {
+		return nil
+	}
+	event := models.AuditEvent{
+		Time:       c.timestamp(),
+		Actor:      ActorFromContext(ctx),
+		Action:     action,
+		CourseUUID: courseUUID,
+		Before:     before,
+		After:      after,
+	}
+	if after != nil {
+		event.Time = after.UpdatedAt
+	}
+	if studentUUID != uuid.Nil {
+		event.StudentUUID = &studentUUID
+	}
+	if events, ok := ctx.Value(auditBufferKey{}).(*[]models.AuditEvent); ok {
+		*events = append(*events, event)
+		return nil
+	}
+	if err := c.auditSink.Record(ctx, event); err != nil {
+		return fmt.Errorf("unable to record the audit event: %w", err)
+	}
+	return nil
+}
+
+// snapshot returns a copy of the given course which is not modified with it.
+func snapshot(course *models.Course) *models.Course {
+	copied := *course
+	copied.Students = make(map[uuid.UUID]models.Enrollment, len(course.Students))
+	for studentUUID, enrollment := range course.Students {
+		copied.Students[studentUUID] = enrollment
+	}
+	copied.Waitlist = append([]uuid.UUID(nil), course.Waitlist...)
+	return &copied
+}
+
+// updated returns a snapshot of the given course as it is stored by a successful Repo.Update.
+func updated(course *models.Course) *models.Course {
+	after := snapshot(course)
+	after.Version++
+	return after
+}
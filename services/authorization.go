@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// Action is a modification or a read of a course which the principals must be authorized to make.
+type Action string
+
+const (
+	ActionCreateCourse      Action = "create"
+	ActionUpdateCourse      Action = "update"
+	ActionDeleteCourse      Action = "delete"
+	ActionRegisterStudent   Action = "register a student to"
+	ActionUnregisterStudent Action = "unregister a student from"
+	ActionLeaveWaitlist     Action = "remove a student from the waitlist of"
+	ActionReadHistory       Action = "read the history of"
+)
+
+// Authorizer is the interface that defines the method for deciding whether the principal of a context
+// may modify a course or read its history.
+type Authorizer interface {
+	// Authorize returns a *ForbiddenErr unless the principal of the context may make the given action
+	// on the given course, for the given student if the action is about a student.
+	Authorize(ctx context.Context, action Action, course models.CourseMeta, studentUUID uuid.UUID) error
+}
+
+// WithAuthorizer makes the CourseManager authorize every modification of the courses, and the reads of their history,
+// with the given Authorizer.
+// Without one, every modification is allowed, e.g. in development.
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(c *CourseManager) {
+		c.authorizer = authorizer
+	}
+}
+
+// RoleBasedAuthorizer is the Authorizer granting the permissions of the roles of the principals:
+//   - RoleAdmin may make any action.
+//   - RoleTutor may create, update and delete the courses they facilitate, and read their history.
+//   - RoleStudent may register themselves to a course, unregister themselves and leave its waitlist.
+//   - RoleRegistrar may register any student to a course, unregister them and remove them from its waitlist.
+//
+// Unauthenticated calls are forbidden.
+type RoleBasedAuthorizer struct{}
+
+// Authorize implements Authorizer.
+func (RoleBasedAuthorizer) Authorize(ctx context.Context, action Action, course models.CourseMeta, studentUUID uuid.UUID) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return NewForbiddenErr("", action, course.Uuid)
+	}
+	if principal.HasRole(RoleAdmin) {
+		return nil
+	}
+	switch action {
+	case ActionCreateCourse, ActionUpdateCourse, ActionDeleteCourse, ActionReadHistory:
+		if principal.HasRole(RoleTutor) && principal.is(course.TutorUUID) {
+			return nil
+		}
+	case ActionRegisterStudent, ActionUnregisterStudent, ActionLeaveWaitlist:
+		if principal.HasRole(RoleRegistrar) || (principal.HasRole(RoleStudent) && principal.is(studentUUID)) {
+			return nil
+		}
+	}
+	return NewForbiddenErr(principal.Subject, action, course.Uuid)
+}
+
+// authorize returns a *ForbiddenErr unless the Authorizer of the CourseManager, if any, allows the principal
+// of the context to make the given action.
+func (c CourseManager) authorize(ctx context.Context, action Action, course models.CourseMeta, studentUUID uuid.UUID) error {
+	if c.authorizer == nil {
+		return nil
+	}
+	return c.authorizer.Authorize(ctx, action, course, studentUUID)
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/tomasdembelli/course-manager/db-mock"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func TestRoleBasedAuthorizer_Authorize(t *testing.T) {
+	tutorUUID, studentUUID := uuid.New(), uuid.New()
+	course := models.CourseMeta{Uuid: uuid.New(), Name: "Go", TutorUUID: tutorUUID}
+	admin := &Principal{Subject: "admin", Roles: []Role{RoleAdmin}}
+	tutor := &Principal{Subject: tutorUUID.String(), Roles: []Role{RoleTutor}}
+	otherTutor := &Principal{Subject: uuid.NewString(), Roles: []Role{RoleTutor}}
+	student := &Principal{Subject: studentUUID.String(), Roles: []Role{RoleStudent}}
+	registrar := &Principal{Subject: "apikey:lms", Roles: []Role{RoleRegistrar}}
+	tests := []struct {
+		name        string
+		principal   *Principal
+		action      Action
+		studentUUID uuid.UUID
+		want        bool
+	}{
+		{name: "unauthenticated", action: ActionRegisterStudent, studentUUID: studentUUID},
+		{name: "admin creates", principal: admin, action: ActionCreateCourse, want: true},
+		{name: "admin registers a student", principal: admin, action: ActionRegisterStudent, studentUUID: studentUUID, want: true},
+		{name: "tutor creates their course", principal: tutor, action: ActionCreateCourse, want: true},
+		{name: "tutor updates their course", principal: tutor, action: ActionUpdateCourse, want: true},
+		{name: "tutor deletes their course", principal: tutor, action: ActionDeleteCourse, want: true},
+		{name: "tutor deletes another course", principal: otherTutor, action: ActionDeleteCourse},
+		{name: "tutor creates another course", principal: otherTutor, action: ActionCreateCourse},
+		{name: "tutor reads the history of their course", principal: tutor, action: ActionReadHistory, want: true},
+		{name: "tutor reads the history of another course", principal: otherTutor, action: ActionReadHistory},
+		{name: "admin reads the history", principal: admin, action: ActionReadHistory, want: true},
+		{name: "student reads the history", principal: student, action: ActionReadHistory},
+		{name: "registrar reads the history", principal: registrar, action: ActionReadHistory},
+		{name: "tutor registers a student", principal: tutor, action: ActionRegisterStudent, studentUUID: studentUUID},
+		{name: "student registers themselves", principal: student, action: ActionRegisterStudent, studentUUID: studentUUID, want: true},
+		{name: "student unregisters themselves", principal: student, action: ActionUnregisterStudent, studentUUID: studentUUID, want: true},
+		{name: "student leaves the waitlist", principal: student, action: ActionLeaveWaitlist, studentUUID: studentUUID, want: true},
+		{name: "student registers another student", principal: student, action: ActionRegisterStudent, studentUUID: uuid.New()},
+		{name: "student updates a course", principal: student, action: ActionUpdateCourse},
+		{name: "registrar registers a student", principal: registrar, action: ActionRegisterStudent, studentUUID: studentUUID, want: true},
+		{name: "registrar removes a student from the waitlist", principal: registrar, action: ActionLeaveWaitlist, studentUUID: studentUUID, want: true},
+		{name: "registrar deletes a course", principal: registrar, action: ActionDeleteCourse},
+		{
+			name:      "tutor subject without the role",
+			principal: &Principal{Subject: tutorUUID.String(), Roles: []Role{RoleStudent}},
+			action:    ActionDeleteCourse,
+		},
+		{
+			name:        "student subject without the role",
+			principal:   &Principal{Subject: studentUUID.String(), Roles: []Role{RoleTutor}},
+			action:      ActionRegisterStudent,
+			studentUUID: studentUUID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			if tt.principal != nil {
+				ctx = ContextWithPrincipal(ctx, *tt.principal)
+			}
+			err := RoleBasedAuthorizer{}.Authorize(ctx, tt.action, course, tt.studentUUID)
+			if tt.want && err != nil {
+				t.Errorf("Authorize() error = %v, want nil", err)
+			}
+			if !tt.want && !errors.Is(err, models.ErrForbidden) {
+				t.Errorf("Authorize() error = %v, want %v", err, models.ErrForbidden)
+			}
+		})
+	}
+}
+
+func TestCourseManager_authorization(t *testing.T) {
+	course := generateUsersInCourse(1)
+	var enrolledUUID uuid.UUID
+	for studentUUID := range course.Students {
+		enrolledUUID = studentUUID
+	}
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{course.Uuid: course},
+		TutorByUUID:  fixedTutors(),
+	}), nil, WithAuthorizer(RoleBasedAuthorizer{}))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	stranger := ContextWithPrincipal(context.TODO(), Principal{Subject: uuid.NewString(), Roles: []Role{RoleTutor, RoleStudent}})
+
+	calls := map[string]func(ctx context.Context) error{
+		"Create": func(ctx context.Context) error {
+			_, err := c.Create(ctx, models.CourseMeta{Name: "Other", TutorUUID: course.TutorUUID})
+			return err
+		},
+		"UpdateMeta": func(ctx context.Context) error {
+			meta := course.CourseMeta
+			meta.Name = "Renamed"
+			_, err := c.UpdateMeta(ctx, meta, 0)
+			return err
+		},
+		"RegisterStudent": func(ctx context.Context) error {
+			_, err := c.RegisterStudent(ctx, course.Uuid, uuid.New(), 0)
+			return err
+		},
+		"UnregisterStudent": func(ctx context.Context) error {
+			return c.UnregisterStudent(ctx, course.Uuid, enrolledUUID, 0)
+		},
+		"LeaveWaitlist": func(ctx context.Context) error {
+			return c.LeaveWaitlist(ctx, course.Uuid, enrolledUUID, 0)
+		},
+		"Delete": func(ctx context.Context) error {
+			return c.Delete(ctx, course.Uuid, 0)
+		},
+		"History": func(ctx context.Context) error {
+			_, err := c.History(ctx, course.Uuid)
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			for _, ctx := range []context.Context{context.TODO(), stranger} {
+				if err := call(ctx); !errors.Is(err, models.ErrForbidden) {
+					t.Errorf("%v() error = %v, want %v", name, err, models.ErrForbidden)
+				}
+			}
+			got, err := c.Get(context.TODO(), course.Uuid)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if !reflect.DeepEqual(got.CourseMeta, course.CourseMeta) || len(got.Students) != 1 {
+				t.Errorf("got the course %+v, want it unchanged", got)
+			}
+		})
+	}
+
+	// The tutor of the course may read its history and delete it, and deleting a missing course stays a no-op.
+	tutor := ContextWithPrincipal(context.TODO(), Principal{Subject: course.TutorUUID.String(), Roles: []Role{RoleTutor}})
+	if _, err = c.History(tutor, course.Uuid); err != nil {
+		t.Errorf("History() by the tutor error = %v", err)
+	}
+	if err = c.Delete(tutor, course.Uuid, 0); err != nil {
+		t.Errorf("Delete() by the tutor error = %v", err)
+	}
+	if err = c.Delete(stranger, course.Uuid, 0); err != nil {
+		t.Errorf("Delete() of a missing course error = %v", err)
+	}
+	// Only admins may read the history of a deleted course.
+	if _, err = c.History(tutor, course.Uuid); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("History() of a deleted course by the tutor error = %v, want %v", err, models.ErrForbidden)
+	}
+	admin := ContextWithPrincipal(context.TODO(), Principal{Subject: "admin", Roles: []Role{RoleAdmin}})
+	if _, err = c.History(admin, course.Uuid); err != nil {
+		t.Errorf("History() of a deleted course by an admin error = %v", err)
+	}
+}
+
+func TestCourseManager_UpdateMeta_reassignment(t *testing.T) {
+	course := generateUsersInCourse(0)
+	otherTutorUUID := uuid.New()
+	tutors := fixedTutors()
+	tutors[otherTutorUUID] = models.Tutor{User: models.User{Uuid: otherTutorUUID}}
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{course.Uuid: course},
+		TutorByUUID:  tutors,
+	}), nil, WithAuthorizer(RoleBasedAuthorizer{}))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	meta := course.CourseMeta
+	meta.TutorUUID = otherTutorUUID
+
+	// A tutor may not hand their course over to another tutor.
+	tutor := ContextWithPrincipal(context.TODO(), Principal{Subject: course.TutorUUID.String(), Roles: []Role{RoleTutor}})
+	if _, err = c.UpdateMeta(tutor, meta, 0); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("UpdateMeta() by the tutor error = %v, want %v", err, models.ErrForbidden)
+	}
+	got, err := c.Get(context.TODO(), course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.TutorUUID != course.TutorUUID {
+		t.Errorf("got the tutor %v, want %v", got.TutorUUID, course.TutorUUID)
+	}
+
+	// An admin may.
+	admin := ContextWithPrincipal(context.TODO(), Principal{Subject: "admin", Roles: []Role{RoleAdmin}})
+	if got, err = c.UpdateMeta(admin, meta, 0); err != nil || got.TutorUUID != otherTutorUUID {
+		t.Errorf("UpdateMeta() by an admin got = %v, %v, want the tutor %v", got, err, otherTutorUUID)
+	}
+}
@@ -2,161 +2,476 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tomasdembelli/course-manager/models"
 )
 
+// DefaultCourseLimit is the number of courses of the pages listed by CourseManager.List, unless the query limits it.
+const DefaultCourseLimit = 20
+
+// TutorRepo is the interface that defines the methods for persisting tutors.
+type TutorRepo interface {
+	// TutorById returns a *models.NotFoundError if there is no tutor for the given UUID.
+	TutorById(ctx context.Context, tutorUUID uuid.UUID) (*models.Tutor, error)
+	ListTutors(ctx context.Context) ([]models.Tutor, error)
+	// CreateTutor returns a *models.AlreadyExistsError if a tutor with the same UUID exists.
+	CreateTutor(ctx context.Context, tutor models.Tutor) error
+	// UpdateTutor returns a *models.NotFoundError if the tutor does not exist.
+	UpdateTutor(ctx context.Context, tutor models.Tutor) error
+	// DeleteTutor is a no-op if the tutor does not exist.
+	DeleteTutor(ctx context.Context, tutorUUID uuid.UUID) error
+}
+
+// StudentRepo is the interface that defines the methods for persisting students.
+type StudentRepo interface {
+	// StudentById returns a *models.NotFoundError if there is no student for the given UUID.
+	StudentById(ctx context.Context, studentUUID uuid.UUID) (*models.Student, error)
+	ListStudents(ctx context.Context) ([]models.Student, error)
+	// CreateStudent returns a *models.AlreadyExistsError if a student with the same UUID exists.
+	CreateStudent(ctx context.Context, student models.Student) error
+	// UpdateStudent returns a *models.NotFoundError if the student does not exist.
+	UpdateStudent(ctx context.Context, student models.Student) error
+	// DeleteStudent is a no-op if the student does not exist.
+	// It returns an error matching models.ErrConstraint if the student is registered to or waitlisted for a course.
+	DeleteStudent(ctx context.Context, studentUUID uuid.UUID) error
+}
+
 // Repo is the interface that defines the methods for persisting and manipulating service data.
+// Courses reference the tutors and students of the TutorRepo and StudentRepo by UUID,
+// and the domain events telling about their changes are stored into the Outbox in the same unit of work.
+// Implementations must report missing courses, tutors and students with a *models.NotFoundError,
+// never with a zero value, and duplicated ones with a *models.AlreadyExistsError. Calls made with a done context must fail with an error wrapping the context's error,
+// without changing anything. The repotest package checks an implementation against this contract.
 type Repo interface {
+	TutorRepo
+	StudentRepo
+	Outbox
+	// WithTx runs fn as a single unit of work: the Repo calls made by fn with the context it is given
+	// are committed atomically if fn returns nil, and discarded otherwise. Concurrent units of work must
+	// not observe or overwrite each other's changes, so that a read-modify-write in fn is safe.
+	// Calling WithTx within fn joins the ongoing unit of work.
+	// Implementations may run fn more than once, so it must not have side effects outside the Repo.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// ById returns a *models.NotFoundError if there is no course for the given UUID.
 	ById(ctx context.Context, courseUUID uuid.UUID) (*models.Course, error)
 	ByTutor(ctx context.Context, tutorUUID uuid.UUID) ([]models.Course, error)
 	ByStudent(ctx context.Context, studentUUID uuid.UUID) ([]models.Course, error)
-	List(ctx context.Context) ([]models.Course, error)
+	// CountStudentCourses returns the number of courses the given student is registered to or waitlisted for.
+	CountStudentCourses(ctx context.Context, studentUUID uuid.UUID) (int, error)
+	// List returns the page of the courses selected by the given query.
+	// It returns an error if the cursor of the query has not been returned by a query of the same order.
+	List(ctx context.Context, query models.CourseQuery) (*models.CoursePage, error)
+	// Create stores the given course at version 1.
+	// It returns a *models.AlreadyExistsError if a course with the same UUID exists.
 	Create(ctx context.Context, course models.Course) error
+	// Delete is a no-op if the course does not exist.
 	Delete(ctx context.Context, uuid uuid.UUID) error
+	// Update stores the given course and increments its version, keeping the CreatedAt and CreatedBy of the stored course.
+	// It returns a *models.NotFoundError if the course does not exist,
+	// and a *models.VersionConflictErr if the given version is not the stored one.
 	Update(ctx context.Context, course models.Course) error
 }
 
-const (
-	tutorMaxCourse   = 2
-	studentMaxCourse = 4
-	courseMaxStudent = 20
-)
-
 // CourseManager is the service for managing the courses.
 type CourseManager struct {
-	repo   Repo
-	logger *log.Logger
+	repo      Repo
+	logger    *log.Logger
+	policy    Policy
+	now       func() time.Time
+	auditSink AuditSink
+	// auditInTx tells whether the AuditSink is the Repo, which records the events within its units of work.
+	auditInTx  bool
+	authorizer Authorizer
+}
+
+// Option configures a CourseManager.
+type Option func(c *CourseManager)
+
+// WithPolicy makes the CourseManager enforce the given Policy instead of the DefaultPolicy.
+func WithPolicy(policy Policy) Option {
+	return func(c *CourseManager) {
+		c.policy = policy
+	}
 }
 
-// NewCourseManager initiates a new CourseManager service with the given repo.
-func NewCourseManager(repo Repo, logger *log.Logger) (CourseManager, error) {
+// WithClock makes the CourseManager timestamp the courses and enrollments with the given clock instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(c *CourseManager) {
+		c.now = now
+	}
+}
+
+// NewCourseManager initiates a new CourseManager service with the given repo and options.
+func NewCourseManager(repo Repo, logger *log.Logger, opts ...Option) (CourseManager, error) {
 	if repo == nil {
 		return CourseManager{}, NewNilErr("repo")
 	}
 
 	if logger == nil {
-		return CourseManager{
-			repo:   repo,
-			logger: log.Default(),
-		}, nil
+		logger = log.Default()
 	}
 
-	return CourseManager{
+	courseManager := CourseManager{
 		repo:   repo,
 		logger: logger,
-	}, nil
-}
-
-// Create creates a new course. It enforces that a tutor can facilitate maximum 2 courses.
-func (c *CourseManager) Create(ctx context.Context, courseMeta models.CourseMeta) (*models.Course, error) {
-	if courseMeta.Tutor == nil {
-		return nil, NewNilErr("tutor")
+		policy: DefaultPolicy,
+		now:    time.Now,
 	}
-	coursesByTutor, err := c.repo.ByTutor(ctx, courseMeta.Tutor.Uuid)
-	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve courses: %w", err)
+	for _, opt := range opts {
+		opt(&courseManager)
+	}
+	if sink, ok := repo.(AuditSink); ok && courseManager.auditSink != nil {
+		courseManager.auditInTx = sink == courseManager.auditSink
 	}
-	if len(coursesByTutor) >= tutorMaxCourse {
-		return nil, NewCourseConstraintErr(tutorMaxCourseMsg)
+	if err := courseManager.policy.Validate(); err != nil {
+		return CourseManager{}, err
 	}
+	return courseManager, nil
+}
+
+// Create creates a new course on behalf of the actor of the context. It returns a *models.ValidationErr
+// if the course is invalid, and a *models.NotFoundError if its tutor does not exist.
+// It enforces the maximum number of courses a tutor can facilitate.
+func (c *CourseManager) Create(ctx context.Context, courseMeta models.CourseMeta) (*models.Course, error) {
 	if courseMeta.Uuid == uuid.Nil {
 		courseMeta.Uuid = uuid.New()
 	}
+	if err := courseMeta.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.authorize(ctx, ActionCreateCourse, courseMeta, uuid.Nil); err != nil {
+		return nil, err
+	}
 
-	err = c.repo.Create(ctx, models.Course{
-		CourseMeta: courseMeta,
-		Students:   make(map[uuid.UUID]models.Student),
+	var courseCreated *models.Course
+	err := c.withTx(ctx, func(ctx context.Context) error {
+		if err := c.checkTutor(ctx, courseMeta.TutorUUID); err != nil {
+			return err
+		}
+
+		now, actor := c.timestamp(), ActorFromContext(ctx)
+		err := c.repo.Create(ctx, models.Course{
+			CourseMeta: courseMeta,
+			Students:   make(map[uuid.UUID]models.Enrollment),
+			CreatedAt:  now,
+			CreatedBy:  actor,
+			UpdatedAt:  now,
+			UpdatedBy:  actor,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create the course: %w", err)
+		}
+
+		courseCreated, err = c.repo.ById(ctx, courseMeta.Uuid)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the course: %w", err)
+		}
+		err = c.emit(ctx, c.newEvent(ctx, models.CourseCreatedEvent, courseCreated.Uuid, uuid.Nil, snapshot(courseCreated)))
+		if err != nil {
+			return err
+		}
+		return c.audit(ctx, models.CourseCreated, courseCreated.Uuid, uuid.Nil, nil, snapshot(courseCreated))
 	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to create the course: %w", err)
+		return nil, err
 	}
+	return courseCreated, nil
+}
 
-	courseCreated, err := c.repo.ById(ctx, courseMeta.Uuid)
-	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve the course: %w", err)
+// UpdateMeta replaces the metadata of the course with the UUID of the given courseMeta, e.g. to rename it
+// or to reassign its tutor. Reassigning the tutor enforces the maximum number of courses a tutor can facilitate.
+// It returns a *models.ValidationErr if the metadata is invalid, and a *models.NotFoundError if the course or the tutor does not exist.
+// Unless expectedVersion is 0, a *models.VersionConflictErr is returned if the course is not at expectedVersion.
+// Reassigning the tutor is authorized as an update of both the course and the reassigned course.
+// Changing the capacity of the course returns a *models.ValidationErr if it is lower than the number of its enrolled
+// students, and gives the new seats to the waitlisted students as UnregisterStudent does.
+func (c *CourseManager) UpdateMeta(ctx context.Context, courseMeta models.CourseMeta, expectedVersion int) (*models.Course, error) {
+	if err := courseMeta.Validate(); err != nil {
+		return nil, err
 	}
 
-	return courseCreated, nil
-}
+	var courseUpdated *models.Course
+	err := c.withTx(ctx, func(ctx context.Context) error {
+		course, err := c.repo.ById(ctx, courseMeta.Uuid)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the course: %w", err)
+		}
+		if err = c.authorize(ctx, ActionUpdateCourse, course.CourseMeta, uuid.Nil); err != nil {
+			return err
+		}
+		if err = checkVersion(course, expectedVersion); err != nil {
+			return err
+		}
+		before := snapshot(course)
+		if courseMeta.TutorUUID != course.TutorUUID {
+			// Reassigning a course is also an update of the course of its new tutor, so that tutors cannot
+			// hand their courses over to others.
+			if err = c.authorize(ctx, ActionUpdateCourse, courseMeta, uuid.Nil); err != nil {
+				return err
+			}
+			if err = c.checkTutor(ctx, courseMeta.TutorUUID); err != nil {
+				return err
+			}
+		}
 
-// RegisterStudent registers the given models.Student to the given course.
-// This is an idempotent operation.
-// It will return an error if the given course is not found or unable to update it.
-// It enforces:
-//	- A studentUUID can register to maximum 4 courses.
-//	- Maximum 20 students can register a course.
-func (c CourseManager) RegisterStudent(ctx context.Context, courseUUID uuid.UUID, student models.Student) error {
-	course, err := c.repo.ById(ctx, courseUUID)
+		capacity := c.policy.CourseCapacity(courseMeta)
+		resized := capacity != c.policy.CourseCapacity(course.CourseMeta)
+		if resized {
+			if err = course.ValidateCapacity(capacity); err != nil {
+				return err
+			}
+		}
+
+		course.CourseMeta = courseMeta
+		c.touch(ctx, course)
+		var promoted []uuid.UUID
+		if resized {
+			if promoted, err = c.promoteFromWaitlist(ctx, course); err != nil {
+				return err
+			}
+		}
+		if err = c.repo.Update(ctx, *course); err != nil {
+			return fmt.Errorf("unable to update the course: %w", err)
+		}
+		courseUpdated, err = c.repo.ById(ctx, courseMeta.Uuid)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the course: %w", err)
+		}
+		after := snapshot(courseUpdated)
+		events := []models.Event{c.newEvent(ctx, models.CourseUpdatedEvent, courseUpdated.Uuid, uuid.Nil, after)}
+		for _, promotedUUID := range promoted {
+			events = append(events, c.newEvent(ctx, models.StudentPromotedEvent, courseUpdated.Uuid, promotedUUID, after))
+		}
+		if err = c.emit(ctx, events...); err != nil {
+			return err
+		}
+		return c.audit(ctx, models.CourseUpdated, courseUpdated.Uuid, uuid.Nil, before, after)
+	})
 	if err != nil {
-		return fmt.Errorf("unable to retrieve the course: %w", err)
-	}
-	if len(course.Students) >= courseMaxStudent {
-		return NewCourseConstraintErr(courseMaxStudentMsg)
+		return nil, err
 	}
-	coursesByStudent, err := c.repo.ByStudent(ctx, student.Uuid)
-	if err != nil {
-		return fmt.Errorf("unable to retrieve courses: %w", err)
-	}
-	if len(coursesByStudent) >= studentMaxCourse {
-		return fmt.Errorf("a studentUUID can subscribe to maximum %d courses", studentMaxCourse)
-	}
-	course.Students[student.Uuid] = student
-	err = c.repo.Update(ctx, *course)
+	return courseUpdated, nil
+}
+
+// RegisterStudent registers the given student to the given course, or puts them on its waitlist
+// if the course is full. It returns whether the student has been enrolled or waitlisted.
+// This is an idempotent operation. The capacity checks and the registration are done atomically.
+// Unless expectedVersion is 0, a *models.VersionConflictErr is returned if the course is not at expectedVersion.
+// It returns a *models.NotFoundError if the course or the student does not exist.
+// It enforces:
+//   - The maximum number of courses a studentUUID can register to or be waitlisted for.
+//   - The capacity of the course.
+func (c CourseManager) RegisterStudent(ctx context.Context, courseUUID, studentUUID uuid.UUID, expectedVersion int) (RegistrationStatus, error) {
+	var status RegistrationStatus
+	err := c.withTx(ctx, func(ctx context.Context) error {
+		course, err := c.repo.ById(ctx, courseUUID)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the course: %w", err)
+		}
+		if err = c.authorize(ctx, ActionRegisterStudent, course.CourseMeta, studentUUID); err != nil {
+			return err
+		}
+		if err = checkVersion(course, expectedVersion); err != nil {
+			return err
+		}
+		if _, ok := course.Students[studentUUID]; ok {
+			status = Enrolled
+			return nil
+		}
+		if waitlistPosition(course, studentUUID) > 0 {
+			status = Waitlisted
+			return nil
+		}
+		_, err = c.repo.StudentById(ctx, studentUUID)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the student: %w", err)
+		}
+		// The waitlists count too, so that students cannot queue for more courses than they may attend.
+		inCourses, err := c.repo.CountStudentCourses(ctx, studentUUID)
+		if err != nil {
+			return fmt.Errorf("unable to count the courses of the student: %w", err)
+		}
+		if inCourses >= c.policy.StudentMaxCourse {
+			return &CourseConstraintErr{
+				Constraint:  StudentMaxCourse,
+				Limit:       c.policy.StudentMaxCourse,
+				CourseUUID:  courseUUID,
+				StudentUUID: studentUUID,
+			}
+		}
+		before := snapshot(course)
+		c.touch(ctx, course)
+		if len(course.Students) >= c.policy.CourseCapacity(course.CourseMeta) {
+			course.Waitlist = append(course.Waitlist, studentUUID)
+			status = Waitlisted
+		} else {
+			course.Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID, EnrolledAt: course.UpdatedAt}
+			status = Enrolled
+		}
+		err = c.repo.Update(ctx, *course)
+		if err != nil {
+			return fmt.Errorf("unable to update the course: %w", err)
+		}
+		after := updated(course)
+		event := c.newEvent(ctx, models.StudentRegisteredEvent, courseUUID, studentUUID, after)
+		event.Status = string(status)
+		if err = c.emit(ctx, event); err != nil {
+			return err
+		}
+		return c.audit(ctx, models.StudentRegistered, courseUUID, studentUUID, before, after)
+	})
 	if err != nil {
-		return fmt.Errorf("unable to update the course: %w", err)
+		return "", err
 	}
-	return nil
+	return status, nil
 }
 
-// UnregisterStudent removes the given models.Student from the given course.
+// UnregisterStudent removes the given student from the given course.
+// The freed seat is given to the first waitlisted student who has not reached their maximum number of courses.
 // This is an idempotent operation.
-// It will return an error if the given course is not found or unable to update it.
-// If the studentUUID has not been registered to the course previously, no error will be returned (no-op).
-func (c CourseManager) UnregisterStudent(ctx context.Context, courseUUID, studentUUID uuid.UUID) error {
-	course, err := c.repo.ById(ctx, courseUUID)
-	if err != nil {
-		return fmt.Errorf("unable to retrieve the course: %w", err)
-	}
-	delete(course.Students, studentUUID)
-	err = c.repo.Update(ctx, *course)
-	if err != nil {
-		return fmt.Errorf("unable to update the course: %w", err)
-	}
-	return nil
+// It returns a *models.NotFoundError if the course does not exist.
+// If the studentUUID is neither registered to the course nor on its waitlist, no error will be returned (no-op).
+// Unless expectedVersion is 0, a *models.VersionConflictErr is returned if the course is not at expectedVersion.
+func (c CourseManager) UnregisterStudent(ctx context.Context, courseUUID, studentUUID uuid.UUID, expectedVersion int) error {
+	return c.withTx(ctx, func(ctx context.Context) error {
+		course, err := c.repo.ById(ctx, courseUUID)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the course: %w", err)
+		}
+		if err = c.authorize(ctx, ActionUnregisterStudent, course.CourseMeta, studentUUID); err != nil {
+			return err
+		}
+		if err = checkVersion(course, expectedVersion); err != nil {
+			return err
+		}
+		_, enrolled := course.Students[studentUUID]
+		if !enrolled && waitlistPosition(course, studentUUID) == 0 {
+			return nil
+		}
+		before := snapshot(course)
+		delete(course.Students, studentUUID)
+		c.touch(ctx, course)
+		promoted, err := c.promoteFromWaitlist(ctx, course)
+		if err != nil {
+			return err
+		}
+		err = c.repo.Update(ctx, *course)
+		if err != nil {
+			return fmt.Errorf("unable to update the course: %w", err)
+		}
+		after := updated(course)
+		var events []models.Event
+		if enrolled {
+			events = append(events, c.newEvent(ctx, models.StudentUnregisteredEvent, courseUUID, studentUUID, after))
+		}
+		for _, promotedUUID := range promoted {
+			events = append(events, c.newEvent(ctx, models.StudentPromotedEvent, courseUUID, promotedUUID, after))
+		}
+		if err = c.emit(ctx, events...); err != nil {
+			return err
+		}
+		return c.audit(ctx, models.StudentUnregistered, courseUUID, studentUUID, before, after)
+	})
 }
 
 // Delete deletes the course for the given courseUUID.
 // This is an idempotent operation.
-func (c *CourseManager) Delete(ctx context.Context, courseUUID uuid.UUID) error {
-	err := c.repo.Delete(ctx, courseUUID)
-	if err != nil {
-		return fmt.Errorf("unable to delete the course: %w", err)
-	}
-	return nil
+// Unless expectedVersion is 0, a *models.VersionConflictErr is returned if the course is not at expectedVersion.
+func (c *CourseManager) Delete(ctx context.Context, courseUUID uuid.UUID, expectedVersion int) error {
+	return c.withTx(ctx, func(ctx context.Context) error {
+		course, err := c.repo.ById(ctx, courseUUID)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the course: %w", err)
+		}
+		if err = c.authorize(ctx, ActionDeleteCourse, course.CourseMeta, uuid.Nil); err != nil {
+			return err
+		}
+		if err = checkVersion(course, expectedVersion); err != nil {
+			return err
+		}
+		if err = c.repo.Delete(ctx, courseUUID); err != nil {
+			return fmt.Errorf("unable to delete the course: %w", err)
+		}
+		if err = c.emit(ctx, c.newEvent(ctx, models.CourseDeletedEvent, courseUUID, uuid.Nil, nil)); err != nil {
+			return err
+		}
+		return c.audit(ctx, models.CourseDeleted, courseUUID, uuid.Nil, course, nil)
+	})
 }
 
-// List returns all courses in the repo.
-// It returns an error if it fails to fetch courses from the repo.
-func (c *CourseManager) List(ctx context.Context) ([]models.Course, error) {
-	courses, err := c.repo.List(ctx)
+// List returns the page of the courses selected by the given query, which has DefaultCourseLimit courses
+// unless the query limits it. The HasFreeSeats filter applies the capacity of the Policy of the CourseManager.
+// It returns a *models.ValidationErr if the query is invalid.
+func (c *CourseManager) List(ctx context.Context, query models.CourseQuery) (*models.CoursePage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultCourseLimit
+	}
+	query.DefaultCapacity = c.policy.CourseMaxStudent
+	page, err := c.repo.List(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve courses: %w", err)
 	}
-	return courses, nil
+	if page.Courses == nil {
+		page.Courses = []models.Course{}
+	}
+	return page, nil
 }
 
 // Get returns the models.Course for the given course UUID.
+// It returns a *models.NotFoundError if the course does not exist.
 func (c CourseManager) Get(ctx context.Context, courseUUID uuid.UUID) (*models.Course, error) {
 	course, err := c.repo.ById(ctx, courseUUID)
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve course by UUID: %w", err)
 	}
-	if course.Uuid == uuid.Nil {
-		return nil, NewCourseNotFoundErr(courseUUID)
-	}
 	return course, nil
 }
+
+// checkTutor returns a *models.NotFoundError if the given tutor does not exist,
+// and a *CourseConstraintErr if they cannot facilitate another course.
+func (c *CourseManager) checkTutor(ctx context.Context, tutorUUID uuid.UUID) error {
+	_, err := c.repo.TutorById(ctx, tutorUUID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the tutor: %w", err)
+	}
+	coursesByTutor, err := c.repo.ByTutor(ctx, tutorUUID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve courses: %w", err)
+	}
+	if len(coursesByTutor) >= c.policy.TutorMaxCourse {
+		return &CourseConstraintErr{Constraint: TutorMaxCourse, Limit: c.policy.TutorMaxCourse, TutorUUID: tutorUUID}
+	}
+	return nil
+}
+
+// timestamp returns the current time of the clock of the CourseManager in UTC.
+func (c CourseManager) timestamp() time.Time {
+	// PostgreSQL stores microseconds.
+	return c.now().UTC().Truncate(time.Microsecond)
+}
+
+// touch records that the given course is modified now by the actor of the context.
+func (c CourseManager) touch(ctx context.Context, course *models.Course) {
+	course.UpdatedAt = c.timestamp()
+	course.UpdatedBy = ActorFromContext(ctx)
+}
+
+// checkVersion returns a *models.VersionConflictErr if the given course is not at the expected version.
+// An expected version of 0 matches any version.
+func checkVersion(course *models.Course, expectedVersion int) error {
+	if expectedVersion != 0 && course.Version != expectedVersion {
+		return models.NewVersionConflictErr(course.Uuid, expectedVersion, course.Version)
+	}
+	return nil
+}
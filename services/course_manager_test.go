@@ -2,12 +2,16 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/audit"
 	. "github.com/tomasdembelli/course-manager/db-mock"
 	"github.com/tomasdembelli/course-manager/models"
 )
@@ -45,6 +49,7 @@ func TestNewCourseManager(t *testing.T) {
 			want: CourseManager{
 				repo:   &MockRepo{},
 				logger: log.Default(),
+				policy: DefaultPolicy,
 			},
 		},
 		{
@@ -56,6 +61,7 @@ func TestNewCourseManager(t *testing.T) {
 			want: CourseManager{
 				repo:   &MockRepo{},
 				logger: log.Default(),
+				policy: DefaultPolicy,
 			},
 		},
 	}
@@ -66,6 +72,11 @@ func TestNewCourseManager(t *testing.T) {
 				t.Errorf("NewCourseManager() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if !tt.wantErr && got.now == nil {
+				t.Errorf("NewCourseManager() got no clock")
+			}
+			// Functions are only deeply equal if they are nil.
+			got.now = nil
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewCourseManager() got = %v, want %v", got, tt.want)
 			}
@@ -79,28 +90,40 @@ func TestNewCourseManager(t *testing.T) {
 func generateUsersInCourse(numberOfStudents int) models.Course {
 	course := models.Course{
 		CourseMeta: models.CourseMeta{
-			Name: "test courseMeta",
-			Uuid: fixedUuid,
-			Tutor: &models.Tutor{
-				User: models.User{
-					Uuid: fixedUuid,
-				},
-			},
+			Name:      "test courseMeta",
+			Uuid:      fixedUuid,
+			TutorUUID: fixedUuid,
 		},
-		Students: make(map[uuid.UUID]models.Student),
+		Students: make(map[uuid.UUID]models.Enrollment),
 	}
 
 	for i := 0; i < numberOfStudents; i++ {
 		studentUuid := uuid.New()
-		course.Students[studentUuid] = models.Student{
-			User: models.User{
-				Uuid: studentUuid,
-			},
-		}
+		course.Students[studentUuid] = models.Enrollment{StudentUUID: studentUuid}
 	}
 	return course
 }
 
+// fixedTutors returns the tutors referenced by the courses of generateUsersInCourse.
+func fixedTutors() map[uuid.UUID]models.Tutor {
+	return map[uuid.UUID]models.Tutor{fixedUuid: {User: models.User{Uuid: fixedUuid}}}
+}
+
+// generateStudents returns the given number of students by UUID.
+func generateStudents(numberOfStudents int) map[uuid.UUID]models.Student {
+	students := make(map[uuid.UUID]models.Student, numberOfStudents)
+	for i := 0; i < numberOfStudents; i++ {
+		studentUuid := uuid.New()
+		students[studentUuid] = models.Student{User: models.User{Uuid: studentUuid}}
+	}
+	return students
+}
+
+// fixedStudents returns a student with the fixedUuid.
+func fixedStudents() map[uuid.UUID]models.Student {
+	return map[uuid.UUID]models.Student{fixedUuid: {User: models.User{Uuid: fixedUuid}}}
+}
+
 func TestCourseManager_Create(t *testing.T) {
 	predefinedCourse := generateUsersInCourse(10)
 
@@ -123,20 +146,29 @@ func TestCourseManager_Create(t *testing.T) {
 		{
 			name:        "nil tutor error",
 			fields:      fields{repo: &MockRepo{}},
-			args:        args{ctx: context.TODO(), courseMeta: models.CourseMeta{}},
+			args:        args{ctx: context.TODO(), courseMeta: models.CourseMeta{Name: "test course"}},
 			want:        nil,
 			wantErr:     true,
-			expectedErr: NewNilErr("tutor"),
+			expectedErr: &models.ValidationErr{Fields: []models.FieldError{{Field: "tutorUUID", Message: "tutorUUID cannot be empty"}}},
+		},
+		{
+			name:   "tutor not found",
+			fields: fields{repo: NewMockRepo(&Config{})},
+			args: args{ctx: context.TODO(), courseMeta: models.CourseMeta{
+				Name:      "test course",
+				TutorUUID: fixedUuid,
+			},
+			},
+			want:        nil,
+			wantErr:     true,
+			expectedErr: fmt.Errorf("unable to retrieve the tutor: %w", models.NewTutorNotFoundErr(fixedUuid)),
 		},
 		{
 			name:   "error at ByTutor",
-			fields: fields{repo: NewMockRepo(&Config{ErrByTutor: NewMockError()})},
+			fields: fields{repo: NewMockRepo(&Config{ErrByTutor: NewMockError(), TutorByUUID: fixedTutors()})},
 			args: args{ctx: context.TODO(), courseMeta: models.CourseMeta{
-				Tutor: &models.Tutor{
-					User: models.User{
-						Uuid: fixedUuid,
-					},
-				},
+				Name:      "test course",
+				TutorUUID: fixedUuid,
 			},
 			},
 			want:        nil,
@@ -146,40 +178,37 @@ func TestCourseManager_Create(t *testing.T) {
 		{
 			name: "tutor max courseMeta validation",
 			fields: fields{repo: NewMockRepo(&Config{
+				TutorByUUID: fixedTutors(),
 				CourseByUUID: map[uuid.UUID]models.Course{
 					uuid.New(): {
 						CourseMeta: models.CourseMeta{
-							Tutor: &models.Tutor{
-								User: models.User{Uuid: fixedUuid},
-							},
-							Name: "courseMeta 1",
+							TutorUUID: fixedUuid,
+							Name:      "courseMeta 1",
 						},
 					},
 					uuid.New(): {
 						CourseMeta: models.CourseMeta{
-							Tutor: &models.Tutor{
-								User: models.User{Uuid: fixedUuid},
-							},
-							Name: "courseMeta 2",
+							TutorUUID: fixedUuid,
+							Name:      "courseMeta 2",
 						},
 					},
 				},
 			})},
 			args: args{ctx: context.TODO(), courseMeta: models.CourseMeta{
-				Tutor: &models.Tutor{
-					User: models.User{Uuid: fixedUuid},
-				},
+				Name:      "test course",
+				TutorUUID: fixedUuid,
 			},
 			},
 			want:        nil,
 			wantErr:     true,
-			expectedErr: NewCourseConstraintErr(tutorMaxCourseMsg),
+			expectedErr: &CourseConstraintErr{Constraint: TutorMaxCourse, Limit: DefaultPolicy.TutorMaxCourse, TutorUUID: fixedUuid},
 		},
 		{
 			name: "err at Create",
 			fields: fields{
 				repo: NewMockRepo(&Config{
-					ErrCreate: NewMockError(),
+					ErrCreate:   NewMockError(),
+					TutorByUUID: fixedTutors(),
 				}),
 			},
 			args: args{
@@ -193,7 +222,8 @@ func TestCourseManager_Create(t *testing.T) {
 			name: "err at ById",
 			fields: fields{
 				repo: NewMockRepo(&Config{
-					ErrById: NewMockError(),
+					ErrById:     NewMockError(),
+					TutorByUUID: fixedTutors(),
 				}),
 			},
 			args: args{
@@ -233,9 +263,9 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 		logger *log.Logger
 	}
 	type args struct {
-		ctx        context.Context
-		courseUUID uuid.UUID
-		student    models.Student
+		ctx         context.Context
+		courseUUID  uuid.UUID
+		studentUUID uuid.UUID
 	}
 	tests := []struct {
 		name               string
@@ -243,6 +273,7 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 		args               args
 		wantErr            bool
 		expectedErrMessage string
+		wantStatus         RegistrationStatus
 	}{
 		{
 			name: "error at ById",
@@ -252,47 +283,48 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 				}),
 			},
 			args: args{
-				ctx:        context.TODO(),
-				courseUUID: uuid.New(),
-				student:    models.Student{},
+				ctx:         context.TODO(),
+				courseUUID:  uuid.New(),
+				studentUUID: uuid.New(),
 			},
 			wantErr:            true,
 			expectedErrMessage: "unable to retrieve the course: mock error",
 		},
 		{
-			name: "course max student validation",
+			name: "full course waitlists the student",
 			fields: fields{
-				repo: NewMockRepo(&Config{CourseByUUID: map[uuid.UUID]models.Course{
-					predefinedCourseWith20Students.Uuid: predefinedCourseWith20Students,
-				}}),
+				repo: NewMockRepo(&Config{
+					CourseByUUID: map[uuid.UUID]models.Course{
+						predefinedCourseWith20Students.Uuid: predefinedCourseWith20Students,
+					},
+					StudentByUUID: fixedStudents(),
+				}),
 			},
 			args: args{
-				ctx:        context.TODO(),
-				courseUUID: predefinedCourseWith20Students.Uuid,
-				student:    models.Student{},
+				ctx:         context.TODO(),
+				courseUUID:  predefinedCourseWith20Students.Uuid,
+				studentUUID: fixedUuid,
 			},
-			wantErr:            true,
-			expectedErrMessage: NewCourseConstraintErr(courseMaxStudentMsg).Error(),
+			wantStatus: Waitlisted,
 		},
 		{
-			name: "err at ByStudent",
+			name: "err at CountStudentCourses",
 			fields: fields{
 				repo: NewMockRepo(&Config{
-					ErrByStudent: NewMockError(),
+					ErrByStudent:  NewMockError(),
+					StudentByUUID: fixedStudents(),
 					CourseByUUID: map[uuid.UUID]models.Course{
 						predefinedCourseWith10Students.Uuid: predefinedCourseWith10Students,
 					},
 				}),
 			},
 			args: args{
-				ctx:        context.TODO(),
-				courseUUID: predefinedCourseWith10Students.Uuid,
-				student: models.Student{
-					User: models.User{Uuid: fixedUuid},
-				},
+				ctx:         context.TODO(),
+				courseUUID:  predefinedCourseWith10Students.Uuid,
+				studentUUID: fixedUuid,
 			},
 			wantErr:            true,
-			expectedErrMessage: fmt.Errorf("unable to retrieve courses: %w", NewMockError()).Error(),
+			expectedErrMessage: fmt.Errorf("unable to count the courses of the student: %w", NewMockError()).Error(),
 		},
 		{
 			name: "error at Update",
@@ -301,19 +333,35 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 					CourseByUUID: map[uuid.UUID]models.Course{
 						fixedUuid: predefinedCourseWith10Students,
 					},
-					ErrUpdate: NewMockError(),
+					StudentByUUID: fixedStudents(),
+					ErrUpdate:     NewMockError(),
 				}),
 			},
 			args: args{
-				ctx:        context.TODO(),
-				courseUUID: fixedUuid,
-				student: models.Student{
-					User: models.User{Uuid: fixedUuid},
-				},
+				ctx:         context.TODO(),
+				courseUUID:  fixedUuid,
+				studentUUID: fixedUuid,
 			},
 			wantErr:            true,
 			expectedErrMessage: "unable to update the course: mock error",
 		},
+		{
+			name: "student not found",
+			fields: fields{
+				repo: NewMockRepo(&Config{
+					CourseByUUID: map[uuid.UUID]models.Course{
+						fixedUuid: copyCourse(predefinedCourseWith10Students),
+					},
+				}),
+			},
+			args: args{
+				ctx:         context.TODO(),
+				courseUUID:  fixedUuid,
+				studentUUID: fixedUuid,
+			},
+			wantErr:            true,
+			expectedErrMessage: fmt.Errorf("unable to retrieve the student: %w", models.NewStudentNotFoundErr(fixedUuid)).Error(),
+		},
 		{
 			name: "successful registry",
 			fields: fields{
@@ -321,16 +369,16 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 					CourseByUUID: map[uuid.UUID]models.Course{
 						fixedUuid: predefinedCourseWith10Students,
 					},
+					StudentByUUID: fixedStudents(),
 				}),
 			},
 			args: args{
-				ctx:        context.TODO(),
-				courseUUID: fixedUuid,
-				student: models.Student{
-					User: models.User{Uuid: fixedUuid},
-				},
+				ctx:         context.TODO(),
+				courseUUID:  fixedUuid,
+				studentUUID: fixedUuid,
 			},
-			wantErr: false,
+			wantErr:    false,
+			wantStatus: Enrolled,
 		},
 	}
 	for _, tt := range tests {
@@ -339,7 +387,7 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 			if err != nil {
 				t.Fatal("unexpected error", err)
 			}
-			err = c.RegisterStudent(tt.args.ctx, tt.args.courseUUID, tt.args.student)
+			status, err := c.RegisterStudent(tt.args.ctx, tt.args.courseUUID, tt.args.studentUUID, 0)
 			if tt.wantErr && tt.expectedErrMessage != err.Error() {
 				t.Errorf("RegisterStudent() error = %v, wantErr %v", err.Error(), tt.expectedErrMessage)
 			}
@@ -347,12 +395,25 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 				if err != nil {
 					t.Errorf("unexpected error RegisterStudent() error = %v", err)
 				}
-				courses, err := c.repo.ByStudent(tt.args.ctx, tt.args.student.Uuid)
+				if status != tt.wantStatus {
+					t.Errorf("RegisterStudent() got = %v, want %v", status, tt.wantStatus)
+				}
+				if status == Waitlisted {
+					position, err := c.WaitlistPosition(tt.args.ctx, tt.args.courseUUID, tt.args.studentUUID)
+					if err != nil {
+						t.Fatal("unexpected error", err)
+					}
+					if position != 1 {
+						t.Errorf("WaitlistPosition() got = %v, want %v", position, 1)
+					}
+					return
+				}
+				courses, err := c.repo.ByStudent(tt.args.ctx, tt.args.studentUUID)
 				if err != nil {
 					t.Fatal("unexpected error", err)
 				}
-				if courses[0].Students[tt.args.student.Uuid].Uuid != tt.args.student.Uuid {
-					t.Errorf("failed to register the studentUUID. expected %v, got %v", tt.args.student.Uuid, courses[0].Students[tt.args.student.Uuid].Uuid)
+				if courses[0].Students[tt.args.studentUUID].StudentUUID != tt.args.studentUUID {
+					t.Errorf("failed to register the studentUUID. expected %v, got %v", tt.args.studentUUID, courses[0].Students[tt.args.studentUUID].StudentUUID)
 				}
 			}
 		})
@@ -361,9 +422,9 @@ func TestCourseManager_RegisterStudent(t *testing.T) {
 
 func TestCourseManager_UnregisterStudent(t *testing.T) {
 	predefinedCourse := generateUsersInCourse(10)
-	var anExistingStudent models.Student
-	for _, student := range predefinedCourse.Students {
-		anExistingStudent = student
+	var anExistingStudent models.Enrollment
+	for _, enrollment := range predefinedCourse.Students {
+		anExistingStudent = enrollment
 		break
 	}
 	type fields struct {
@@ -410,11 +471,29 @@ func TestCourseManager_UnregisterStudent(t *testing.T) {
 			args: args{
 				ctx:         context.TODO(),
 				courseUUID:  fixedUuid,
-				studentUUID: fixedUuid,
+				studentUUID: anExistingStudent.StudentUUID,
 			},
 			wantErr:            true,
 			expectedErrMessage: "unable to update the course: mock error",
 		},
+		{
+			// The course is not updated, so the update error is not returned.
+			name: "student not registered",
+			fields: fields{
+				repo: NewMockRepo(&Config{
+					CourseByUUID: map[uuid.UUID]models.Course{
+						fixedUuid: predefinedCourse,
+					},
+					ErrUpdate: NewMockError(),
+				}),
+			},
+			args: args{
+				ctx:         context.TODO(),
+				courseUUID:  fixedUuid,
+				studentUUID: fixedUuid,
+			},
+			wantErr: false,
+		},
 		{
 			name: "successful un-registry",
 			fields: fields{
@@ -427,7 +506,7 @@ func TestCourseManager_UnregisterStudent(t *testing.T) {
 			args: args{
 				ctx:         context.TODO(),
 				courseUUID:  fixedUuid,
-				studentUUID: anExistingStudent.Uuid,
+				studentUUID: anExistingStudent.StudentUUID,
 			},
 			wantErr: false,
 		},
@@ -438,7 +517,7 @@ func TestCourseManager_UnregisterStudent(t *testing.T) {
 			if err != nil {
 				t.Fatal("unexpected error", err)
 			}
-			err = c.UnregisterStudent(tt.args.ctx, tt.args.courseUUID, tt.args.studentUUID)
+			err = c.UnregisterStudent(tt.args.ctx, tt.args.courseUUID, tt.args.studentUUID, 0)
 			if tt.wantErr && tt.expectedErrMessage != err.Error() {
 				t.Errorf("UnregisterStudent() error = %v, wantErr %v", err.Error(), tt.expectedErrMessage)
 			}
@@ -467,8 +546,9 @@ func TestCourseManager_Delete(t *testing.T) {
 		logger *log.Logger
 	}
 	type args struct {
-		ctx        context.Context
-		courseUUID uuid.UUID
+		ctx             context.Context
+		courseUUID      uuid.UUID
+		expectedVersion int
 	}
 	tests := []struct {
 		name               string
@@ -477,20 +557,65 @@ func TestCourseManager_Delete(t *testing.T) {
 		wantErr            bool
 		expectedErrMessage string
 	}{
+		{
+			name: "error at repo ById",
+			fields: fields{
+				repo: NewMockRepo(&Config{
+					ErrById: NewMockError(),
+				}),
+			},
+			args: args{
+				ctx:        context.TODO(),
+				courseUUID: uuid.New(),
+			},
+			wantErr:            true,
+			expectedErrMessage: "unable to retrieve the course: mock error",
+		},
 		{
 			name: "error at repo Delete",
 			fields: fields{
 				repo: NewMockRepo(&Config{
+					CourseByUUID: map[uuid.UUID]models.Course{
+						fixedUuid: {},
+					},
 					ErrDelete: NewMockError(),
 				}),
 			},
 			args: args{
 				ctx:        context.TODO(),
-				courseUUID: uuid.New(),
+				courseUUID: fixedUuid,
 			},
 			wantErr:            true,
 			expectedErrMessage: "unable to delete the course: mock error",
 		},
+		{
+			name: "unknown course",
+			fields: fields{
+				repo: NewMockRepo(nil),
+			},
+			args: args{
+				ctx:        context.TODO(),
+				courseUUID: uuid.New(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "stale version",
+			fields: fields{
+				repo: NewMockRepo(&Config{
+					CourseByUUID: map[uuid.UUID]models.Course{
+						fixedUuid: {CourseMeta: models.CourseMeta{Uuid: fixedUuid}, Version: 3},
+					},
+				}),
+			},
+			args: args{
+				ctx:             context.TODO(),
+				courseUUID:      fixedUuid,
+				expectedVersion: 2,
+			},
+			wantErr:            true,
+			expectedErrMessage: (&models.VersionConflictErr{CourseUUID: fixedUuid, Actual: 3, Expected: 2}).Error(),
+		},
 		{
 			name: "successful Delete",
 			fields: fields{
@@ -513,20 +638,19 @@ func TestCourseManager_Delete(t *testing.T) {
 			if err != nil {
 				t.Fatal("unexpected error", err)
 			}
-			err = c.Delete(tt.args.ctx, tt.args.courseUUID)
+			err = c.Delete(tt.args.ctx, tt.args.courseUUID, tt.args.expectedVersion)
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("expected error, but none raised")
+					t.Fatalf("expected error, but none raised")
 				}
 				if tt.expectedErrMessage != err.Error() {
-					t.Errorf("RegisterStudent() error = %v, wantErr %v", err.Error(), tt.expectedErrMessage)
+					t.Errorf("Delete() error = %v, wantErr %v", err.Error(), tt.expectedErrMessage)
 				}
 			} else {
 				if err != nil {
 					t.Errorf("unexpected error Delete() error = %v", err)
 				}
-				course, _ := c.repo.ById(tt.args.ctx, tt.args.courseUUID)
-				if course.Uuid != uuid.Nil {
+				if _, err = c.repo.ById(tt.args.ctx, tt.args.courseUUID); !errors.Is(err, models.ErrNotFound) {
 					t.Errorf("failed to delete course %v", tt.args.courseUUID)
 				}
 
@@ -542,18 +666,36 @@ func TestCourseManager_List(t *testing.T) {
 			Uuid: uuid.New(),
 		},
 	}
+	course := func(name string, numberOfStudents int) models.Course {
+		course := models.Course{
+			CourseMeta: models.CourseMeta{Uuid: uuid.New(), Name: name, TutorUUID: tutor.Uuid},
+			Students:   make(map[uuid.UUID]models.Enrollment),
+		}
+		for i := 0; i < numberOfStudents; i++ {
+			studentUUID := uuid.New()
+			course.Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID}
+		}
+		return course
+	}
+	algebra, biology, chemistry := course("algebra", 1), course("biology", 3), course("chemistry", 2)
+	courseByUUID := map[uuid.UUID]models.Course{
+		algebra.Uuid:   algebra,
+		biology.Uuid:   biology,
+		chemistry.Uuid: chemistry,
+	}
 	type fields struct {
 		repo   Repo
 		logger *log.Logger
 	}
 	type args struct {
-		ctx context.Context
+		ctx   context.Context
+		query models.CourseQuery
 	}
 	tests := []struct {
 		name               string
 		fields             fields
 		args               args
-		want               []models.Course
+		want               *models.CoursePage
 		wantErr            bool
 		expectedErrMessage string
 	}{
@@ -570,47 +712,94 @@ func TestCourseManager_List(t *testing.T) {
 			wantErr:            true,
 			expectedErrMessage: "unable to retrieve courses: mock error",
 		},
+		{
+			name: "invalid query",
+			fields: fields{
+				repo: NewMockRepo(nil),
+			},
+			args: args{
+				ctx:   context.TODO(),
+				query: models.CourseQuery{Sort: "tutor", Limit: models.MaxCourseLimit + 1},
+			},
+			wantErr:            true,
+			expectedErrMessage: "validation failed: sort must be one of name, createdAt and enrollments, limit must be between 0 and 100",
+		},
+		{
+			name: "no courses",
+			fields: fields{
+				repo: NewMockRepo(nil),
+			},
+			args: args{
+				ctx: context.TODO(),
+			},
+			want: &models.CoursePage{Courses: []models.Course{}},
+		},
 		{
 			name: "successful listing",
 			fields: fields{
-				repo: NewMockRepo(&Config{
-					CourseByUUID: map[uuid.UUID]models.Course{
-						fixedUuid: {
-							CourseMeta: models.CourseMeta{
-								Uuid:  fixedUuid,
-								Name:  "courseMeta 1",
-								Tutor: &tutor,
-							},
-						},
-					},
-				}),
+				repo: NewMockRepo(&Config{CourseByUUID: courseByUUID}),
 			},
 			args: args{
 				ctx: context.TODO(),
 			},
-			want: []models.Course{
-				{CourseMeta: models.CourseMeta{
-					Uuid:  fixedUuid,
-					Name:  "courseMeta 1",
-					Tutor: &tutor,
-				},
+			want: &models.CoursePage{Courses: []models.Course{algebra, biology, chemistry}},
+		},
+		{
+			name: "first page by enrollments",
+			fields: fields{
+				repo: NewMockRepo(&Config{CourseByUUID: courseByUUID}),
+			},
+			args: args{
+				ctx:   context.TODO(),
+				query: models.CourseQuery{Sort: models.SortByEnrollments, Descending: true, Limit: 2},
+			},
+			want: &models.CoursePage{
+				Courses:    []models.Course{biology, chemistry},
+				NextCursor: models.CourseQuery{Sort: models.SortByEnrollments, Descending: true}.NextCursor(chemistry),
+			},
+		},
+		{
+			name: "last page by enrollments",
+			fields: fields{
+				repo: NewMockRepo(&Config{CourseByUUID: courseByUUID}),
+			},
+			args: args{
+				ctx: context.TODO(),
+				query: models.CourseQuery{
+					Sort:       models.SortByEnrollments,
+					Descending: true,
+					Limit:      2,
+					Cursor:     models.CourseQuery{Sort: models.SortByEnrollments, Descending: true}.NextCursor(chemistry),
 				},
 			},
+			want: &models.CoursePage{Courses: []models.Course{algebra}},
+		},
+		{
+			name: "courses with free seats",
+			fields: fields{
+				repo: NewMockRepo(&Config{CourseByUUID: courseByUUID}),
+			},
+			args: args{
+				ctx:   context.TODO(),
+				query: models.CourseQuery{CourseFilter: models.CourseFilter{HasFreeSeats: true}},
+			},
+			want: &models.CoursePage{Courses: []models.Course{algebra, chemistry}},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewCourseManager(tt.fields.repo, tt.fields.logger)
+			c, err := NewCourseManager(tt.fields.repo, tt.fields.logger,
+				WithPolicy(Policy{TutorMaxCourse: 2, StudentMaxCourse: 4, CourseMaxStudent: 3}))
 			if err != nil {
 				t.Fatal("unexpected error", err)
 			}
-			got, err := c.List(tt.args.ctx)
+			got, err := c.List(tt.args.ctx, tt.args.query)
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("expected error, but none raised")
+					t.Fatalf("expected error, but none raised")
 				}
 				if tt.expectedErrMessage != err.Error() {
-					t.Errorf("RegisterStudent() error = %v, wantErr %v", err.Error(), tt.expectedErrMessage)
+					t.Errorf("List() error = %v, wantErr %v", err.Error(), tt.expectedErrMessage)
 				}
 			} else {
 				if err != nil {
@@ -697,3 +886,607 @@ func TestCourseManager_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestCourseManager_RegisterStudent_concurrent(t *testing.T) {
+	predefinedCourse := generateUsersInCourse(DefaultPolicy.CourseMaxStudent - 1)
+	const registrations = 10
+	students := generateStudents(registrations)
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{
+			fixedUuid: predefinedCourse,
+		},
+		StudentByUUID: students,
+	}), nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	statuses := make(chan RegistrationStatus, registrations)
+	var wg sync.WaitGroup
+	for studentUUID := range students {
+		wg.Add(1)
+		go func(studentUUID uuid.UUID) {
+			defer wg.Done()
+			status, err := c.RegisterStudent(context.TODO(), fixedUuid, studentUUID, 0)
+			if err != nil {
+				t.Errorf("unexpected error RegisterStudent() error = %v", err)
+			}
+			statuses <- status
+		}(studentUUID)
+	}
+	wg.Wait()
+	close(statuses)
+
+	var enrolled int
+	for status := range statuses {
+		if status == Enrolled {
+			enrolled++
+		}
+	}
+	if enrolled != 1 {
+		t.Errorf("expected exactly 1 registration to be enrolled, got %d", enrolled)
+	}
+	course, err := c.Get(context.TODO(), fixedUuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(course.Students) != DefaultPolicy.CourseMaxStudent {
+		t.Errorf("expected %d students, got %d", DefaultPolicy.CourseMaxStudent, len(course.Students))
+	}
+	if len(course.Waitlist) != registrations-1 {
+		t.Errorf("expected %d waitlisted students, got %d", registrations-1, len(course.Waitlist))
+	}
+}
+
+func TestCourseManager_RegisterStudent_rollback(t *testing.T) {
+	predefinedCourse := generateUsersInCourse(10)
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{
+			fixedUuid: predefinedCourse,
+		},
+		StudentByUUID: fixedStudents(),
+		ErrUpdate:     NewMockError(),
+	}), nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	studentUUID := fixedUuid
+	_, err = c.RegisterStudent(context.TODO(), fixedUuid, studentUUID, 0)
+	if err == nil {
+		t.Fatal("expected error, but none raised")
+	}
+	course, err := c.Get(context.TODO(), fixedUuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, ok := course.Students[studentUUID]; ok {
+		t.Errorf("expected the failed registration to be rolled back")
+	}
+}
+
+func TestCourseManager_RegisterStudent_version(t *testing.T) {
+	predefinedCourse := generateUsersInCourse(10)
+	predefinedCourse.Version = 3
+	tests := []struct {
+		name            string
+		expectedVersion int
+		wantConflict    bool
+	}{
+		{name: "any version", expectedVersion: 0},
+		{name: "current version", expectedVersion: 3},
+		{name: "stale version", expectedVersion: 2, wantConflict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCourseManager(NewMockRepo(&Config{
+				CourseByUUID: map[uuid.UUID]models.Course{
+					fixedUuid: copyCourse(predefinedCourse),
+				},
+				StudentByUUID: fixedStudents(),
+			}), nil)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			_, err = c.RegisterStudent(context.TODO(), fixedUuid, fixedUuid, tt.expectedVersion)
+			var conflictErr *models.VersionConflictErr
+			if errors.As(err, &conflictErr) != tt.wantConflict {
+				t.Fatalf("RegisterStudent() error = %v, wantConflict %v", err, tt.wantConflict)
+			}
+			if !tt.wantConflict && err != nil {
+				t.Fatalf("unexpected error RegisterStudent() error = %v", err)
+			}
+			course, err := c.Get(context.TODO(), fixedUuid)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			wantVersion := 4
+			if tt.wantConflict {
+				wantVersion = 3
+			}
+			if course.Version != wantVersion {
+				t.Errorf("expected version %d, got %d", wantVersion, course.Version)
+			}
+		})
+	}
+}
+
+func TestCourseManager_UpdateMeta(t *testing.T) {
+	predefinedCourse := generateUsersInCourse(2)
+	predefinedCourse.Version = 3
+	busyTutor, freeTutor, unknownTutor, unknownCourse := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	otherCourse := generateUsersInCourse(0)
+	otherCourse.Uuid = uuid.New()
+	otherCourse.TutorUUID = busyTutor
+
+	tests := []struct {
+		name            string
+		courseMeta      models.CourseMeta
+		expectedVersion int
+		wantErr         error
+		wantConflict    bool
+		wantInvalid     []string
+	}{
+		{
+			name:       "rename the course",
+			courseMeta: models.CourseMeta{Uuid: fixedUuid, Name: "renamed", TutorUUID: fixedUuid},
+		},
+		{
+			name:            "reassign the tutor",
+			courseMeta:      models.CourseMeta{Uuid: fixedUuid, Name: "test course", TutorUUID: freeTutor, MaxStudents: 5},
+			expectedVersion: 3,
+		},
+		{
+			name:       "reassign to a tutor at their course limit",
+			courseMeta: models.CourseMeta{Uuid: fixedUuid, Name: "test course", TutorUUID: busyTutor},
+			wantErr:    NewCourseConstraintErr(TutorMaxCourse, 1),
+		},
+		{
+			name:       "reassign to an unknown tutor",
+			courseMeta: models.CourseMeta{Uuid: fixedUuid, Name: "test course", TutorUUID: unknownTutor},
+			wantErr:    models.NewTutorNotFoundErr(unknownTutor),
+		},
+		{
+			name:        "invalid metadata",
+			courseMeta:  models.CourseMeta{Uuid: fixedUuid, Name: " ", MaxStudents: -1},
+			wantInvalid: []string{"name", "tutorUUID", "maxStudents"},
+		},
+		{
+			name:        "capacity below the enrolled students",
+			courseMeta:  models.CourseMeta{Uuid: fixedUuid, Name: "test course", TutorUUID: fixedUuid, MaxStudents: 1},
+			wantInvalid: []string{"maxStudents"},
+		},
+		{
+			name:       "course not found",
+			courseMeta: models.CourseMeta{Uuid: unknownCourse, Name: "test course", TutorUUID: fixedUuid},
+			wantErr:    models.NewCourseNotFoundErr(unknownCourse),
+		},
+		{
+			name:            "stale version",
+			courseMeta:      models.CourseMeta{Uuid: fixedUuid, Name: "renamed", TutorUUID: fixedUuid},
+			expectedVersion: 2,
+			wantConflict:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCourseManager(NewMockRepo(&Config{
+				CourseByUUID: map[uuid.UUID]models.Course{
+					fixedUuid:        copyCourse(predefinedCourse),
+					otherCourse.Uuid: otherCourse,
+				},
+				TutorByUUID: map[uuid.UUID]models.Tutor{
+					fixedUuid: {User: models.User{Uuid: fixedUuid}},
+					busyTutor: {User: models.User{Uuid: busyTutor}},
+					freeTutor: {User: models.User{Uuid: freeTutor}},
+				},
+			}), nil, WithPolicy(Policy{TutorMaxCourse: 1, StudentMaxCourse: 1, CourseMaxStudent: 10}))
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			got, err := c.UpdateMeta(context.TODO(), tt.courseMeta, tt.expectedVersion)
+			var conflictErr *models.VersionConflictErr
+			if errors.As(err, &conflictErr) != tt.wantConflict {
+				t.Fatalf("UpdateMeta() error = %v, wantConflict %v", err, tt.wantConflict)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateMeta() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validationErr *models.ValidationErr
+			if errors.As(err, &validationErr) != (tt.wantInvalid != nil) {
+				t.Fatalf("UpdateMeta() error = %v, wantInvalid %v", err, tt.wantInvalid)
+			}
+			if tt.wantInvalid != nil {
+				var fields []string
+				for _, field := range validationErr.Fields {
+					fields = append(fields, field.Field)
+				}
+				if !reflect.DeepEqual(fields, tt.wantInvalid) {
+					t.Errorf("UpdateMeta() invalid fields = %v, want %v", fields, tt.wantInvalid)
+				}
+			}
+			if tt.wantErr != nil || tt.wantConflict || tt.wantInvalid != nil {
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if got.CourseMeta != tt.courseMeta {
+				t.Errorf("UpdateMeta() got = %v, want %v", got.CourseMeta, tt.courseMeta)
+			}
+			if len(got.Students) != len(predefinedCourse.Students) || got.Version != predefinedCourse.Version+1 {
+				t.Errorf("UpdateMeta() got = %v, want the students and version %d", got, predefinedCourse.Version+1)
+			}
+		})
+	}
+}
+
+func copyCourse(course models.Course) models.Course {
+	students := course.Students
+	course.Students = make(map[uuid.UUID]models.Enrollment, len(students))
+	for studentUUID, enrollment := range students {
+		course.Students[studentUUID] = enrollment
+	}
+	course.Waitlist = append([]uuid.UUID(nil), course.Waitlist...)
+	return course
+}
+
+func TestCourseManager_courseNotFound(t *testing.T) {
+	unknownCourse := uuid.New()
+	tests := []struct {
+		name string
+		call func(c CourseManager) error
+	}{
+		{
+			name: "Get",
+			call: func(c CourseManager) error {
+				_, err := c.Get(context.TODO(), unknownCourse)
+				return err
+			},
+		},
+		{
+			name: "RegisterStudent",
+			call: func(c CourseManager) error {
+				_, err := c.RegisterStudent(context.TODO(), unknownCourse, fixedUuid, 0)
+				return err
+			},
+		},
+		{
+			name: "UnregisterStudent",
+			call: func(c CourseManager) error {
+				return c.UnregisterStudent(context.TODO(), unknownCourse, fixedUuid, 0)
+			},
+		},
+		{
+			name: "WaitlistPosition",
+			call: func(c CourseManager) error {
+				_, err := c.WaitlistPosition(context.TODO(), unknownCourse, fixedUuid)
+				return err
+			},
+		},
+		{
+			name: "LeaveWaitlist",
+			call: func(c CourseManager) error {
+				return c.LeaveWaitlist(context.TODO(), unknownCourse, fixedUuid, 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMockRepo(&Config{
+				StudentByUUID: map[uuid.UUID]models.Student{fixedUuid: {User: models.User{Uuid: fixedUuid}}},
+			})
+			c, err := NewCourseManager(repo, nil)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if err = tt.call(c); !errors.Is(err, models.NewCourseNotFoundErr(unknownCourse)) {
+				t.Errorf("%v() error = %v, want %v", tt.name, err, models.NewCourseNotFoundErr(unknownCourse))
+			}
+			page, err := repo.List(context.TODO(), models.CourseQuery{})
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if len(page.Courses) != 0 {
+				t.Errorf("%v() created the courses %v", tt.name, page.Courses)
+			}
+		})
+	}
+}
+
+func TestCourseManager_audit(t *testing.T) {
+	tutorUUID, first, second := uuid.New(), uuid.New(), uuid.New()
+	now := time.Date(2022, 3, 1, 10, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		TutorByUUID: map[uuid.UUID]models.Tutor{tutorUUID: {User: models.User{Uuid: tutorUUID}}},
+		StudentByUUID: map[uuid.UUID]models.Student{
+			first:  {User: models.User{Uuid: first}},
+			second: {User: models.User{Uuid: second}},
+		},
+	}), nil,
+		WithPolicy(Policy{TutorMaxCourse: 2, StudentMaxCourse: 4, CourseMaxStudent: 1}),
+		WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	// The clock is read in UTC, at the microsecond precision of the repos.
+	start := now.UTC().Truncate(time.Microsecond)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	course, err := c.Create(ContextWithActor(context.TODO(), "alice"), models.CourseMeta{Name: "Golang", TutorUUID: tutorUUID})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if course.CreatedAt != at(0) || course.CreatedBy != "alice" || course.UpdatedAt != at(0) || course.UpdatedBy != "alice" {
+		t.Errorf("Create() got = %v, want a course created and updated by alice at %v", course, at(0))
+	}
+
+	now = now.Add(time.Minute)
+	ctx := ContextWithActor(context.TODO(), "bob")
+	for _, studentUUID := range []uuid.UUID{first, second} {
+		if _, err = c.RegisterStudent(ctx, course.Uuid, studentUUID, 0); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	now = now.Add(time.Minute)
+	if err = c.UnregisterStudent(context.TODO(), course.Uuid, first, 0); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	got, err := c.Get(context.TODO(), course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := models.Course{
+		CourseMeta: course.CourseMeta,
+		Students:   map[uuid.UUID]models.Enrollment{second: {StudentUUID: second, EnrolledAt: at(2)}},
+		Version:    4,
+		CreatedAt:  at(0),
+		CreatedBy:  "alice",
+		UpdatedAt:  at(2),
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Get() got = %v, want %v", *got, want)
+	}
+}
+
+func TestCourseManager_History(t *testing.T) {
+	tutorUUID, first, second := uuid.New(), uuid.New(), uuid.New()
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	sink := audit.NewMemorySink()
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		TutorByUUID: map[uuid.UUID]models.Tutor{tutorUUID: {User: models.User{Uuid: tutorUUID}}},
+		StudentByUUID: map[uuid.UUID]models.Student{
+			first:  {User: models.User{Uuid: first}},
+			second: {User: models.User{Uuid: second}},
+		},
+	}), nil,
+		WithPolicy(Policy{TutorMaxCourse: 2, StudentMaxCourse: 4, CourseMaxStudent: 1}),
+		WithClock(func() time.Time { return now }),
+		WithAuditSink(sink))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	ctx := ContextWithActor(context.TODO(), "alice")
+
+	course, err := c.Create(ctx, models.CourseMeta{Name: "Golang", TutorUUID: tutorUUID})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course.Name = "Advanced Golang"
+	if _, err = c.UpdateMeta(ctx, course.CourseMeta, 1); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for _, studentUUID := range []uuid.UUID{first, second, first} {
+		if _, err = c.RegisterStudent(ctx, course.Uuid, studentUUID, 0); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	if err = c.UnregisterStudent(ContextWithActor(context.TODO(), "bob"), course.Uuid, first, 0); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err = c.LeaveWaitlist(ctx, course.Uuid, second, 0); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = c.Delete(ctx, course.Uuid, 0); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	events, err := c.History(context.TODO(), course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	type summary struct {
+		actor       string
+		action      models.AuditAction
+		studentUUID uuid.UUID
+		before      int
+		after       int
+	}
+	// No-ops are not recorded, e.g. the promoted student leaving the waitlist,
+	// and the versions tell the before and after states.
+	want := []summary{
+		{actor: "alice", action: models.CourseCreated, after: 1},
+		{actor: "alice", action: models.CourseUpdated, before: 1, after: 2},
+		{actor: "alice", action: models.StudentRegistered, studentUUID: first, before: 2, after: 3},
+		{actor: "alice", action: models.StudentRegistered, studentUUID: second, before: 3, after: 4},
+		{actor: "bob", action: models.StudentUnregistered, studentUUID: first, before: 4, after: 5},
+		{actor: "alice", action: models.CourseDeleted, before: 5},
+	}
+	var got []summary
+	for _, event := range events {
+		s := summary{actor: event.Actor, action: event.Action}
+		if event.StudentUUID != nil {
+			s.studentUUID = *event.StudentUUID
+		}
+		if event.Before != nil {
+			s.before = event.Before.Version
+		}
+		if event.After != nil {
+			s.after = event.After.Version
+		}
+		if event.Time != now || event.CourseUUID != course.Uuid {
+			t.Errorf("History() got event %v, want an event of the course %v at %v", event, course.Uuid, now)
+		}
+		got = append(got, s)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("History() got = %+v, want %+v", got, want)
+	}
+	// The waitlisted student has been promoted by the unregistration.
+	if events[1].Before.Name != "Golang" || events[1].After.Name != "Advanced Golang" {
+		t.Errorf("History() got the names %v and %v, want the course renamed", events[1].Before.Name, events[1].After.Name)
+	}
+	if enrolled := events[4].After.Students; len(enrolled) != 1 || enrolled[second].EnrolledAt != now {
+		t.Errorf("History() got enrollments %v after the unregistration, want %v enrolled", enrolled, second)
+	}
+	if len(events[3].After.Waitlist) != 1 || len(events[4].After.Waitlist) != 0 {
+		t.Errorf("History() got waitlists %v and %v, want the student promoted", events[3].After.Waitlist, events[4].After.Waitlist)
+	}
+}
+
+// failingSink is an AuditSink failing to record the events.
+type failingSink struct {
+	audit.MemorySink
+}
+
+func (*failingSink) Record(context.Context, models.AuditEvent) error {
+	return NewMockError()
+}
+
+// auditingRepo is a Repo recording the audit events within its units of work, and failing to.
+type auditingRepo struct {
+	*MockRepo
+	failingSink
+}
+
+func TestCourseManager_auditFailure(t *testing.T) {
+	tutorUUID, studentUUID := uuid.New(), uuid.New()
+	repo := &auditingRepo{MockRepo: NewMockRepo(&Config{
+		TutorByUUID:   map[uuid.UUID]models.Tutor{tutorUUID: {User: models.User{Uuid: tutorUUID}}},
+		StudentByUUID: map[uuid.UUID]models.Student{studentUUID: {User: models.User{Uuid: studentUUID}}},
+	})}
+	c, err := NewCourseManager(repo, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course, err := c.Create(context.TODO(), models.CourseMeta{Name: "Golang", TutorUUID: tutorUUID})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	c, err = NewCourseManager(repo, nil, WithAuditSink(repo))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	// The modifications which the Repo cannot audit are rolled back, with their events.
+	wantErr := "unable to record the audit event: mock error"
+	if _, err = c.RegisterStudent(context.TODO(), course.Uuid, studentUUID, 0); err == nil || err.Error() != wantErr {
+		t.Errorf("RegisterStudent() error = %v, want %v", err, wantErr)
+	}
+	if err = c.Delete(context.TODO(), course.Uuid, 0); err == nil || err.Error() != wantErr {
+		t.Errorf("Delete() error = %v, want %v", err, wantErr)
+	}
+	got, err := c.Get(context.TODO(), course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Version != 1 || len(got.Students) != 0 {
+		t.Errorf("Get() got = %+v, want the course unchanged", got)
+	}
+	events, err := repo.PendingEvents(context.TODO(), "relay", 10)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(events) != 1 || events[0].Type != models.CourseCreatedEvent {
+		t.Errorf("PendingEvents() got = %v, want only the creation of the course", events)
+	}
+
+	// The events which another AuditSink fails to record once the modifications have committed are logged.
+	c, err = NewCourseManager(repo, nil, WithAuditSink(&failingSink{}))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = c.RegisterStudent(context.TODO(), course.Uuid, studentUUID, 0); err != nil {
+		t.Errorf("RegisterStudent() error = %v, want nil", err)
+	}
+	if got, err = c.Get(context.TODO(), course.Uuid); err != nil || len(got.Students) != 1 {
+		t.Errorf("Get() got = %+v, %v, want the student registered", got, err)
+	}
+}
+
+// errRetry is the error of the first run of the units of work of a flakyRepo.
+var errRetry = errors.New("serialization failure")
+
+// flakyRepo is a Repo discarding the first run of every unit of work and running it again, as PostgreSQL
+// does on serialization failures, and failing to commit the second run with its commitErr, if any.
+type flakyRepo struct {
+	*MockRepo
+	commitErr error
+}
+
+func (r *flakyRepo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := r.MockRepo.WithTx(ctx, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		return errRetry
+	})
+	if !errors.Is(err, errRetry) {
+		return err
+	}
+	return r.MockRepo.WithTx(ctx, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		return r.commitErr
+	})
+}
+
+func TestCourseManager_auditRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		commitErr error
+		want      []models.AuditAction
+	}{
+		{name: "retried units of work", want: []models.AuditAction{models.CourseCreated, models.StudentRegistered}},
+		{name: "failing units of work", commitErr: NewMockError()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tutorUUID, studentUUID, courseUUID := uuid.New(), uuid.New(), uuid.New()
+			sink := audit.NewMemorySink()
+			c, err := NewCourseManager(&flakyRepo{MockRepo: NewMockRepo(&Config{
+				TutorByUUID:   map[uuid.UUID]models.Tutor{tutorUUID: {User: models.User{Uuid: tutorUUID}}},
+				StudentByUUID: map[uuid.UUID]models.Student{studentUUID: {User: models.User{Uuid: studentUUID}}},
+			}), commitErr: tt.commitErr}, nil, WithAuditSink(sink))
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			_, err = c.Create(context.TODO(), models.CourseMeta{Uuid: courseUUID, Name: "Golang", TutorUUID: tutorUUID})
+			if (err != nil) != (tt.commitErr != nil) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.commitErr)
+			}
+			_, err = c.RegisterStudent(context.TODO(), courseUUID, studentUUID, 0)
+			if (err != nil) != (tt.commitErr != nil) {
+				t.Fatalf("RegisterStudent() error = %v, want %v", err, tt.commitErr)
+			}
+
+			// Only the committed runs of the units of work are audited.
+			events, err := sink.History(context.TODO(), courseUUID)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			var got []models.AuditAction
+			for _, event := range events {
+				got = append(got, event.Action)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("History() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,72 +4,118 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
 )
 
 const (
-	courseNotFoundFmt = "Course with UUID = %v not found"
-	cannotBeNilFmt    = "%v cannot be nil"
-	validationErrFmt  = "validation failed: %v"
+	cannotBeNilFmt   = "%v cannot be nil"
+	validationErrFmt = "validation failed: %v"
+	forbiddenFmt     = "%v is not allowed to %v the course with UUID = %v"
 )
 
-// NotFoundError should be returned when a service can't find a courseMeta.
-type NotFoundError struct {
-	message string
-}
-
-func NewNotFoundErr(message string) *NotFoundError {
-	return &NotFoundError{message: message}
-}
-
-func NewCourseNotFoundErr(courseUuid uuid.UUID) *NotFoundError {
-	return NewNotFoundErr(fmt.Sprintf(courseNotFoundFmt, courseUuid))
-}
-
-// Error implements error. Returns the error message associated with the NilErr.
-func (e *NotFoundError) Error() string {
-	return e.message
-}
-
-// Is reports whether the given error is equal to the NilErr
-func (e *NotFoundError) Is(target error) bool { return target.Error() == e.message }
-
-// NilErr should be returned when an input is nil.
+// NilErr should be returned when an input is nil. It matches models.ErrInvalid.
 type NilErr struct {
-	message string
+	// Item is the name of the nil input.
+	Item string
 }
 
 func NewNilErr(item string) *NilErr {
-	return &NilErr{message: fmt.Sprintf(cannotBeNilFmt, item)}
+	return &NilErr{Item: item}
 }
 
 // Error implements error. Returns the error message associated with the NilErr.
 func (e *NilErr) Error() string {
-	return e.message
+	return fmt.Sprintf(cannotBeNilFmt, e.Item)
 }
 
-// Is reports whether the given error is equal to the NilErr
-func (e *NilErr) Is(target error) bool { return target.Error() == e.message }
+// Is reports whether the target is models.ErrInvalid, or a NilErr for the same input.
+func (e *NilErr) Is(target error) bool {
+	if target == models.ErrInvalid {
+		return true
+	}
+	t, ok := target.(*NilErr)
+	return ok && t.Item == e.Item
+}
 
-type courseConstraint string
+// Constraint identifies a rule that an operation would break, as reported by CourseConstraintErr.Constraint.
+type Constraint string
 
 const (
-	tutorMaxCourseMsg   courseConstraint = "a tutor can facilitate maximum 2 courses"
-	studentMaxCourseMsg courseConstraint = "a studentUUID can register to maximum 4 courses"
-	courseMaxStudentMsg courseConstraint = "maximum 20 students can register a courseMeta"
+	// TutorMaxCourse limits the number of courses a tutor can facilitate.
+	TutorMaxCourse Constraint = "tutor_max_course"
+	// StudentMaxCourse limits the number of courses a student can register to.
+	StudentMaxCourse Constraint = "student_max_course"
+	// CourseMaxStudent limits the number of students who can register to a course.
+	CourseMaxStudent Constraint = "course_max_student"
+	// TutorHasCourses prevents deleting a tutor who facilitates courses.
+	TutorHasCourses Constraint = "tutor_has_courses"
+	// StudentInCourses prevents deleting a student who is registered or waitlisted to courses.
+	StudentInCourses Constraint = "student_in_courses"
 )
 
+var constraintFmts = map[Constraint]string{
+	TutorMaxCourse:   "a tutor can facilitate maximum %d courses",
+	StudentMaxCourse: "a student can register to maximum %d courses",
+	CourseMaxStudent: "maximum %d students can register a course",
+	TutorHasCourses:  "the tutor facilitates %d courses",
+	StudentInCourses: "the student is registered or waitlisted to %d courses",
+}
+
+// CourseConstraintErr should be returned when an operation would violate a Constraint.
+// It matches models.ErrConstraint.
 type CourseConstraintErr struct {
-	message string
+	Constraint Constraint
+	// Limit is the maximum number of courses or students allowed by the Constraint,
+	// or the number of courses preventing a deletion.
+	Limit int
+	// CourseUUID, TutorUUID and StudentUUID identify the resources involved, unless they are uuid.Nil.
+	CourseUUID  uuid.UUID
+	TutorUUID   uuid.UUID
+	StudentUUID uuid.UUID
 }
 
-func NewCourseConstraintErr(check courseConstraint) *CourseConstraintErr {
-	return &CourseConstraintErr{message: fmt.Sprintf(validationErrFmt, check)}
+func NewCourseConstraintErr(constraint Constraint, limit int) *CourseConstraintErr {
+	return &CourseConstraintErr{Constraint: constraint, Limit: limit}
 }
 
+// Error implements error. Returns the error message associated with the CourseConstraintErr.
 func (e *CourseConstraintErr) Error() string {
-	return e.message
+	return fmt.Sprintf(validationErrFmt, fmt.Sprintf(constraintFmts[e.Constraint], e.Limit))
 }
 
+// Is reports whether the target is models.ErrConstraint, or a CourseConstraintErr for the same Constraint and Limit.
 func (e *CourseConstraintErr) Is(target error) bool {
-	return target.Error() == e.message
+	if target == models.ErrConstraint {
+		return true
+	}
+	t, ok := target.(*CourseConstraintErr)
+	return ok && t.Constraint == e.Constraint && t.Limit == e.Limit
+}
+
+// ForbiddenErr is returned when the principal of a context is not allowed to make an Action on a course.
+// It matches models.ErrForbidden.
+type ForbiddenErr struct {
+	// Subject is the subject of the principal, or an empty string if the call is not authenticated.
+	Subject    string
+	Action     Action
+	CourseUUID uuid.UUID
+}
+
+// NewForbiddenErr returns a ForbiddenErr for the given subject, action and course.
+func NewForbiddenErr(subject string, action Action, courseUUID uuid.UUID) *ForbiddenErr {
+	return &ForbiddenErr{Subject: subject, Action: action, CourseUUID: courseUUID}
+}
+
+// Error implements error.
+func (e *ForbiddenErr) Error() string {
+	subject := e.Subject
+	if subject == "" {
+		subject = "an unauthenticated caller"
+	}
+	return fmt.Sprintf(forbiddenFmt, subject, e.Action, e.CourseUUID)
+}
+
+// Is reports whether the target is models.ErrForbidden.
+func (e *ForbiddenErr) Is(target error) bool {
+	return target == models.ErrForbidden
 }
@@ -1,34 +1,39 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
 
-	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
 )
 
 func TestCourseConstraintErr_Error(t *testing.T) {
-	type fields struct {
-		message string
-	}
 	tests := []struct {
-		name   string
-		fields fields
-		want   string
+		name string
+		err  *CourseConstraintErr
+		want string
 	}{
 		{
-			name:   "valid error",
-			fields: fields{message: "violated some constraint"},
-			want:   "violated some constraint",
+			name: "tutor max course",
+			err:  NewCourseConstraintErr(TutorMaxCourse, 2),
+			want: "validation failed: a tutor can facilitate maximum 2 courses",
+		},
+		{
+			name: "student max course",
+			err:  &CourseConstraintErr{Constraint: StudentMaxCourse, Limit: 4, StudentUUID: fixedUuid},
+			want: "validation failed: a student can register to maximum 4 courses",
+		},
+		{
+			name: "tutor has courses",
+			err:  NewCourseConstraintErr(TutorHasCourses, 1),
+			want: "validation failed: the tutor facilitates 1 courses",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := &CourseConstraintErr{
-				message: tt.fields.message,
-			}
-			if got := e.Error(); got != tt.want {
+			if got := tt.err.Error(); got != tt.want {
 				t.Errorf("Error() = %v, want %v", got, tt.want)
 			}
 		})
@@ -36,37 +41,22 @@ func TestCourseConstraintErr_Error(t *testing.T) {
 }
 
 func TestCourseConstraintErr_Is(t *testing.T) {
-	type fields struct {
-		message string
-	}
-	type args struct {
-		target error
-	}
+	err := &CourseConstraintErr{Constraint: TutorMaxCourse, Limit: 2, TutorUUID: fixedUuid}
 	tests := []struct {
 		name   string
-		fields fields
-		args   args
+		target error
 		want   bool
 	}{
-		{
-			name:   "expected error",
-			fields: fields{message: fmt.Sprintf(validationErrFmt, string(tutorMaxCourseMsg))},
-			args:   args{target: NewCourseConstraintErr(tutorMaxCourseMsg)},
-			want:   true,
-		},
-		{
-			name:   "not expected error",
-			fields: fields{message: fmt.Sprintf(validationErrFmt, "random string")},
-			args:   args{target: NewCourseConstraintErr(tutorMaxCourseMsg)},
-			want:   false,
-		},
+		{name: "kind of the error", target: models.ErrConstraint, want: true},
+		{name: "same constraint", target: NewCourseConstraintErr(TutorMaxCourse, 2), want: true},
+		{name: "other limit", target: NewCourseConstraintErr(TutorMaxCourse, 3), want: false},
+		{name: "other constraint", target: NewCourseConstraintErr(StudentMaxCourse, 2), want: false},
+		{name: "other kind", target: models.ErrNotFound, want: false},
+		{name: "same message", target: errors.New(err.Error()), want: false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := &CourseConstraintErr{
-				message: tt.fields.message,
-			}
-			if got := e.Is(tt.args.target); got != tt.want {
+			if got := errors.Is(fmt.Errorf("wrapped: %w", err), tt.target); got != tt.want {
 				t.Errorf("Is() = %v, want %v", got, tt.want)
 			}
 		})
@@ -75,7 +65,8 @@ func TestCourseConstraintErr_Is(t *testing.T) {
 
 func TestNewCourseConstraintErr(t *testing.T) {
 	type args struct {
-		check courseConstraint
+		constraint Constraint
+		limit      int
 	}
 	tests := []struct {
 		name string
@@ -83,24 +74,19 @@ func TestNewCourseConstraintErr(t *testing.T) {
 		want *CourseConstraintErr
 	}{
 		{
-			name: "valid error tutor max courseMeta",
-			args: args{check: tutorMaxCourseMsg},
-			want: &CourseConstraintErr{message: fmt.Sprintf(validationErrFmt, tutorMaxCourseMsg)},
+			name: "tutor max course",
+			args: args{constraint: TutorMaxCourse, limit: 2},
+			want: &CourseConstraintErr{Constraint: TutorMaxCourse, Limit: 2},
 		},
 		{
-			name: "valid error studentUUID max courseMeta",
-			args: args{check: studentMaxCourseMsg},
-			want: &CourseConstraintErr{message: fmt.Sprintf(validationErrFmt, studentMaxCourseMsg)},
-		},
-		{
-			name: "valid error courseMeta max studentUUID",
-			args: args{check: courseMaxStudentMsg},
-			want: &CourseConstraintErr{message: fmt.Sprintf(validationErrFmt, courseMaxStudentMsg)},
+			name: "course max student",
+			args: args{constraint: CourseMaxStudent, limit: 20},
+			want: &CourseConstraintErr{Constraint: CourseMaxStudent, Limit: 20},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewCourseConstraintErr(tt.args.check); !reflect.DeepEqual(got, tt.want) {
+			if got := NewCourseConstraintErr(tt.args.constraint, tt.args.limit); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewCourseConstraintErr() = %v, want %v", got, tt.want)
 			}
 		})
@@ -108,202 +94,61 @@ func TestNewCourseConstraintErr(t *testing.T) {
 }
 
 func TestNewNilErr(t *testing.T) {
-	type args struct {
-		item string
-	}
-	tests := []struct {
-		name string
-		args args
-		want *NilErr
-	}{
-		{
-			name: "valid error",
-			args: args{item: "some item"},
-			want: NewNilErr("some item"),
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := NewNilErr(tt.args.item); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewNilErr() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func TestNewNotFoundErr(t *testing.T) {
-	type args struct {
-		message string
-	}
-	tests := []struct {
-		name string
-		args args
-		want *NotFoundError
-	}{
-		{
-			name: "valid error",
-			args: args{message: "some item"},
-			want: NewNotFoundErr("some item"),
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := NewNotFoundErr(tt.args.message); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewNotFoundErr() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func TestNewCourseNotFoundErr(t *testing.T) {
-	type args struct {
-		courseUuid uuid.UUID
-	}
-	tests := []struct {
-		name string
-		args args
-		want *NotFoundError
-	}{
-		{
-			name: "valid error",
-			args: args{courseUuid: uuid.MustParse("5d61cbc8-9ccd-4348-a623-d61dd7658dd7")},
-			want: NewCourseNotFoundErr(uuid.MustParse("5d61cbc8-9ccd-4348-a623-d61dd7658dd7")),
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := NewCourseNotFoundErr(tt.args.courseUuid); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewCourseNotFoundErr() = %v, want %v", got, tt.want)
-			}
-		})
+	if got, want := NewNilErr("repo"), (&NilErr{Item: "repo"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewNilErr() = %v, want %v", got, want)
 	}
 }
 
 func TestNilErr_Error(t *testing.T) {
-	type fields struct {
-		message string
-	}
-	tests := []struct {
-		name   string
-		fields fields
-		want   string
-	}{
-		{
-			name:   "valid error",
-			fields: fields{message: "this cannot be nil"},
-			want:   "this cannot be nil",
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			e := &NilErr{
-				message: tt.fields.message,
-			}
-			if got := e.Error(); got != tt.want {
-				t.Errorf("Error() = %v, want %v", got, tt.want)
-			}
-		})
+	if got, want := NewNilErr("repo").Error(), "repo cannot be nil"; got != want {
+		t.Errorf("Error() = %v, want %v", got, want)
 	}
 }
 
 func TestNilErr_Is(t *testing.T) {
-	type fields struct {
-		message string
-	}
-	type args struct {
-		target error
-	}
 	tests := []struct {
 		name   string
-		fields fields
-		args   args
+		target error
 		want   bool
 	}{
-		{
-			name:   "matching error",
-			fields: fields{message: "this cannot be nil"},
-			args:   args{target: NewNilErr("this")},
-			want:   true,
-		},
-		{
-			name:   "not-matching error",
-			fields: fields{message: "this cannot be nil"},
-			args:   args{target: NewNilErr("that")},
-			want:   false,
-		},
+		{name: "kind of the error", target: models.ErrInvalid, want: true},
+		{name: "same input", target: NewNilErr("repo"), want: true},
+		{name: "other input", target: NewNilErr("logger"), want: false},
+		{name: "same message", target: errors.New("repo cannot be nil"), want: false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := &NilErr{
-				message: tt.fields.message,
-			}
-			if got := e.Is(tt.args.target); got != tt.want {
+			if got := errors.Is(NewNilErr("repo"), tt.target); got != tt.want {
 				t.Errorf("Is() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestNotFoundError_Error(t *testing.T) {
-	type fields struct {
-		message string
-	}
-	tests := []struct {
-		name   string
-		fields fields
-		want   string
-	}{
-		{
-			name:   "valid error",
-			fields: fields{message: "this is not found"},
-			want:   "this is not found",
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			e := &NotFoundError{
-				message: tt.fields.message,
-			}
-			if got := e.Error(); got != tt.want {
-				t.Errorf("Error() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func TestNotFoundError_Is(t *testing.T) {
-	type fields struct {
-		message string
-	}
-	type args struct {
-		target error
-	}
+func TestForbiddenErr(t *testing.T) {
 	tests := []struct {
-		name   string
-		fields fields
-		args   args
-		want   bool
+		name string
+		err  *ForbiddenErr
+		want string
 	}{
 		{
-			name:   "matching error",
-			fields: fields{message: "this is not found"},
-			args:   args{target: NewNotFoundErr("this is not found")},
-			want:   true,
+			name: "authenticated",
+			err:  NewForbiddenErr("3fa85f64-5717-4562-b3fc-2c963f66afa6", ActionDeleteCourse, fixedUuid),
+			want: "3fa85f64-5717-4562-b3fc-2c963f66afa6 is not allowed to delete the course with UUID = " + fixedUuid.String(),
 		},
 		{
-			name:   "un-matching error",
-			fields: fields{message: "this is not found"},
-			args:   args{target: NewNotFoundErr("that is not found")},
-			want:   false,
+			name: "unauthenticated",
+			err:  NewForbiddenErr("", ActionRegisterStudent, fixedUuid),
+			want: "an unauthenticated caller is not allowed to register a student to the course with UUID = " + fixedUuid.String(),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			e := &NotFoundError{
-				message: tt.fields.message,
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
 			}
-			if got := e.Is(tt.args.target); got != tt.want {
-				t.Errorf("Is() = %v, want %v", got, tt.want)
+			if !errors.Is(fmt.Errorf("wrapped: %w", tt.err), models.ErrForbidden) {
+				t.Errorf("%v is not a models.ErrForbidden", tt.err)
 			}
 		})
 	}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+const (
+	// DefaultRelayInterval is the interval at which a Relay polls its Outbox, unless it is given another one.
+	DefaultRelayInterval = time.Second
+
+	// relayBatchSize is the maximum number of events a Relay reads from its Outbox at once.
+	relayBatchSize = 100
+)
+
+// Outbox is the interface that defines the methods for storing domain events until they are published,
+// so that they are stored in the same unit of work as the changes they tell about.
+// Every consumer of the Outbox, named by a stable string, publishes the events on its own:
+// an event is pending for a consumer until the consumer marks it as published.
+type Outbox interface {
+	// AddToOutbox stores the given events after the stored ones.
+	AddToOutbox(ctx context.Context, events ...models.Event) error
+	// PendingEvents returns up to limit stored events which the given consumer has not published,
+	// in the order they have been stored.
+	PendingEvents(ctx context.Context, consumer string, limit int) ([]models.Event, error)
+	// MarkPublished records that the given consumer has published the given events. Unknown events are ignored.
+	MarkPublished(ctx context.Context, consumer string, eventIDs ...uuid.UUID) error
+	// RemovePublished removes the events which have been published by every one of the given consumers.
+	RemovePublished(ctx context.Context, consumers ...string) error
+}
+
+// Publisher is the interface that defines the method for delivering domain events to downstream systems.
+// The publishers package provides implementations of it.
+type Publisher interface {
+	// Publish delivers the given event, and returns an error if it may not have been delivered.
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// Relay publishes the events of an Outbox with the Publishers of its consumers, in order and at least once.
+// Every consumer has its own position in the Outbox: an event is retried with a Publisher until it has been
+// published with it, without holding back nor repeating the deliveries of the other consumers.
+// An event is removed from the Outbox once every consumer has published it.
+// Only one Relay should publish the events of an Outbox, and it should always be given the same consumers,
+// as the events are kept until all of them have published them.
+type Relay struct {
+	outbox    Outbox
+	consumers map[string]Publisher
+	logger    *log.Logger
+	interval  time.Duration
+}
+
+// NewRelay returns a Relay publishing the events of the given outbox with the publishers of the given consumers,
+// which are keyed by their names, polling the outbox at the given interval, or at DefaultRelayInterval if it is 0.
+func NewRelay(outbox Outbox, consumers map[string]Publisher, logger *log.Logger, interval time.Duration) (*Relay, error) {
+	if outbox == nil {
+		return nil, NewNilErr("outbox")
+	}
+	if len(consumers) == 0 {
+		return nil, NewNilErr("consumers")
+	}
+	for _, publisher := range consumers {
+		if publisher == nil {
+			return nil, NewNilErr("publisher")
+		}
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	if interval <= 0 {
+		interval = DefaultRelayInterval
+	}
+	return &Relay{
+		outbox:    outbox,
+		consumers: consumers,
+		logger:    logger,
+		interval:  interval,
+	}, nil
+}
+
+// Run publishes the events of the outbox until the given context is done. It should be run in its own goroutine.
+// Failures are logged and the events are retried at the next interval.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Printf("unable to relay the events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes the pending events of every consumer until there are none, removes the events published
+// by all of them, and returns the number of published events.
+// A consumer stops at its first event which cannot be published, so that its events are published in order,
+// while the other consumers carry on. The error of the first failing consumer is returned.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	names := make([]string, 0, len(r.consumers))
+	for name := range r.consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	published := 0
+	var flushErr error
+	for _, name := range names {
+		n, err := r.flush(ctx, name, r.consumers[name])
+		published += n
+		if err != nil && flushErr == nil {
+			flushErr = fmt.Errorf("unable to relay the events to %v: %w", name, err)
+		}
+	}
+	if err := r.outbox.RemovePublished(ctx, names...); err != nil && flushErr == nil {
+		flushErr = fmt.Errorf("unable to remove the published events: %w", err)
+	}
+	return published, flushErr
+}
+
+// flush publishes the pending events of the given consumer with the given publisher until there are none,
+// and returns the number of published events.
+func (r *Relay) flush(ctx context.Context, consumer string, publisher Publisher) (int, error) {
+	published := 0
+	for {
+		events, err := r.outbox.PendingEvents(ctx, consumer, relayBatchSize)
+		if err != nil {
+			return published, fmt.Errorf("unable to retrieve the pending events: %w", err)
+		}
+		for _, event := range events {
+			if err = publisher.Publish(ctx, event); err != nil {
+				return published, fmt.Errorf("unable to publish the event %v: %w", event.ID, err)
+			}
+			if err = r.outbox.MarkPublished(ctx, consumer, event.ID); err != nil {
+				return published, fmt.Errorf("unable to mark the event %v as published: %w", event.ID, err)
+			}
+			published++
+		}
+		if len(events) < relayBatchSize {
+			return published, nil
+		}
+	}
+}
+
+// newEvent returns an event of the given type telling that the actor of the context has changed the given course,
+// whose state after the change is given, or nil if it has been deleted.
+func (c CourseManager) newEvent(ctx context.Context, eventType models.EventType, courseUUID, studentUUID uuid.UUID,
+	after *models.Course) models.Event {
+	event := models.Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		Time:       c.timestamp(),
+		Actor:      ActorFromContext(ctx),
+		CourseUUID: courseUUID,
+		Course:     after,
+	}
+	if after != nil {
+		event.Time = after.UpdatedAt
+	}
+	if studentUUID != uuid.Nil {
+		event.StudentUUID = &studentUUID
+	}
+	return event
+}
+
+// emit stores the given events into the outbox of the repo, within the unit of work of the context.
+func (c CourseManager) emit(ctx context.Context, events ...models.Event) error {
+	if err := c.repo.AddToOutbox(ctx, events...); err != nil {
+		return fmt.Errorf("unable to store the events: %w", err)
+	}
+	return nil
+}
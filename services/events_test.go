@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/tomasdembelli/course-manager/db-mock"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/publishers"
+)
+
+// publisherFunc is a Publisher calling itself.
+type publisherFunc func(ctx context.Context, event models.Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, event models.Event) error {
+	return f(ctx, event)
+}
+
+func TestNewRelay(t *testing.T) {
+	publisher := publishers.NewChannel(0)
+	tests := []struct {
+		name      string
+		outbox    Outbox
+		consumers map[string]Publisher
+		wantErr   error
+	}{
+		{name: "nil outbox", consumers: map[string]Publisher{"channel": publisher}, wantErr: NewNilErr("outbox")},
+		{name: "no consumers", outbox: NewMockRepo(nil), wantErr: NewNilErr("consumers")},
+		{name: "nil publisher", outbox: NewMockRepo(nil), consumers: map[string]Publisher{"channel": nil}, wantErr: NewNilErr("publisher")},
+		{name: "default interval", outbox: NewMockRepo(nil), consumers: map[string]Publisher{"channel": publisher}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relay, err := NewRelay(tt.outbox, tt.consumers, nil, 0)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Fatalf("NewRelay() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && relay.interval != DefaultRelayInterval {
+				t.Errorf("NewRelay() got the interval %v, want %v", relay.interval, DefaultRelayInterval)
+			}
+		})
+	}
+}
+
+func TestRelay_Flush(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewMockRepo(nil)
+	var events []models.Event
+	for i := 0; i < relayBatchSize+2; i++ {
+		events = append(events, models.Event{ID: uuid.New(), Type: models.CourseCreatedEvent, CourseUUID: uuid.New()})
+	}
+	if err := repo.AddToOutbox(ctx, events...); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	var published, others []models.Event
+	failing := events[1].ID
+	relay, err := NewRelay(repo, map[string]Publisher{
+		"failing": publisherFunc(func(ctx context.Context, event models.Event) error {
+			if event.ID == failing {
+				failing = uuid.Nil
+				return NewMockError()
+			}
+			published = append(published, event)
+			return nil
+		}),
+		"other": publisherFunc(func(ctx context.Context, event models.Event) error {
+			others = append(others, event)
+			return nil
+		}),
+	}, nil, 0)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	// The failing consumer does not hold back the other one.
+	n, err := relay.Flush(ctx)
+	if err == nil || n != len(events)+1 {
+		t.Errorf("Flush() got = %v, %v, want 1 event published before the failing one, and all the other events", n, err)
+	}
+	if pending, err := repo.PendingEvents(ctx, "other", 10); err != nil || len(pending) != 0 {
+		t.Errorf("PendingEvents() of the other consumer got = %v, %v, want no events", pending, err)
+	}
+	// Nor is the other consumer given the events again when the failing one retries them.
+	if n, err = relay.Flush(ctx); err != nil || n != len(events)-1 {
+		t.Errorf("Flush() got = %v, %v, want the %v remaining events published", n, err, len(events)-1)
+	}
+	if !reflect.DeepEqual(published, events) {
+		t.Errorf("Flush() published %v, want the events in order %v", published, events)
+	}
+	if !reflect.DeepEqual(others, events) {
+		t.Errorf("Flush() published %v to the other consumer, want the events once in order %v", others, events)
+	}
+	if pending, err := repo.PendingEvents(ctx, "another", 10); err != nil || len(pending) != 0 {
+		t.Errorf("PendingEvents() got = %v, %v, want the published events removed", pending, err)
+	}
+}
+
+func TestRelay_Run(t *testing.T) {
+	repo := NewMockRepo(nil)
+	channel := publishers.NewChannel(0)
+	relay, err := NewRelay(repo, map[string]Publisher{"channel": channel}, nil, time.Millisecond)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	event := models.Event{ID: uuid.New(), Type: models.CourseDeletedEvent, CourseUUID: uuid.New()}
+	if err = repo.AddToOutbox(context.TODO(), event); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	select {
+	case got := <-channel.Events():
+		if !reflect.DeepEqual(got, event) {
+			t.Errorf("Run() published %v, want %v", got, event)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Run() has not published the event")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Run() has not returned once its context is done")
+	}
+}
+
+func TestCourseManager_events(t *testing.T) {
+	ctx := ContextWithActor(context.TODO(), "alice")
+	tutorUUID, first, second := uuid.New(), uuid.New(), uuid.New()
+	repo := NewMockRepo(&Config{
+		TutorByUUID: map[uuid.UUID]models.Tutor{tutorUUID: {User: models.User{Uuid: tutorUUID}}},
+		StudentByUUID: map[uuid.UUID]models.Student{
+			first:  {User: models.User{Uuid: first}},
+			second: {User: models.User{Uuid: second}},
+		},
+	})
+	c, err := NewCourseManager(repo, nil, WithPolicy(Policy{TutorMaxCourse: 2, StudentMaxCourse: 4, CourseMaxStudent: 1}))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	course, err := c.Create(ctx, models.CourseMeta{Name: "Golang", TutorUUID: tutorUUID})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for _, studentUUID := range []uuid.UUID{first, second, second} {
+		if _, err = c.RegisterStudent(ctx, course.Uuid, studentUUID, 0); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	for _, studentUUID := range []uuid.UUID{first, first} {
+		if err = c.UnregisterStudent(ctx, course.Uuid, studentUUID, 0); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	if err = c.Delete(ctx, course.Uuid, 0); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	channel := publishers.NewChannel(10)
+	relay, err := NewRelay(repo, map[string]Publisher{"channel": channel}, nil, 0)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = relay.Flush(context.TODO()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	type summary struct {
+		eventType   models.EventType
+		studentUUID uuid.UUID
+		status      string
+		version     int
+	}
+	// Idempotent calls emit no events, and unregistering the first student promotes the second one.
+	want := []summary{
+		{eventType: models.CourseCreatedEvent, version: 1},
+		{eventType: models.StudentRegisteredEvent, studentUUID: first, status: "enrolled", version: 2},
+		{eventType: models.StudentRegisteredEvent, studentUUID: second, status: "waitlisted", version: 3},
+		{eventType: models.StudentUnregisteredEvent, studentUUID: first, version: 4},
+		{eventType: models.StudentPromotedEvent, studentUUID: second, version: 4},
+		{eventType: models.CourseDeletedEvent},
+	}
+	var got []summary
+	for len(channel.Events()) > 0 {
+		event := <-channel.Events()
+		if event.Actor != "alice" || event.CourseUUID != course.Uuid {
+			t.Errorf("got the event %v, want an event of alice on the course %v", event, course.Uuid)
+		}
+		s := summary{eventType: event.Type, status: event.Status}
+		if event.StudentUUID != nil {
+			s.studentUUID = *event.StudentUUID
+		}
+		if event.Course != nil {
+			s.version = event.Course.Version
+		}
+		got = append(got, s)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("published events got = %+v, want %+v", got, want)
+	}
+}
+
+func TestCourseManager_eventsRollback(t *testing.T) {
+	ctx := context.TODO()
+	course := generateUsersInCourse(0)
+	students := generateStudents(1)
+	repo := NewMockRepo(&Config{
+		CourseByUUID:  map[uuid.UUID]models.Course{course.Uuid: course},
+		TutorByUUID:   fixedTutors(),
+		StudentByUUID: students,
+		ErrOutbox:     NewMockError(),
+	})
+	c, err := NewCourseManager(repo, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for studentUUID := range students {
+		_, err = c.RegisterStudent(ctx, course.Uuid, studentUUID, 0)
+		if want := "unable to store the events: mock error"; err == nil || err.Error() != want {
+			t.Errorf("RegisterStudent() error = %v, want %v", err, want)
+		}
+	}
+	if err = c.Delete(ctx, course.Uuid, 0); err == nil {
+		t.Errorf("expected error deleting the course, but none raised")
+	}
+
+	got, err := c.Get(ctx, course.Uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("the course has been deleted without its event")
+	}
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(got.Students) != 0 || got.Version != course.Version {
+		t.Errorf("the course has been modified without its event: %v", got)
+	}
+}
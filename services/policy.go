@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// Policy defines the enrollment limits enforced by the CourseManager.
+type Policy struct {
+	// TutorMaxCourse is the maximum number of courses a tutor can facilitate.
+	TutorMaxCourse int
+	// StudentMaxCourse is the maximum number of courses a student can register to.
+	StudentMaxCourse int
+	// CourseMaxStudent is the maximum number of students who can register to a course,
+	// unless the course overrides it with models.CourseMeta.MaxStudents.
+	CourseMaxStudent int
+}
+
+// DefaultPolicy is the Policy used unless the CourseManager is given one with WithPolicy.
+var DefaultPolicy = Policy{
+	TutorMaxCourse:   2,
+	StudentMaxCourse: 4,
+	CourseMaxStudent: 20,
+}
+
+// Validate returns an error if any of the limits is not positive.
+func (p Policy) Validate() error {
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{name: "TutorMaxCourse", value: p.TutorMaxCourse},
+		{name: "StudentMaxCourse", value: p.StudentMaxCourse},
+		{name: "CourseMaxStudent", value: p.CourseMaxStudent},
+	}
+	for _, limit := range limits {
+		if limit.value <= 0 {
+			return fmt.Errorf("policy %v must be positive, got %d", limit.name, limit.value)
+		}
+	}
+	return nil
+}
+
+// CourseCapacity returns the maximum number of students who can register to the given course.
+func (p Policy) CourseCapacity(course models.CourseMeta) int {
+	if course.MaxStudents > 0 {
+		return course.MaxStudents
+	}
+	return p.CourseMaxStudent
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/tomasdembelli/course-manager/db-mock"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "default policy", policy: DefaultPolicy},
+		{name: "zero policy", policy: Policy{}, wantErr: true},
+		{name: "negative limit", policy: Policy{TutorMaxCourse: 1, StudentMaxCourse: -1, CourseMaxStudent: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_CourseCapacity(t *testing.T) {
+	tests := []struct {
+		name   string
+		course models.CourseMeta
+		want   int
+	}{
+		{name: "policy capacity", course: models.CourseMeta{}, want: DefaultPolicy.CourseMaxStudent},
+		{name: "course override", course: models.CourseMeta{MaxStudents: 5}, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultPolicy.CourseCapacity(tt.course); got != tt.want {
+				t.Errorf("CourseCapacity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCourseManager_WithPolicy(t *testing.T) {
+	if _, err := NewCourseManager(&MockRepo{}, nil, WithPolicy(Policy{})); err == nil {
+		t.Errorf("expected error with an invalid policy, but none raised")
+	}
+	policy := Policy{TutorMaxCourse: 1, StudentMaxCourse: 1, CourseMaxStudent: 1}
+	c, err := NewCourseManager(&MockRepo{}, nil, WithPolicy(policy))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if c.policy != policy {
+		t.Errorf("expected policy %v, got %v", policy, c.policy)
+	}
+}
+
+func TestCourseManager_enforcesPolicy(t *testing.T) {
+	policy := Policy{TutorMaxCourse: 1, StudentMaxCourse: 1, CourseMaxStudent: 1}
+	tests := []struct {
+		name       string
+		course     models.Course
+		student    models.Student
+		wantStatus RegistrationStatus
+	}{
+		{
+			name:       "course max student of the policy",
+			course:     generateUsersInCourse(1),
+			wantStatus: Waitlisted,
+		},
+		{
+			name: "course max student overridden by the course",
+			course: func() models.Course {
+				course := generateUsersInCourse(3)
+				course.MaxStudents = 3
+				return course
+			}(),
+			wantStatus: Waitlisted,
+		},
+		{
+			name: "course override above the policy",
+			course: func() models.Course {
+				course := generateUsersInCourse(1)
+				course.MaxStudents = 2
+				return course
+			}(),
+			wantStatus: Enrolled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCourseManager(NewMockRepo(&Config{
+				CourseByUUID:  map[uuid.UUID]models.Course{fixedUuid: tt.course},
+				StudentByUUID: fixedStudents(),
+			}), nil, WithPolicy(policy))
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			status, err := c.RegisterStudent(context.TODO(), fixedUuid, fixedUuid, 0)
+			if err != nil {
+				t.Fatalf("unexpected error RegisterStudent() error = %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("RegisterStudent() got = %v, want %v", status, tt.wantStatus)
+			}
+		})
+	}
+
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{fixedUuid: generateUsersInCourse(0)},
+		TutorByUUID:  fixedTutors(),
+	}), nil, WithPolicy(policy))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	_, err = c.Create(context.TODO(), models.CourseMeta{Name: "test course", TutorUUID: fixedUuid})
+	if !errors.Is(err, NewCourseConstraintErr(TutorMaxCourse, 1)) {
+		t.Errorf("Create() error = %v, want the tutor max course constraint", err)
+	}
+
+	registered := generateUsersInCourse(0)
+	registered.Uuid = uuid.New()
+	registered.Students[fixedUuid] = models.Enrollment{StudentUUID: fixedUuid}
+	c, err = NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{
+			fixedUuid:       generateUsersInCourse(0),
+			registered.Uuid: registered,
+		},
+		StudentByUUID: fixedStudents(),
+	}), nil, WithPolicy(policy))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	_, err = c.RegisterStudent(context.TODO(), fixedUuid, fixedUuid, 0)
+	var constraintErr *CourseConstraintErr
+	if !errors.Is(err, models.ErrConstraint) || !errors.As(err, &constraintErr) {
+		t.Fatalf("RegisterStudent() error = %v, want the student max course constraint", err)
+	}
+	want := CourseConstraintErr{Constraint: StudentMaxCourse, Limit: 1, CourseUUID: fixedUuid, StudentUUID: fixedUuid}
+	if *constraintErr != want {
+		t.Errorf("RegisterStudent() error = %+v, want %+v", *constraintErr, want)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Role grants permissions to a Principal.
+type Role string
+
+const (
+	// RoleAdmin is the role of the administrators of the service.
+	RoleAdmin Role = "admin"
+	// RoleTutor is the role of the tutors, whose Principal.Subject is their UUID.
+	RoleTutor Role = "tutor"
+	// RoleStudent is the role of the students, whose Principal.Subject is their UUID.
+	RoleStudent Role = "student"
+	// RoleRegistrar is the role of the callers managing the enrollments of any student, e.g. the LMS sync job.
+	RoleRegistrar Role = "registrar"
+)
+
+// Principal is the authenticated caller of the services.
+type Principal struct {
+	// Subject identifies the caller, e.g. the UUID of a tutor or a student.
+	Subject string
+	// Roles are the roles granted to the caller. Unknown roles grant nothing.
+	Roles []Role
+}
+
+// HasRole reports whether the principal has been granted the given role.
+func (p Principal) HasRole(role Role) bool {
+	for _, granted := range p.Roles {
+		if granted == role {
+			return true
+		}
+	}
+	return false
+}
+
+// is reports whether the subject of the principal is the given UUID, e.g. of a tutor or a student.
+func (p Principal) is(userUUID uuid.UUID) bool {
+	subject, err := uuid.Parse(p.Subject)
+	return err == nil && userUUID != uuid.Nil && subject == userUUID
+}
+
+// principalKey is the context key of the principal making the calls with a context.
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx telling that the calls made with it are made by the given principal,
+// e.g. the authenticated caller of a request. The subject of the principal is also the actor of the calls.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	ctx = ContextWithActor(ctx, principal.Subject)
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal making the calls with the given context,
+// and false if the calls are not authenticated.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is a token bucket holding up to Requests tokens, which are refilled at the rate of Requests per Period.
+// Every request takes a token, so a client can make a burst of Requests requests, and then Requests per Period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseRateLimit returns the RateLimit of the given "<requests>/<period>" string, e.g. "10/1m".
+// The period is a time.Duration, and defaults to 1 if it only has a unit, e.g. "10/s".
+func ParseRateLimit(raw string) (RateLimit, error) {
+	parts := strings.SplitN(raw, "/", 2)
+	if len(parts) != 2 {
+		return RateLimit{}, fmt.Errorf("rate limit %q must be <requests>/<period>, e.g. 10/1m", raw)
+	}
+	limit := RateLimit{}
+	var err error
+	if limit.Requests, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+		return RateLimit{}, fmt.Errorf("rate limit %q must have an integer number of requests", raw)
+	}
+	period := strings.TrimSpace(parts[1])
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	if limit.Period, err = time.ParseDuration(period); err != nil {
+		return RateLimit{}, fmt.Errorf("rate limit %q must have a period such as 1m or 10s", raw)
+	}
+	return limit, limit.Validate()
+}
+
+// String returns the "<requests>/<period>" form of the RateLimit, which ParseRateLimit parses.
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d/%v", l.Requests, l.Period)
+}
+
+// Validate returns an error if the number of requests or the period is not positive.
+func (l RateLimit) Validate() error {
+	if l.Requests <= 0 || l.Period <= 0 {
+		return fmt.Errorf("rate limit %v must have a positive number of requests and period", l)
+	}
+	return nil
+}
+
+// RateLimitDecision is the outcome of taking a token from a RateLimit bucket.
+type RateLimitDecision struct {
+	// Allowed tells whether a token has been taken, or the bucket was empty.
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is the delay until a token is available, if the request is not allowed.
+	RetryAfter time.Duration
+	// Reset is the delay until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitStore is the interface that defines the method for keeping the token buckets of the clients.
+// The ratelimit package provides implementations of it.
+type RateLimitStore interface {
+	// Take takes a token at the given time from the bucket of the given key, which is filled as configured
+	// by the given limit. A bucket which has never been taken from is full.
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitDecision, error)
+}
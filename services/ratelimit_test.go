@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    RateLimit
+		wantErr bool
+	}{
+		{raw: "10/1m", want: RateLimit{Requests: 10, Period: time.Minute}},
+		{raw: "10/s", want: RateLimit{Requests: 10, Period: time.Second}},
+		{raw: " 300 / 1h30m ", want: RateLimit{Requests: 300, Period: 90 * time.Minute}},
+		{raw: "10", wantErr: true},
+		{raw: "ten/1m", wantErr: true},
+		{raw: "10/minute", wantErr: true},
+		{raw: "0/1m", wantErr: true},
+		{raw: "10/-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseRateLimit(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRateLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want && !tt.wantErr {
+				t.Errorf("ParseRateLimit() got = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr {
+				if parsed, _ := ParseRateLimit(got.String()); parsed != got {
+					t.Errorf("ParseRateLimit(%q) got = %v, want %v", got.String(), parsed, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// StudentManager is the service for managing the students registering to the courses.
+type StudentManager struct {
+	repo   Repo
+	logger *log.Logger
+}
+
+// NewStudentManager initiates a new StudentManager service with the given repo.
+func NewStudentManager(repo Repo, logger *log.Logger) (StudentManager, error) {
+	if repo == nil {
+		return StudentManager{}, NewNilErr("repo")
+	}
+
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return StudentManager{
+		repo:   repo,
+		logger: logger,
+	}, nil
+}
+
+// Create creates a new student. A UUID is generated for the student unless it has one.
+// It returns a *models.ValidationErr if the student is invalid.
+func (s StudentManager) Create(ctx context.Context, student models.Student) (*models.Student, error) {
+	if student.Uuid == uuid.Nil {
+		student.Uuid = uuid.New()
+	}
+	if err := student.Validate(); err != nil {
+		return nil, err
+	}
+	err := s.repo.CreateStudent(ctx, student)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the student: %w", err)
+	}
+	return &student, nil
+}
+
+// Get returns the models.Student for the given student UUID.
+// It returns a *models.NotFoundError if the student does not exist.
+func (s StudentManager) Get(ctx context.Context, studentUUID uuid.UUID) (*models.Student, error) {
+	student, err := s.repo.StudentById(ctx, studentUUID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve student by UUID: %w", err)
+	}
+	return student, nil
+}
+
+// List returns all students in the repo.
+func (s StudentManager) List(ctx context.Context) ([]models.Student, error) {
+	students, err := s.repo.ListStudents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve students: %w", err)
+	}
+	return students, nil
+}
+
+// Update replaces the details of the given student, which is seen by every course they registered to.
+// It returns a *models.ValidationErr if the student is invalid, and a *models.NotFoundError if they do not exist.
+func (s StudentManager) Update(ctx context.Context, student models.Student) (*models.Student, error) {
+	if err := student.Validate(); err != nil {
+		return nil, err
+	}
+	err := s.repo.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := s.Get(ctx, student.Uuid); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateStudent(ctx, student); err != nil {
+			return fmt.Errorf("unable to update the student: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+// Delete deletes the student for the given studentUUID.
+// This is an idempotent operation.
+// It returns a *CourseConstraintErr if the student is still registered to, or waitlisted for, any course.
+func (s StudentManager) Delete(ctx context.Context, studentUUID uuid.UUID) error {
+	return s.repo.WithTx(ctx, func(ctx context.Context) error {
+		inCourses, err := s.repo.CountStudentCourses(ctx, studentUUID)
+		if err != nil {
+			return fmt.Errorf("unable to count the courses of the student: %w", err)
+		}
+		if inCourses > 0 {
+			return &CourseConstraintErr{Constraint: StudentInCourses, Limit: inCourses, StudentUUID: studentUUID}
+		}
+		err = s.repo.DeleteStudent(ctx, studentUUID)
+		if errors.Is(err, models.ErrConstraint) {
+			// The student has been registered to a course since they have been counted.
+			return &CourseConstraintErr{Constraint: StudentInCourses, Limit: 1, StudentUUID: studentUUID}
+		}
+		if err != nil {
+			return fmt.Errorf("unable to delete the student: %w", err)
+		}
+		return nil
+	})
+}
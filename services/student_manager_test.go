@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/tomasdembelli/course-manager/db-mock"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func TestStudentManager_CreateGetUpdate(t *testing.T) {
+	ctx := context.TODO()
+	sm, err := NewStudentManager(NewMockRepo(nil), nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	created, err := sm.Create(ctx, models.Student{User: models.User{Name: "Alice", Lastname: "Smith"}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if created.Uuid == uuid.Nil {
+		t.Errorf("Create() expected a UUID to be generated")
+	}
+	if _, err = sm.Create(ctx, *created); err == nil {
+		t.Errorf("expected error creating a student twice, but none raised")
+	}
+
+	created.Faculty = "Physics"
+	if _, err = sm.Update(ctx, *created); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err := sm.Get(ctx, created.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Faculty != "Physics" {
+		t.Errorf("Get() got = %v, want the updated student", got)
+	}
+
+	var notFoundErr *models.NotFoundError
+	if _, err = sm.Get(ctx, uuid.New()); !errors.As(err, &notFoundErr) {
+		t.Errorf("Get() error = %v, want a not found error", err)
+	}
+	if _, err = sm.Update(ctx, models.Student{User: models.User{Uuid: uuid.New(), Name: "Bob", Lastname: "Brown"}}); !errors.As(err, &notFoundErr) {
+		t.Errorf("Update() error = %v, want a not found error", err)
+	}
+}
+
+func TestStudentManager_Delete(t *testing.T) {
+	enrolled := generateUsersInCourse(0)
+	enrolled.Students[fixedUuid] = models.Enrollment{StudentUUID: fixedUuid}
+	waitlisted := generateUsersInCourse(0)
+	waitlisted.Waitlist = []uuid.UUID{fixedUuid}
+	repoErr := errors.New("repo failure")
+	tests := []struct {
+		name        string
+		courses     map[uuid.UUID]models.Course
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:    "student without courses",
+			courses: map[uuid.UUID]models.Course{fixedUuid: generateUsersInCourse(1)},
+		},
+		{
+			name:        "student registered to a course",
+			courses:     map[uuid.UUID]models.Course{fixedUuid: enrolled},
+			expectedErr: NewCourseConstraintErr(StudentInCourses, 1),
+		},
+		{
+			name:        "student on the waitlist of a course",
+			courses:     map[uuid.UUID]models.Course{fixedUuid: waitlisted},
+			expectedErr: NewCourseConstraintErr(StudentInCourses, 1),
+		},
+		{
+			name:        "repo failure",
+			courses:     map[uuid.UUID]models.Course{fixedUuid: generateUsersInCourse(1)},
+			repoErr:     repoErr,
+			expectedErr: repoErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm, err := NewStudentManager(NewMockRepo(&Config{
+				CourseByUUID:  tt.courses,
+				StudentByUUID: fixedStudents(),
+				ErrByStudent:  tt.repoErr,
+			}), nil)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			err = sm.Delete(context.TODO(), fixedUuid)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Delete() error = %v, expectedErr %v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err = sm.Get(context.TODO(), fixedUuid); err == nil {
+				t.Errorf("expected the student to be deleted")
+			}
+		})
+	}
+}
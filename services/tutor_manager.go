@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// TutorManager is the service for managing the tutors facilitating the courses.
+type TutorManager struct {
+	repo   Repo
+	logger *log.Logger
+}
+
+// NewTutorManager initiates a new TutorManager service with the given repo.
+func NewTutorManager(repo Repo, logger *log.Logger) (TutorManager, error) {
+	if repo == nil {
+		return TutorManager{}, NewNilErr("repo")
+	}
+
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return TutorManager{
+		repo:   repo,
+		logger: logger,
+	}, nil
+}
+
+// Create creates a new tutor. A UUID is generated for the tutor unless it has one.
+// It returns a *models.ValidationErr if the tutor is invalid.
+func (t TutorManager) Create(ctx context.Context, tutor models.Tutor) (*models.Tutor, error) {
+	if tutor.Uuid == uuid.Nil {
+		tutor.Uuid = uuid.New()
+	}
+	if err := tutor.Validate(); err != nil {
+		return nil, err
+	}
+	err := t.repo.CreateTutor(ctx, tutor)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the tutor: %w", err)
+	}
+	return &tutor, nil
+}
+
+// Get returns the models.Tutor for the given tutor UUID.
+// It returns a *models.NotFoundError if the tutor does not exist.
+func (t TutorManager) Get(ctx context.Context, tutorUUID uuid.UUID) (*models.Tutor, error) {
+	tutor, err := t.repo.TutorById(ctx, tutorUUID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve tutor by UUID: %w", err)
+	}
+	return tutor, nil
+}
+
+// List returns all tutors in the repo.
+func (t TutorManager) List(ctx context.Context) ([]models.Tutor, error) {
+	tutors, err := t.repo.ListTutors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve tutors: %w", err)
+	}
+	return tutors, nil
+}
+
+// Update replaces the details of the given tutor, which is seen by every course it facilitates.
+// It returns a *models.ValidationErr if the tutor is invalid, and a *models.NotFoundError if they do not exist.
+func (t TutorManager) Update(ctx context.Context, tutor models.Tutor) (*models.Tutor, error) {
+	if err := tutor.Validate(); err != nil {
+		return nil, err
+	}
+	err := t.repo.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := t.Get(ctx, tutor.Uuid); err != nil {
+			return err
+		}
+		if err := t.repo.UpdateTutor(ctx, tutor); err != nil {
+			return fmt.Errorf("unable to update the tutor: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tutor, nil
+}
+
+// Delete deletes the tutor for the given tutorUUID.
+// This is an idempotent operation. It returns a *CourseConstraintErr if the tutor still facilitates courses.
+func (t TutorManager) Delete(ctx context.Context, tutorUUID uuid.UUID) error {
+	return t.repo.WithTx(ctx, func(ctx context.Context) error {
+		courses, err := t.repo.ByTutor(ctx, tutorUUID)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve courses: %w", err)
+		}
+		if len(courses) > 0 {
+			return &CourseConstraintErr{Constraint: TutorHasCourses, Limit: len(courses), TutorUUID: tutorUUID}
+		}
+		if err = t.repo.DeleteTutor(ctx, tutorUUID); err != nil {
+			return fmt.Errorf("unable to delete the tutor: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/tomasdembelli/course-manager/db-mock"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func TestTutorManager_CreateGetUpdate(t *testing.T) {
+	ctx := context.TODO()
+	tm, err := NewTutorManager(NewMockRepo(nil), nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	created, err := tm.Create(ctx, models.Tutor{User: models.User{Name: "John", Lastname: "Stone"}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if created.Uuid == uuid.Nil {
+		t.Errorf("Create() expected a UUID to be generated")
+	}
+
+	created.Name = "Johnny"
+	if _, err = tm.Update(ctx, *created); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	got, err := tm.Get(ctx, created.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Name != "Johnny" {
+		t.Errorf("Get() got = %v, want the updated tutor", got)
+	}
+
+	var notFoundErr *models.NotFoundError
+	if _, err = tm.Get(ctx, uuid.New()); !errors.As(err, &notFoundErr) {
+		t.Errorf("Get() error = %v, want a not found error", err)
+	}
+	if _, err = tm.Update(ctx, models.Tutor{User: models.User{Uuid: uuid.New(), Name: "Jane", Lastname: "Doe"}}); !errors.As(err, &notFoundErr) {
+		t.Errorf("Update() error = %v, want a not found error", err)
+	}
+}
+
+func TestTutorManager_Delete(t *testing.T) {
+	tests := []struct {
+		name        string
+		courses     map[uuid.UUID]models.Course
+		expectedErr error
+	}{
+		{
+			name:    "tutor without courses",
+			courses: map[uuid.UUID]models.Course{},
+		},
+		{
+			name:        "tutor facilitating courses",
+			courses:     map[uuid.UUID]models.Course{fixedUuid: generateUsersInCourse(0)},
+			expectedErr: NewCourseConstraintErr(TutorHasCourses, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, err := NewTutorManager(NewMockRepo(&Config{
+				CourseByUUID: tt.courses,
+				TutorByUUID:  fixedTutors(),
+			}), nil)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			err = tm.Delete(context.TODO(), fixedUuid)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Delete() error = %v, expectedErr %v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if _, err = tm.Get(context.TODO(), fixedUuid); err == nil {
+				t.Errorf("expected the tutor to be deleted")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// RegistrationStatus tells whether a student registering to a course has been enrolled or waitlisted.
+type RegistrationStatus string
+
+const (
+	// Enrolled is the status of a student registered to a course.
+	Enrolled RegistrationStatus = "enrolled"
+	// Waitlisted is the status of a student queued on the waitlist of a full course.
+	Waitlisted RegistrationStatus = "waitlisted"
+)
+
+// WaitlistPosition returns the 1-based position of the given student on the waitlist of the given course.
+// It returns a *models.NotFoundError if the course is not found or the student is not on its waitlist.
+func (c CourseManager) WaitlistPosition(ctx context.Context, courseUUID, studentUUID uuid.UUID) (int, error) {
+	course, err := c.Get(ctx, courseUUID)
+	if err != nil {
+		return 0, err
+	}
+	position := waitlistPosition(course, studentUUID)
+	if position == 0 {
+		return 0, models.NewNotOnWaitlistErr(courseUUID, studentUUID)
+	}
+	return position, nil
+}
+
+// LeaveWaitlist removes the given student from the waitlist of the given course.
+// This is an idempotent operation. It returns a *models.NotFoundError if the course does not exist.
+// Unless expectedVersion is 0, a *models.VersionConflictErr is returned if the course is not at expectedVersion.
+func (c CourseManager) LeaveWaitlist(ctx context.Context, courseUUID, studentUUID uuid.UUID, expectedVersion int) error {
+	return c.withTx(ctx, func(ctx context.Context) error {
+		course, err := c.repo.ById(ctx, courseUUID)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve the course: %w", err)
+		}
+		if err = c.authorize(ctx, ActionLeaveWaitlist, course.CourseMeta, studentUUID); err != nil {
+			return err
+		}
+		if err = checkVersion(course, expectedVersion); err != nil {
+			return err
+		}
+		position := waitlistPosition(course, studentUUID)
+		if position == 0 {
+			return nil
+		}
+		before := snapshot(course)
+		waitlist := make([]uuid.UUID, 0, len(course.Waitlist)-1)
+		waitlist = append(waitlist, course.Waitlist[:position-1]...)
+		course.Waitlist = append(waitlist, course.Waitlist[position:]...)
+		c.touch(ctx, course)
+		err = c.repo.Update(ctx, *course)
+		if err != nil {
+			return fmt.Errorf("unable to update the course: %w", err)
+		}
+		after := updated(course)
+		if err = c.emit(ctx, c.newEvent(ctx, models.StudentLeftWaitlistEvent, courseUUID, studentUUID, after)); err != nil {
+			return err
+		}
+		return c.audit(ctx, models.StudentLeftWaitlist, courseUUID, studentUUID, before, after)
+	})
+}
+
+// promoteFromWaitlist enrolls waitlisted students to the given course, in order, until the course is full,
+// and returns the enrolled students. They are enrolled at the UpdatedAt of the course.
+// Students who have reached their maximum number of courses are skipped and keep their position.
+func (c CourseManager) promoteFromWaitlist(ctx context.Context, course *models.Course) ([]uuid.UUID, error) {
+	capacity := c.policy.CourseCapacity(course.CourseMeta)
+	var waitlist, promoted []uuid.UUID
+	for _, studentUUID := range course.Waitlist {
+		if len(course.Students) >= capacity {
+			waitlist = append(waitlist, studentUUID)
+			continue
+		}
+		coursesByStudent, err := c.repo.ByStudent(ctx, studentUUID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve courses: %w", err)
+		}
+		if len(coursesByStudent) >= c.policy.StudentMaxCourse {
+			waitlist = append(waitlist, studentUUID)
+			continue
+		}
+		course.Students[studentUUID] = models.Enrollment{StudentUUID: studentUUID, EnrolledAt: course.UpdatedAt}
+		promoted = append(promoted, studentUUID)
+	}
+	course.Waitlist = waitlist
+	return promoted, nil
+}
+
+// waitlistPosition returns the 1-based position of the given student on the waitlist of the given course,
+// or 0 if they are not on it.
+func waitlistPosition(course *models.Course, studentUUID uuid.UUID) int {
+	for i, waitlistedUUID := range course.Waitlist {
+		if waitlistedUUID == studentUUID {
+			return i + 1
+		}
+	}
+	return 0
+}
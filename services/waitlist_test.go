@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/tomasdembelli/course-manager/db-mock"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+func TestCourseManager_UnregisterStudent_promotesFromWaitlist(t *testing.T) {
+	policy := Policy{TutorMaxCourse: 2, StudentMaxCourse: 1, CourseMaxStudent: 1}
+	busy, free := uuid.New(), uuid.New()
+
+	course := generateUsersInCourse(1)
+	course.Waitlist = []uuid.UUID{busy, free}
+	var enrolledUUID uuid.UUID
+	for studentUUID := range course.Students {
+		enrolledUUID = studentUUID
+	}
+	otherCourse := generateUsersInCourse(0)
+	otherCourse.Uuid = uuid.New()
+	otherCourse.Students[busy] = models.Enrollment{StudentUUID: busy}
+
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{
+			course.Uuid:      course,
+			otherCourse.Uuid: otherCourse,
+		},
+	}), nil, WithPolicy(policy))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err = c.UnregisterStudent(context.TODO(), course.Uuid, enrolledUUID, 0); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	got, err := c.Get(context.TODO(), course.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, ok := got.Students[free]; !ok || len(got.Students) != 1 {
+		t.Errorf("expected only %v to be promoted, got %v", free, got.Students)
+	}
+	position, err := c.WaitlistPosition(context.TODO(), course.Uuid, busy)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if position != 1 {
+		t.Errorf("WaitlistPosition() got = %v, want %v", position, 1)
+	}
+}
+
+func TestCourseManager_RegisterStudent_waitlistLimit(t *testing.T) {
+	policy := Policy{TutorMaxCourse: 2, StudentMaxCourse: 1, CourseMaxStudent: 1}
+	studentUUID := uuid.New()
+
+	waitlisted := generateUsersInCourse(1)
+	waitlisted.Waitlist = []uuid.UUID{studentUUID}
+	full := generateUsersInCourse(1)
+	full.Uuid = uuid.New()
+
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{
+			waitlisted.Uuid: waitlisted,
+			full.Uuid:       full,
+		},
+		StudentByUUID: map[uuid.UUID]models.Student{studentUUID: {User: models.User{Uuid: studentUUID}}},
+	}), nil, WithPolicy(policy))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	// The waitlist the student is on counts against their maximum number of courses.
+	_, err = c.RegisterStudent(context.TODO(), full.Uuid, studentUUID, 0)
+	var constraintErr *CourseConstraintErr
+	if !errors.As(err, &constraintErr) || constraintErr.Constraint != StudentMaxCourse {
+		t.Fatalf("RegisterStudent() error = %v, want a %v constraint error", err, StudentMaxCourse)
+	}
+	got, err := c.Get(context.TODO(), full.Uuid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(got.Waitlist) != 0 {
+		t.Errorf("expected the student not to be waitlisted, got %v", got.Waitlist)
+	}
+}
+
+func TestCourseManager_UpdateMeta_promotesFromWaitlist(t *testing.T) {
+	policy := Policy{TutorMaxCourse: 2, StudentMaxCourse: 4, CourseMaxStudent: 1}
+	first, second := uuid.New(), uuid.New()
+
+	course := generateUsersInCourse(1)
+	course.Waitlist = []uuid.UUID{first, second}
+
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{course.Uuid: course},
+	}), nil, WithPolicy(policy))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	meta := course.CourseMeta
+	meta.MaxStudents = 2
+	got, err := c.UpdateMeta(context.TODO(), meta, 0)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, ok := got.Students[first]; !ok || len(got.Students) != 2 {
+		t.Errorf("expected %v to be promoted, got %v", first, got.Students)
+	}
+	if len(got.Waitlist) != 1 || got.Waitlist[0] != second {
+		t.Errorf("expected %v to stay on the waitlist, got %v", second, got.Waitlist)
+	}
+}
+
+func TestCourseManager_WaitlistPosition(t *testing.T) {
+	course := generateUsersInCourse(DefaultPolicy.CourseMaxStudent)
+	first, second := uuid.New(), uuid.New()
+	course.Waitlist = []uuid.UUID{first, second}
+	tests := []struct {
+		name         string
+		courseUUID   uuid.UUID
+		studentUUID  uuid.UUID
+		want         int
+		wantNotFound bool
+	}{
+		{name: "first in line", courseUUID: course.Uuid, studentUUID: first, want: 1},
+		{name: "second in line", courseUUID: course.Uuid, studentUUID: second, want: 2},
+		{name: "not on the waitlist", courseUUID: course.Uuid, studentUUID: uuid.New(), wantNotFound: true},
+		{name: "course not found", courseUUID: uuid.New(), studentUUID: first, wantNotFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCourseManager(NewMockRepo(&Config{
+				CourseByUUID: map[uuid.UUID]models.Course{course.Uuid: copyCourse(course)},
+			}), nil)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			got, err := c.WaitlistPosition(context.TODO(), tt.courseUUID, tt.studentUUID)
+			var notFoundErr *models.NotFoundError
+			if errors.As(err, &notFoundErr) != tt.wantNotFound {
+				t.Fatalf("WaitlistPosition() error = %v, wantNotFound %v", err, tt.wantNotFound)
+			}
+			if got != tt.want {
+				t.Errorf("WaitlistPosition() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCourseManager_LeaveWaitlist(t *testing.T) {
+	course := generateUsersInCourse(DefaultPolicy.CourseMaxStudent)
+	first, second := uuid.New(), uuid.New()
+	course.Waitlist = []uuid.UUID{first, second}
+
+	c, err := NewCourseManager(NewMockRepo(&Config{
+		CourseByUUID: map[uuid.UUID]models.Course{course.Uuid: copyCourse(course)},
+	}), nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = c.LeaveWaitlist(context.TODO(), course.Uuid, first, 0); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	position, err := c.WaitlistPosition(context.TODO(), course.Uuid, second)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if position != 1 {
+		t.Errorf("WaitlistPosition() got = %v, want %v", position, 1)
+	}
+	if _, err = c.WaitlistPosition(context.TODO(), course.Uuid, first); err == nil {
+		t.Errorf("expected %v to have left the waitlist", first)
+	}
+}
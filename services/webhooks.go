@@ -0,0 +1,499 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+const (
+	// DefaultWebhookInterval is the interval at which WebhookManager.Run attempts the due deliveries,
+	// unless it is given another one.
+	DefaultWebhookInterval = time.Second
+
+	// deliveryBatchSize is the maximum number of deliveries a WebhookManager attempts at once.
+	deliveryBatchSize = 100
+	// secretSize is the number of random bytes of the secrets of the webhooks.
+	secretSize = 32
+)
+
+// errWebhookDisabled is recorded as the failure of the deliveries to a disabled webhook.
+var errWebhookDisabled = errors.New("the webhook is disabled")
+
+// WebhookStore is the interface that defines the methods for persisting the webhooks and their deliveries.
+// The webhooks package provides implementations of it.
+type WebhookStore interface {
+	// CreateWebhook returns a *models.AlreadyExistsError if a webhook with the same ID exists.
+	CreateWebhook(ctx context.Context, webhook models.Webhook) error
+	// WebhookByID returns a *models.NotFoundError if there is no webhook for the given ID.
+	WebhookByID(ctx context.Context, webhookID uuid.UUID) (*models.Webhook, error)
+	// ListWebhooks returns the webhooks in the order they have been created.
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	// UpdateWebhook returns a *models.NotFoundError if the webhook does not exist.
+	UpdateWebhook(ctx context.Context, webhook models.Webhook) error
+	// DeleteWebhook deletes the webhook and its deliveries. It is a no-op if the webhook does not exist.
+	DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error
+	// AddDeliveries stores the given deliveries, ignoring those of an event which is already stored for the same webhook.
+	AddDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) error
+	// UpdateDelivery stores the given delivery. It is a no-op if the delivery does not exist.
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	// DueDeliveries returns up to limit pending deliveries whose NextAttemptAt is not after the given time,
+	// earliest first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// Deliveries returns the deliveries of the given webhook in the order they have been stored.
+	// Implementations may only keep the latest ones.
+	Deliveries(ctx context.Context, webhookID uuid.UUID) ([]models.WebhookDelivery, error)
+}
+
+// WebhookPolicy defines how the WebhookManager retries the deliveries and disables the failing webhooks.
+type WebhookPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a delivery.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt of a delivery. It doubles at every further attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between two attempts of a delivery.
+	MaxBackoff time.Duration
+	// MaxFailures is the number of consecutive failed attempts disabling a webhook.
+	MaxFailures int
+}
+
+// DefaultWebhookPolicy is the WebhookPolicy used unless the WebhookManager is given one with WithWebhookPolicy.
+// A delivery is attempted for about an hour before it fails.
+var DefaultWebhookPolicy = WebhookPolicy{
+	MaxAttempts:    8,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     30 * time.Minute,
+	MaxFailures:    20,
+}
+
+// Validate returns an error if any of the limits is not positive.
+func (p WebhookPolicy) Validate() error {
+	limits := []struct {
+		name  string
+		value int64
+	}{
+		{name: "MaxAttempts", value: int64(p.MaxAttempts)},
+		{name: "InitialBackoff", value: int64(p.InitialBackoff)},
+		{name: "MaxBackoff", value: int64(p.MaxBackoff)},
+		{name: "MaxFailures", value: int64(p.MaxFailures)},
+	}
+	for _, limit := range limits {
+		if limit.value <= 0 {
+			return fmt.Errorf("webhook policy %v must be positive, got %d", limit.name, limit.value)
+		}
+	}
+	return nil
+}
+
+// Backoff returns the delay after the given number of failed attempts of a delivery.
+func (p WebhookPolicy) Backoff(attempts int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// WebhookManager is the service for managing the webhooks subscribed to the domain events, and delivering them.
+// It is a Publisher storing a delivery of every event for each webhook subscribed to its type,
+// and Run attempts the deliveries, retrying the failed ones with an exponential backoff.
+type WebhookManager struct {
+	store        WebhookStore
+	newPublisher func(url, secret string) Publisher
+	logger       *log.Logger
+	policy       WebhookPolicy
+	now          func() time.Time
+	// privateTargets allows the webhooks to target the local host and private networks.
+	privateTargets bool
+	// mu serializes the modifications of the webhooks, which are read-modify-writes.
+	mu *sync.Mutex
+}
+
+// WebhookOption configures a WebhookManager.
+type WebhookOption func(w *WebhookManager)
+
+// WithWebhookPolicy makes the WebhookManager retry the deliveries with the given WebhookPolicy
+// instead of the DefaultWebhookPolicy.
+func WithWebhookPolicy(policy WebhookPolicy) WebhookOption {
+	return func(w *WebhookManager) {
+		w.policy = policy
+	}
+}
+
+// WithWebhookClock makes the WebhookManager schedule the deliveries with the given clock instead of time.Now.
+func WithWebhookClock(now func() time.Time) WebhookOption {
+	return func(w *WebhookManager) {
+		w.now = now
+	}
+}
+
+// WithPrivateWebhookTargets allows the webhooks to target the local host and the addresses which are not
+// a models.PublicIP, e.g. in development. They are rejected otherwise.
+func WithPrivateWebhookTargets() WebhookOption {
+	return func(w *WebhookManager) {
+		w.privateTargets = true
+	}
+}
+
+// NewWebhookManager initiates a new WebhookManager service with the given store and options.
+// newPublisher returns the Publisher POSTing the events to the given URL, signed with the given secret,
+// e.g. a publishers.Webhook.
+func NewWebhookManager(store WebhookStore, newPublisher func(url, secret string) Publisher, logger *log.Logger,
+	opts ...WebhookOption) (WebhookManager, error) {
+	if store == nil {
+		return WebhookManager{}, NewNilErr("store")
+	}
+	if newPublisher == nil {
+		return WebhookManager{}, NewNilErr("newPublisher")
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	webhookManager := WebhookManager{
+		store:        store,
+		newPublisher: newPublisher,
+		logger:       logger,
+		policy:       DefaultWebhookPolicy,
+		now:          time.Now,
+		mu:           &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(&webhookManager)
+	}
+	if err := webhookManager.policy.Validate(); err != nil {
+		return WebhookManager{}, err
+	}
+	return webhookManager, nil
+}
+
+// Create subscribes a new webhook to the events of the types of the given metadata on behalf of the principal
+// of the context, who owns it. The webhook is enabled, and it is returned with the secret of its signatures,
+// which cannot be retrieved anymore.
+// It returns a *models.ValidationErr if the metadata is invalid, or if its URL targets a private address
+// without WithPrivateWebhookTargets.
+func (w WebhookManager) Create(ctx context.Context, webhookMeta models.WebhookMeta) (*models.Webhook, error) {
+	if err := w.validate(webhookMeta); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("unable to generate the secret of the webhook: %w", err)
+	}
+	webhookMeta.Enabled = true
+	webhook := models.Webhook{
+		WebhookMeta: webhookMeta,
+		ID:          uuid.New(),
+		Owner:       ownerFromContext(ctx),
+		Secret:      hex.EncodeToString(secret),
+		CreatedAt:   w.now().UTC(),
+	}
+	if err := w.store.CreateWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("unable to create the webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// Get returns the webhook with the given ID, without its secret.
+// It returns a *models.NotFoundError if the webhook does not exist, or if the principal of the context
+// may not manage it.
+func (w WebhookManager) Get(ctx context.Context, webhookID uuid.UUID) (*models.Webhook, error) {
+	webhook, err := w.webhookByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// List returns the webhooks which the principal of the context may manage, without their secrets.
+func (w WebhookManager) List(ctx context.Context) ([]models.Webhook, error) {
+	stored, err := w.store.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the webhooks: %w", err)
+	}
+	webhooks := []models.Webhook{}
+	for _, webhook := range stored {
+		if mayManageWebhook(ctx, webhook) {
+			webhook.Secret = ""
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+// Update replaces the metadata of the webhook with the given ID. Enabling a disabled webhook resets its failures.
+// It returns a *models.ValidationErr if the metadata is invalid as on Create, and a *models.NotFoundError
+// if the webhook does not exist or if the principal of the context may not manage it.
+func (w WebhookManager) Update(ctx context.Context, webhookID uuid.UUID, webhookMeta models.WebhookMeta) (*models.Webhook, error) {
+	if err := w.validate(webhookMeta); err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	webhook, err := w.webhookByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case webhookMeta.Enabled && !webhook.Enabled:
+		webhook.Failures = 0
+		webhook.DisabledAt = nil
+	case !webhookMeta.Enabled && webhook.Enabled:
+		now := w.now().UTC()
+		webhook.DisabledAt = &now
+	}
+	webhook.WebhookMeta = webhookMeta
+	if err = w.store.UpdateWebhook(ctx, *webhook); err != nil {
+		return nil, fmt.Errorf("unable to update the webhook: %w", err)
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// Delete deletes the webhook with the given ID and its deliveries. It is a no-op if the webhook does not exist,
+// or if the principal of the context may not manage it.
+func (w WebhookManager) Delete(ctx context.Context, webhookID uuid.UUID) error {
+	_, err := w.webhookByID(ctx, webhookID)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = w.store.DeleteWebhook(ctx, webhookID); err != nil {
+		return fmt.Errorf("unable to delete the webhook: %w", err)
+	}
+	return nil
+}
+
+// Deliveries returns the deliveries of the webhook with the given ID, with their attempts, oldest first.
+// It returns a *models.NotFoundError if the webhook does not exist, or if the principal of the context
+// may not manage it.
+func (w WebhookManager) Deliveries(ctx context.Context, webhookID uuid.UUID) ([]models.WebhookDelivery, error) {
+	if _, err := w.webhookByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	deliveries, err := w.store.Deliveries(ctx, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the deliveries of the webhook: %w", err)
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// validate returns a *models.ValidationErr if the given metadata is invalid, or if its URL targets
+// a private address without WithPrivateWebhookTargets.
+func (w WebhookManager) validate(webhookMeta models.WebhookMeta) error {
+	if err := webhookMeta.Validate(); err != nil {
+		return err
+	}
+	if w.privateTargets {
+		return nil
+	}
+	return webhookMeta.ValidatePublic()
+}
+
+// webhookByID returns the webhook with the given ID, or a *models.NotFoundError if it does not exist
+// or if the principal of the context may not manage it, so that the webhooks of others are not disclosed.
+func (w WebhookManager) webhookByID(ctx context.Context, webhookID uuid.UUID) (*models.Webhook, error) {
+	webhook, err := w.store.WebhookByID(ctx, webhookID)
+	if err == nil && !mayManageWebhook(ctx, *webhook) {
+		err = models.NewWebhookNotFoundErr(webhookID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// mayManageWebhook reports whether the principal of the context is an admin or the owner of the given webhook.
+// Unauthenticated calls may only manage the webhooks created by unauthenticated calls, e.g. in development.
+func mayManageWebhook(ctx context.Context, webhook models.Webhook) bool {
+	principal, ok := PrincipalFromContext(ctx)
+	if ok && principal.HasRole(RoleAdmin) {
+		return true
+	}
+	return webhook.Owner == ownerFromContext(ctx)
+}
+
+// ownerFromContext returns the subject of the principal of the context, or an empty string if the calls
+// are not authenticated.
+func ownerFromContext(ctx context.Context) string {
+	principal, _ := PrincipalFromContext(ctx)
+	return principal.Subject
+}
+
+// Publish implements Publisher. It stores a pending delivery of the given event for each enabled webhook
+// subscribed to its type, which Run attempts.
+func (w WebhookManager) Publish(ctx context.Context, event models.Event) error {
+	webhooks, err := w.store.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list the webhooks: %w", err)
+	}
+	now := w.now().UTC()
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Status:        models.DeliveryPending,
+			Attempts:      []models.DeliveryAttempt{},
+			NextAttemptAt: &now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err = w.store.AddDeliveries(ctx, deliveries...); err != nil {
+		return fmt.Errorf("unable to store the deliveries: %w", err)
+	}
+	return nil
+}
+
+// Run attempts the due deliveries at the given interval, or at DefaultWebhookInterval if it is 0,
+// until the given context is done. It should be run in its own goroutine, and only once per WebhookStore.
+func (w WebhookManager) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWebhookInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Deliver(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Printf("unable to deliver the webhooks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver attempts the due deliveries, and returns the number of attempted deliveries.
+// Failed attempts are recorded into the deliveries, which are retried after a backoff until they reach
+// the MaxAttempts of the policy, and webhooks are disabled once they reach its MaxFailures.
+// It only returns an error if the store fails or the context is done.
+func (w WebhookManager) Deliver(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := w.store.DueDeliveries(ctx, w.now().UTC(), deliveryBatchSize)
+		if err != nil {
+			return attempted, fmt.Errorf("unable to retrieve the due deliveries: %w", err)
+		}
+		for _, delivery := range deliveries {
+			if err = w.attempt(ctx, delivery); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(deliveries) < deliveryBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// attempt POSTs the event of the given delivery to its webhook, and records the outcome.
+func (w WebhookManager) attempt(ctx context.Context, delivery models.WebhookDelivery) error {
+	webhook, err := w.store.WebhookByID(ctx, delivery.WebhookID)
+	if errors.Is(err, models.ErrNotFound) {
+		// The webhook has been deleted since the delivery has been retrieved, and so has the delivery.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the webhook: %w", err)
+	}
+
+	if !webhook.Enabled {
+		// Events are not delivered late to webhooks enabled again.
+		return w.finish(ctx, delivery, models.DeliveryFailed, errWebhookDisabled)
+	}
+	publishErr := w.newPublisher(webhook.URL, webhook.Secret).Publish(ctx, delivery.Event)
+	if err = ctx.Err(); err != nil {
+		// The attempt has been interrupted, so it is neither a success nor a failure of the webhook.
+		return err
+	}
+	status := models.DeliverySucceeded
+	if publishErr != nil {
+		status = models.DeliveryFailed
+		if len(delivery.Attempts)+1 < w.policy.MaxAttempts {
+			status = models.DeliveryPending
+		}
+	}
+	if err = w.finish(ctx, delivery, status, publishErr); err != nil {
+		return err
+	}
+	return w.recordOutcome(ctx, webhook.ID, publishErr == nil)
+}
+
+// finish records an attempt of the given delivery which has failed with the given error, if any,
+// and gives the delivery the given status, scheduling its next attempt if it is pending.
+func (w WebhookManager) finish(ctx context.Context, delivery models.WebhookDelivery, status models.DeliveryStatus,
+	attemptErr error) error {
+	now := w.now().UTC()
+	attempt := models.DeliveryAttempt{Time: now}
+	if attemptErr != nil {
+		attempt.Error = attemptErr.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	if status == models.DeliveryPending {
+		next := now.Add(w.policy.Backoff(len(delivery.Attempts)))
+		delivery.NextAttemptAt = &next
+	}
+	if err := w.store.UpdateDelivery(ctx, delivery); err != nil {
+		return fmt.Errorf("unable to update the delivery %v: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// recordOutcome resets the failures of the given webhook after a successful attempt, or counts a failed one,
+// disabling the webhook once it reaches the MaxFailures of the policy.
+func (w WebhookManager) recordOutcome(ctx context.Context, webhookID uuid.UUID, succeeded bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	webhook, err := w.store.WebhookByID(ctx, webhookID)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the webhook: %w", err)
+	}
+	if succeeded {
+		if webhook.Failures == 0 {
+			return nil
+		}
+		webhook.Failures = 0
+	} else {
+		webhook.Failures++
+		if webhook.Enabled && webhook.Failures >= w.policy.MaxFailures {
+			now := w.now().UTC()
+			webhook.Enabled = false
+			webhook.DisabledAt = &now
+			w.logger.Printf("the webhook %v has been disabled after %d consecutive failures", webhook.ID, webhook.Failures)
+		}
+	}
+	if err = w.store.UpdateWebhook(ctx, *webhook); err != nil && !errors.Is(err, models.ErrNotFound) {
+		return fmt.Errorf("unable to update the webhook: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/webhooks"
+)
+
+func TestNewWebhookManager(t *testing.T) {
+	newPublisher := func(url, secret string) Publisher { return nil }
+	tests := []struct {
+		name         string
+		store        WebhookStore
+		newPublisher func(url, secret string) Publisher
+		opts         []WebhookOption
+		wantErr      bool
+	}{
+		{name: "nil store", newPublisher: newPublisher, wantErr: true},
+		{name: "nil publisher", store: webhooks.NewMemoryStore(), wantErr: true},
+		{
+			name:         "invalid policy",
+			store:        webhooks.NewMemoryStore(),
+			newPublisher: newPublisher,
+			opts:         []WebhookOption{WithWebhookPolicy(WebhookPolicy{MaxAttempts: 1})},
+			wantErr:      true,
+		},
+		{name: "valid", store: webhooks.NewMemoryStore(), newPublisher: newPublisher},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWebhookManager(tt.store, tt.newPublisher, nil, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewWebhookManager() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookPolicy_Backoff(t *testing.T) {
+	policy := WebhookPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, MaxFailures: 1}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 100, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookManager_crud(t *testing.T) {
+	ctx := context.TODO()
+	w, err := NewWebhookManager(webhooks.NewMemoryStore(), func(url, secret string) Publisher { return nil }, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	_, err = w.Create(ctx, models.WebhookMeta{URL: "ftp://example.com", EventTypes: []models.EventType{"Unknown"}})
+	var validationErr *models.ValidationErr
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 2 {
+		t.Errorf("Create() of an invalid webhook error = %v, want a validation error of the url and eventTypes", err)
+	}
+
+	meta := models.WebhookMeta{URL: "https://example.com/hooks", EventTypes: []models.EventType{models.CourseCreatedEvent}}
+	created, err := w.Create(ctx, meta)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !created.Enabled || len(created.Secret) != 2*secretSize || created.ID == uuid.Nil {
+		t.Errorf("Create() got = %+v, want an enabled webhook with a secret", created)
+	}
+
+	want := *created
+	want.Secret = ""
+	got, err := w.Get(ctx, created.ID)
+	if err != nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("Get() got = %+v, %v, want %+v without its secret", got, err, want)
+	}
+	list, err := w.List(ctx)
+	if err != nil || !reflect.DeepEqual(list, []models.Webhook{want}) {
+		t.Errorf("List() got = %+v, %v, want %+v", list, err, []models.Webhook{want})
+	}
+
+	meta.EventTypes = append(meta.EventTypes, models.CourseDeletedEvent)
+	updated, err := w.Update(ctx, created.ID, meta)
+	if err != nil || updated.Enabled || updated.DisabledAt == nil || updated.Secret != "" ||
+		!reflect.DeepEqual(updated.EventTypes, meta.EventTypes) {
+		t.Errorf("Update() got = %+v, %v, want the webhook disabled with the new event types", updated, err)
+	}
+
+	if err = w.Delete(ctx, created.ID); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	wantErr := models.NewWebhookNotFoundErr(created.ID)
+	if _, err = w.Get(ctx, created.ID); !errors.Is(err, wantErr) {
+		t.Errorf("Get() of a deleted webhook error = %v, want %v", err, wantErr)
+	}
+	if _, err = w.Update(ctx, created.ID, meta); !errors.Is(err, wantErr) {
+		t.Errorf("Update() of a deleted webhook error = %v, want %v", err, wantErr)
+	}
+	if _, err = w.Deliveries(ctx, created.ID); !errors.Is(err, wantErr) {
+		t.Errorf("Deliveries() of a deleted webhook error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestWebhookManager_privateTargets(t *testing.T) {
+	ctx := context.TODO()
+	newPublisher := func(url, secret string) Publisher { return nil }
+	meta := models.WebhookMeta{URL: "http://169.254.169.254/latest", EventTypes: []models.EventType{models.CourseCreatedEvent}}
+	w, err := NewWebhookManager(webhooks.NewMemoryStore(), newPublisher, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = w.Create(ctx, meta); !errors.Is(err, models.ErrInvalid) {
+		t.Errorf("Create() of a private webhook error = %v, want %v", err, models.ErrInvalid)
+	}
+	public := meta
+	public.URL = "https://example.com/hooks"
+	created, err := w.Create(ctx, public)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = w.Update(ctx, created.ID, meta); !errors.Is(err, models.ErrInvalid) {
+		t.Errorf("Update() to a private URL error = %v, want %v", err, models.ErrInvalid)
+	}
+
+	w, err = NewWebhookManager(webhooks.NewMemoryStore(), newPublisher, nil, WithPrivateWebhookTargets())
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = w.Create(ctx, meta); err != nil {
+		t.Errorf("Create() of a private webhook with WithPrivateWebhookTargets() error = %v", err)
+	}
+}
+
+func TestWebhookManager_owner(t *testing.T) {
+	w, err := NewWebhookManager(webhooks.NewMemoryStore(), func(url, secret string) Publisher { return nil }, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	alice := ContextWithPrincipal(context.TODO(), Principal{Subject: "alice", Roles: []Role{RoleTutor}})
+	bob := ContextWithPrincipal(context.TODO(), Principal{Subject: "bob", Roles: []Role{RoleTutor}})
+	admin := ContextWithPrincipal(context.TODO(), Principal{Subject: "admin", Roles: []Role{RoleAdmin}})
+	meta := models.WebhookMeta{URL: "https://example.com/hooks", EventTypes: []models.EventType{models.CourseCreatedEvent}}
+	created, err := w.Create(alice, meta)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if created.Owner != "alice" {
+		t.Errorf("Create() got the owner %q, want %q", created.Owner, "alice")
+	}
+
+	// The webhooks of others are not disclosed.
+	wantErr := models.NewWebhookNotFoundErr(created.ID)
+	for _, ctx := range []context.Context{bob, context.TODO()} {
+		if list, err := w.List(ctx); err != nil || len(list) != 0 {
+			t.Errorf("List() by another principal got = %+v, %v, want no webhooks", list, err)
+		}
+		if _, err = w.Get(ctx, created.ID); !errors.Is(err, wantErr) {
+			t.Errorf("Get() by another principal error = %v, want %v", err, wantErr)
+		}
+		if _, err = w.Update(ctx, created.ID, meta); !errors.Is(err, wantErr) {
+			t.Errorf("Update() by another principal error = %v, want %v", err, wantErr)
+		}
+		if _, err = w.Deliveries(ctx, created.ID); !errors.Is(err, wantErr) {
+			t.Errorf("Deliveries() by another principal error = %v, want %v", err, wantErr)
+		}
+		if err = w.Delete(ctx, created.ID); err != nil {
+			t.Errorf("Delete() by another principal error = %v, want a no-op", err)
+		}
+	}
+
+	for _, ctx := range []context.Context{alice, admin} {
+		if list, err := w.List(ctx); err != nil || len(list) != 1 || list[0].ID != created.ID {
+			t.Errorf("List() by the owner or an admin got = %+v, %v, want the webhook", list, err)
+		}
+		if _, err = w.Get(ctx, created.ID); err != nil {
+			t.Errorf("Get() by the owner or an admin error = %v", err)
+		}
+	}
+	if err = w.Delete(admin, created.ID); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err = w.Get(alice, created.ID); !errors.Is(err, wantErr) {
+		t.Errorf("Get() of a webhook deleted by an admin error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestWebhookManager_Deliver(t *testing.T) {
+	ctx := context.TODO()
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	type call struct {
+		url, secret string
+		eventID     uuid.UUID
+	}
+	var calls []call
+	var failure error
+	w, err := NewWebhookManager(webhooks.NewMemoryStore(), func(url, secret string) Publisher {
+		return publisherFunc(func(ctx context.Context, event models.Event) error {
+			calls = append(calls, call{url: url, secret: secret, eventID: event.ID})
+			return failure
+		})
+	}, nil,
+		WithWebhookPolicy(WebhookPolicy{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Hour, MaxFailures: 4}),
+		WithWebhookClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	meta := models.WebhookMeta{URL: "https://example.com/hooks", EventTypes: []models.EventType{models.StudentRegisteredEvent}}
+	webhook, err := w.Create(ctx, meta)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	other, err := w.Create(ctx, models.WebhookMeta{URL: "https://example.com/other", EventTypes: []models.EventType{models.CourseCreatedEvent}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	publish := func() models.Event {
+		event := models.Event{ID: uuid.New(), Type: models.StudentRegisteredEvent, CourseUUID: uuid.New()}
+		if err := w.Publish(ctx, event); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		return event
+	}
+	deliver := func(want int) {
+		t.Helper()
+		if got, err := w.Deliver(ctx); err != nil || got != want {
+			t.Fatalf("Deliver() got = %v, %v, want %v attempted deliveries", got, err, want)
+		}
+	}
+	check := func(wantFailures int, wantEnabled bool, wantStatuses ...models.DeliveryStatus) []models.WebhookDelivery {
+		t.Helper()
+		got, err := w.Get(ctx, webhook.ID)
+		if err != nil || got.Failures != wantFailures || got.Enabled != wantEnabled {
+			t.Errorf("Get() got = %+v, %v, want %d failures and enabled = %v", got, err, wantFailures, wantEnabled)
+		}
+		deliveries, err := w.Deliveries(ctx, webhook.ID)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		var statuses []models.DeliveryStatus
+		for _, delivery := range deliveries {
+			statuses = append(statuses, delivery.Status)
+		}
+		if !reflect.DeepEqual(statuses, wantStatuses) {
+			t.Errorf("Deliveries() got the statuses %v, want %v", statuses, wantStatuses)
+		}
+		return deliveries
+	}
+
+	// A failing delivery is retried with an exponential backoff until its MaxAttempts.
+	failure = errors.New("mock error")
+	publish()
+	deliver(1)
+	deliveries := check(1, true, models.DeliveryPending)
+	if want := now.Add(time.Minute); !deliveries[0].NextAttemptAt.Equal(want) {
+		t.Errorf("got the next attempt at %v, want %v", deliveries[0].NextAttemptAt, want)
+	}
+	deliver(0)
+	now = now.Add(time.Minute)
+	deliver(1)
+	deliveries = check(2, true, models.DeliveryPending)
+	if want := now.Add(2 * time.Minute); !deliveries[0].NextAttemptAt.Equal(want) {
+		t.Errorf("got the next attempt at %v, want %v", deliveries[0].NextAttemptAt, want)
+	}
+	now = now.Add(2 * time.Minute)
+	deliver(1)
+	deliveries = check(3, true, models.DeliveryFailed)
+	if len(deliveries[0].Attempts) != 3 || deliveries[0].Attempts[2].Error != "mock error" || deliveries[0].NextAttemptAt != nil {
+		t.Errorf("got the delivery %+v, want 3 failed attempts", deliveries[0])
+	}
+
+	// The webhook is disabled after MaxFailures consecutive failures, and its pending deliveries fail.
+	publish()
+	deliver(1)
+	check(4, false, models.DeliveryFailed, models.DeliveryPending)
+	now = now.Add(time.Minute)
+	calls = nil
+	deliver(1)
+	deliveries = check(4, false, models.DeliveryFailed, models.DeliveryFailed)
+	if len(calls) != 0 || deliveries[1].Attempts[1].Error != errWebhookDisabled.Error() {
+		t.Errorf("got the calls %v and the delivery %+v, want no calls to a disabled webhook", calls, deliveries[1])
+	}
+	publish()
+	deliver(0)
+
+	// Enabling the webhook again resets its failures, and a successful delivery is signed with its secret.
+	meta.Enabled = true
+	if _, err = w.Update(ctx, webhook.ID, meta); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	failure = nil
+	last := publish()
+	if err = w.Publish(ctx, last); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	deliver(1)
+	check(0, true, models.DeliveryFailed, models.DeliveryFailed, models.DeliverySucceeded)
+	if want := []call{{url: webhook.URL, secret: webhook.Secret, eventID: last.ID}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got the calls %v, want %v", calls, want)
+	}
+	if deliveries, err = w.Deliveries(ctx, other.ID); err != nil || len(deliveries) != 0 {
+		t.Errorf("Deliveries() of the other webhook got = %v, %v, want none", deliveries, err)
+	}
+}
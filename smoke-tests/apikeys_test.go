@@ -0,0 +1,63 @@
+package smoke_tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeys(t *testing.T) {
+	rp := RequestParams{
+		BaseUrl: "http://localhost:8000/v1",
+	}
+	err := rp.Do()
+	if err != nil {
+		t.Skip("course manager service is not running, skipping the smoke tests")
+	}
+
+	rp.Path = "/apikeys"
+	rp.Method = http.MethodPost
+	rp.Payload = map[string]interface{}{
+		"apiKey": map[string]interface{}{"name": "smoke", "scopes": []string{"read-only"}},
+	}
+	if err = rp.Do(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if rp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %v, got %v: %v", http.StatusCreated, rp.StatusCode, rp.ResponseBody)
+	}
+	created := rp.ResponseBody.(map[string]interface{})
+	apiKeyID, key, prefix := created["id"].(string), created["key"].(string), created["prefix"].(string)
+	if !strings.HasPrefix(key, prefix) {
+		t.Errorf("expected the key %q to begin with its prefix %q", key, prefix)
+	}
+
+	listed := func() map[string]interface{} {
+		t.Helper()
+		rp.Path = "/apikeys"
+		rp.Method = http.MethodGet
+		rp.Payload = map[string]interface{}{}
+		if err := rp.Do(); err != nil || rp.StatusCode != http.StatusOK {
+			t.Fatalf("unable to list the API keys: %v, %v", err, rp.ResponseBody)
+		}
+		for _, item := range rp.ResponseBody.([]interface{}) {
+			if apiKey := item.(map[string]interface{}); apiKey["id"] == apiKeyID {
+				return apiKey
+			}
+		}
+		t.Fatalf("expected the API key %v to be listed, got %v", apiKeyID, rp.ResponseBody)
+		return nil
+	}
+	if apiKey := listed(); apiKey["key"] != nil || apiKey["revokedAt"] != nil {
+		t.Errorf("expected the API key to be listed without its plaintext nor revocation, got %v", apiKey)
+	}
+
+	rp.Path = "/apikeys/" + apiKeyID
+	rp.Method = http.MethodDelete
+	if err = rp.Do(); err != nil || rp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unable to revoke the API key: %v, %v", err, rp.StatusCode)
+	}
+	if apiKey := listed(); apiKey["revokedAt"] == nil {
+		t.Errorf("expected the API key to be revoked, got %v", apiKey)
+	}
+}
@@ -15,16 +15,46 @@ func TestCourseManager_HappyPath(t *testing.T) {
 		t.Skip("course manager service is not running, skipping the smoke tests")
 	}
 
+	rp.Path = "/tutors"
+	rp.Method = http.MethodPost
+	rp.Payload = map[string]interface{}{
+		"tutor": map[string]string{
+			"name":       "John",
+			"lastname":   "Stone",
+			"faculty":    "Computer Science",
+			"lecturerOf": "Golang",
+		},
+	}
+	err = rp.Do()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if rp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %v, got %v: %v", http.StatusCreated, rp.StatusCode, rp.ResponseBody)
+	}
+	tutorUUID := rp.ResponseBody.(map[string]interface{})["uuid"].(string)
+
+	rp.Path = "/students"
+	rp.Payload = map[string]interface{}{
+		"student": map[string]string{
+			"name":     "Alice J",
+			"lastname": "Smith",
+			"faculty":  "Computer Science",
+		},
+	}
+	err = rp.Do()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if rp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %v, got %v: %v", http.StatusCreated, rp.StatusCode, rp.ResponseBody)
+	}
+	studentUUID := rp.ResponseBody.(map[string]interface{})["uuid"].(string)
+
 	rp.Payload = map[string]interface{}{
 		"course": map[string]interface{}{
-			"name": "Microservices with Go",
-			"tutor": map[string]string{
-				"name":       "John",
-				"lastname":   "Stone",
-				"uuid":       "3fa85f64-5717-4562-b3fc-2c963f66afa6",
-				"faculty":    "Computer Science",
-				"lecturerOf": "Golang",
-			},
+			"name":      "Microservices with Go",
+			"tutorUUID": tutorUUID,
 		},
 	}
 
@@ -51,8 +81,12 @@ func TestCourseManager_HappyPath(t *testing.T) {
 		t.Errorf("expected %v, got %v", http.StatusOK, rp.StatusCode)
 		t.Log(rp.ResponseBody)
 	}
+	courseETag := rp.ResponseHeader.Get("ETag")
+	if courseETag == "" {
+		t.Errorf("expected an ETag header")
+	}
 
-	rp.Path = "/listCourses"
+	rp.Path = "/listCourses?sort=-createdAt&tutorUUID=" + tutorUUID
 	rp.Method = http.MethodGet
 	err = rp.Do()
 	if err != nil {
@@ -63,7 +97,7 @@ func TestCourseManager_HappyPath(t *testing.T) {
 		t.Log(rp.ResponseBody)
 	}
 	var exists bool
-	for _, u := range rp.ResponseBody.([]interface{}) {
+	for _, u := range rp.ResponseBody.(map[string]interface{})["courses"].([]interface{}) {
 		if u.(map[string]interface{})["uuid"].(string) == courseUUID {
 			exists = true
 		}
@@ -72,15 +106,29 @@ func TestCourseManager_HappyPath(t *testing.T) {
 		t.Errorf("course list does not contain expected course %v", courseUUID)
 	}
 
+	rp.Path = "/updateCourse/" + courseUUID
+	rp.Method = http.MethodPatch
+	rp.Header = map[string]string{"If-Match": courseETag, "Content-Type": "application/merge-patch+json"}
+	rp.Payload = map[string]interface{}{
+		"name": "Advanced Microservices with Go",
+	}
+	err = rp.Do()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if rp.StatusCode != http.StatusOK {
+		t.Errorf("expected %v, got %v", http.StatusOK, rp.StatusCode)
+		t.Log(rp.ResponseBody)
+	} else if name := rp.ResponseBody.(map[string]interface{})["name"]; name != "Advanced Microservices with Go" {
+		t.Errorf("expected the course to be renamed, got %v", name)
+	}
+	courseETag = rp.ResponseHeader.Get("ETag")
+
 	rp.Path = "/registerStudent/" + courseUUID
 	rp.Method = http.MethodPut
+	rp.Header = map[string]string{"If-Match": courseETag}
 	rp.Payload = map[string]interface{}{
-		"student": map[string]string{
-			"name":     "Alice J",
-			"lastname": "Smith",
-			"uuid":     "3fa85f64-5717-4562-b3fc-2c963f66afa7",
-			"faculty":  "Computer Science",
-		},
+		"studentUUID": studentUUID,
 	}
 	err = rp.Do()
 	if err != nil {
@@ -91,10 +139,32 @@ func TestCourseManager_HappyPath(t *testing.T) {
 		t.Log(rp.ResponseBody)
 	}
 
+	rp.Path = "/registerStudent/" + courseUUID
+	rp.Method = http.MethodPut
+	err = rp.Do()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if rp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected %v with a stale ETag, got %v", http.StatusPreconditionFailed, rp.StatusCode)
+		t.Log(rp.ResponseBody)
+	}
+
+	rp.Path = "/getCourse/" + courseUUID
+	rp.Method = http.MethodGet
+	rp.Header = nil
+	rp.Payload = map[string]interface{}{}
+	err = rp.Do()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	courseETag = rp.ResponseHeader.Get("ETag")
+
 	rp.Path = "/unregisterStudent/" + courseUUID
 	rp.Method = http.MethodPut
+	rp.Header = map[string]string{"If-Match": courseETag}
 	rp.Payload = map[string]interface{}{
-		"studentUUID": "3fa85f64-5717-4562-b3fc-2c963f66afa7",
+		"studentUUID": studentUUID,
 	}
 	err = rp.Do()
 	if err != nil {
@@ -107,13 +177,48 @@ func TestCourseManager_HappyPath(t *testing.T) {
 
 	rp.Path = "/deleteCourse/" + courseUUID
 	rp.Method = http.MethodDelete
+	rp.Header = map[string]string{"If-Match": courseETag}
 	rp.Payload = map[string]interface{}{}
 	err = rp.Do()
 	if err != nil {
 		t.Errorf("unexpected error %v", err)
 	}
+	if rp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected %v with a stale ETag, got %v", http.StatusPreconditionFailed, rp.StatusCode)
+		t.Log(rp.ResponseBody)
+	}
+
+	rp.Path = "/getCourse/" + courseUUID
+	rp.Method = http.MethodGet
+	rp.Header = nil
+	err = rp.Do()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	courseETag = rp.ResponseHeader.Get("ETag")
+
+	rp.Path = "/deleteCourse/" + courseUUID
+	rp.Method = http.MethodDelete
+	rp.Header = map[string]string{"If-Match": courseETag}
+	err = rp.Do()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
 	if rp.StatusCode != http.StatusNoContent {
 		t.Errorf("expected %v, got %v", http.StatusNoContent, rp.StatusCode)
 		t.Log(rp.ResponseBody)
 	}
+	rp.Header = nil
+
+	for _, path := range []string{"/students/" + studentUUID, "/tutors/" + tutorUUID} {
+		rp.Path = path
+		err = rp.Do()
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if rp.StatusCode != http.StatusNoContent {
+			t.Errorf("expected %v deleting %v, got %v", http.StatusNoContent, path, rp.StatusCode)
+			t.Log(rp.ResponseBody)
+		}
+	}
 }
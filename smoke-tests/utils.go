@@ -10,14 +10,15 @@ import (
 )
 
 type RequestParams struct {
-	BaseUrl      string
-	Path         string
-	Header       map[string]string
-	Method       string
-	StatusCode   int
-	ResponseBody interface{}
-	Payload      map[string]interface{}
-	Params       map[string]string
+	BaseUrl        string
+	Path           string
+	Header         map[string]string
+	Method         string
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   interface{}
+	Payload        map[string]interface{}
+	Params         map[string]string
 }
 
 func (r *RequestParams) Do() error {
@@ -49,6 +50,7 @@ func (r *RequestParams) Do() error {
 		return fmt.Errorf("failed to DO a %v request to %v", request.Method, request.RequestURI)
 	}
 	r.StatusCode = response.StatusCode
+	r.ResponseHeader = response.Header
 	defer response.Body.Close()
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
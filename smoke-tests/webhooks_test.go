@@ -0,0 +1,116 @@
+package smoke_tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomasdembelli/course-manager/publishers"
+)
+
+func TestWebhooks(t *testing.T) {
+	rp := RequestParams{
+		BaseUrl: "http://localhost:8000/v1",
+	}
+	err := rp.Do()
+	if err != nil {
+		t.Skip("course manager service is not running, skipping the smoke tests")
+	}
+
+	type delivery struct {
+		eventType, signature, timestamp string
+		body                            []byte
+	}
+	deliveries := make(chan delivery, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{eventType: r.Header.Get("X-Event-Type"), signature: r.Header.Get(publishers.HeaderSignature),
+			timestamp: r.Header.Get(publishers.HeaderSignatureTimestamp), body: body}
+	}))
+	defer receiver.Close()
+
+	rp.Path = "/webhooks"
+	rp.Method = http.MethodPost
+	rp.Payload = map[string]interface{}{
+		"webhook": map[string]interface{}{
+			"url":        receiver.URL,
+			"eventTypes": []string{"CourseCreated"},
+		},
+	}
+	if err = rp.Do(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if rp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %v, got %v: %v", http.StatusCreated, rp.StatusCode, rp.ResponseBody)
+	}
+	webhook := rp.ResponseBody.(map[string]interface{})
+	webhookID, secret := webhook["id"].(string), webhook["secret"].(string)
+	defer func() {
+		rp.Path = "/webhooks/" + webhookID
+		rp.Method = http.MethodDelete
+		rp.Payload = map[string]interface{}{}
+		if err := rp.Do(); err != nil || rp.StatusCode != http.StatusNoContent {
+			t.Errorf("unable to delete the webhook: %v, %v", err, rp.StatusCode)
+		}
+	}()
+
+	rp.Path = "/tutors"
+	rp.Payload = map[string]interface{}{
+		"tutor": map[string]string{"name": "Jane", "lastname": "Hook"},
+	}
+	if err = rp.Do(); err != nil || rp.StatusCode != http.StatusCreated {
+		t.Fatalf("unable to create the tutor: %v, %v", err, rp.ResponseBody)
+	}
+	tutorUUID := rp.ResponseBody.(map[string]interface{})["uuid"].(string)
+	rp.Path = "/createCourse"
+	rp.Payload = map[string]interface{}{
+		"course": map[string]interface{}{"name": "Webhooks with Go", "tutorUUID": tutorUUID},
+	}
+	if err = rp.Do(); err != nil || rp.StatusCode != http.StatusCreated {
+		t.Fatalf("unable to create the course: %v, %v", err, rp.ResponseBody)
+	}
+	courseUUID := rp.ResponseBody.(map[string]interface{})["uuid"].(string)
+	defer func() {
+		for _, path := range []string{"/deleteCourse/" + courseUUID, "/tutors/" + tutorUUID} {
+			rp.Path = path
+			rp.Method = http.MethodDelete
+			rp.Header = map[string]string{"If-Match": "*"}
+			rp.Payload = map[string]interface{}{}
+			_ = rp.Do()
+		}
+	}()
+
+	// Courses created by the other tests are delivered as well.
+	for received := false; !received; {
+		select {
+		case got := <-deliveries:
+			if got.eventType != "CourseCreated" || publishers.Verify(secret, got.signature, got.timestamp, got.body, time.Now()) != nil {
+				t.Errorf("got the %v event signed with %v, want a CourseCreated event signed with the secret",
+					got.eventType, got.signature)
+			}
+			received = strings.Contains(string(got.body), courseUUID)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("the webhook has not received the event")
+		}
+	}
+
+	rp.Path = "/webhooks/" + webhookID + "/deliveries"
+	rp.Method = http.MethodGet
+	rp.Payload = map[string]interface{}{}
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(100 * time.Millisecond) {
+		if err = rp.Do(); err != nil || rp.StatusCode != http.StatusOK {
+			t.Fatalf("unable to list the deliveries: %v, %v", err, rp.ResponseBody)
+		}
+		for _, item := range rp.ResponseBody.([]interface{}) {
+			delivery := item.(map[string]interface{})
+			event := delivery["event"].(map[string]interface{})
+			if event["courseUUID"] == courseUUID && delivery["status"] == "succeeded" {
+				return
+			}
+		}
+	}
+	t.Errorf("expected a succeeded delivery of the course, got %v", rp.ResponseBody)
+}
@@ -0,0 +1,253 @@
+// Package webhooks provides implementations of services.WebhookStore persisting the webhooks and their deliveries.
+package webhooks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+)
+
+// MaxDeliveries is the number of finished deliveries a MemoryStore keeps per webhook.
+// Older ones are discarded, whereas pending ones are always kept.
+const MaxDeliveries = 100
+
+// MemoryStore is a services.WebhookStore keeping the webhooks and their deliveries in memory,
+// e.g. for development and testing. It is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	webhooks []models.Webhook
+	// deliveries are the deliveries of every webhook, in the order they have been stored.
+	deliveries []models.WebhookDelivery
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// CreateWebhook stores the given webhook. It returns a *models.AlreadyExistsError if a webhook with the same ID exists.
+func (s *MemoryStore) CreateWebhook(ctx context.Context, webhook models.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.webhookIndex(webhook.ID) >= 0 {
+		return models.NewAlreadyExistsErr(models.ResourceWebhook, webhook.ID)
+	}
+	s.webhooks = append(s.webhooks, copyWebhook(webhook))
+	return nil
+}
+
+// WebhookByID returns the webhook with the given ID, or a *models.NotFoundError if it does not exist.
+func (s *MemoryStore) WebhookByID(ctx context.Context, webhookID uuid.UUID) (*models.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.webhookIndex(webhookID)
+	if i < 0 {
+		return nil, models.NewWebhookNotFoundErr(webhookID)
+	}
+	webhook := copyWebhook(s.webhooks[i])
+	return &webhook, nil
+}
+
+// ListWebhooks returns the webhooks in the order they have been created.
+func (s *MemoryStore) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	webhooks := make([]models.Webhook, len(s.webhooks))
+	for i, webhook := range s.webhooks {
+		webhooks[i] = copyWebhook(webhook)
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook stores the given webhook. It returns a *models.NotFoundError if the webhook does not exist.
+func (s *MemoryStore) UpdateWebhook(ctx context.Context, webhook models.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.webhookIndex(webhook.ID)
+	if i < 0 {
+		return models.NewWebhookNotFoundErr(webhook.ID)
+	}
+	s.webhooks[i] = copyWebhook(webhook)
+	return nil
+}
+
+// DeleteWebhook deletes the webhook with the given ID and its deliveries. It is a no-op if the webhook does not exist.
+func (s *MemoryStore) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.webhookIndex(webhookID)
+	if i < 0 {
+		return nil
+	}
+	s.webhooks = append(s.webhooks[:i:i], s.webhooks[i+1:]...)
+	s.deliveries = s.filterDeliveries(func(delivery models.WebhookDelivery) bool {
+		return delivery.WebhookID != webhookID
+	})
+	return nil
+}
+
+// AddDeliveries stores the given deliveries, ignoring those of an event which is already stored for the same webhook.
+func (s *MemoryStore) AddDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range deliveries {
+		if s.hasDelivery(delivery.WebhookID, delivery.Event.ID) {
+			continue
+		}
+		s.deliveries = append(s.deliveries, copyDelivery(delivery))
+		s.prune(delivery.WebhookID)
+	}
+	return nil
+}
+
+// UpdateDelivery stores the given delivery. It is a no-op if the delivery does not exist.
+func (s *MemoryStore) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == delivery.ID {
+			s.deliveries[i] = copyDelivery(delivery)
+			s.prune(delivery.WebhookID)
+			return nil
+		}
+	}
+	return nil
+}
+
+// DueDeliveries returns up to limit pending deliveries whose NextAttemptAt is not after the given time, earliest first.
+func (s *MemoryStore) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var due []models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, copyDelivery(delivery))
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Deliveries returns the latest deliveries of the given webhook in the order they have been stored.
+func (s *MemoryStore) Deliveries(ctx context.Context, webhookID uuid.UUID) ([]models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, copyDelivery(delivery))
+		}
+	}
+	return deliveries, nil
+}
+
+// webhookIndex returns the index of the webhook with the given ID, or -1. It must be called with s.mu held.
+func (s *MemoryStore) webhookIndex(webhookID uuid.UUID) int {
+	for i, webhook := range s.webhooks {
+		if webhook.ID == webhookID {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasDelivery reports whether a delivery of the given event to the given webhook is stored.
+// It must be called with s.mu held.
+func (s *MemoryStore) hasDelivery(webhookID, eventID uuid.UUID) bool {
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID && delivery.Event.ID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+// prune discards the oldest finished deliveries of the given webhook beyond MaxDeliveries.
+// It must be called with s.mu held.
+func (s *MemoryStore) prune(webhookID uuid.UUID) {
+	finished := 0
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID && delivery.Status != models.DeliveryPending {
+			finished++
+		}
+	}
+	if finished <= MaxDeliveries {
+		return
+	}
+	s.deliveries = s.filterDeliveries(func(delivery models.WebhookDelivery) bool {
+		if delivery.WebhookID != webhookID || delivery.Status == models.DeliveryPending || finished <= MaxDeliveries {
+			return true
+		}
+		finished--
+		return false
+	})
+}
+
+// filterDeliveries returns the stored deliveries for which keep returns true, in order.
+// It must be called with s.mu held.
+func (s *MemoryStore) filterDeliveries(keep func(delivery models.WebhookDelivery) bool) []models.WebhookDelivery {
+	deliveries := make([]models.WebhookDelivery, 0, len(s.deliveries))
+	for _, delivery := range s.deliveries {
+		if keep(delivery) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// copyWebhook returns a copy of the given webhook which does not share memory with it.
+func copyWebhook(webhook models.Webhook) models.Webhook {
+	webhook.EventTypes = append([]models.EventType(nil), webhook.EventTypes...)
+	if webhook.DisabledAt != nil {
+		disabledAt := *webhook.DisabledAt
+		webhook.DisabledAt = &disabledAt
+	}
+	return webhook
+}
+
+// copyDelivery returns a copy of the given delivery which does not share memory with it.
+// The course of its event is shared, as events are never modified.
+func copyDelivery(delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Attempts = append([]models.DeliveryAttempt{}, delivery.Attempts...)
+	if delivery.NextAttemptAt != nil {
+		nextAttemptAt := *delivery.NextAttemptAt
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+	return delivery
+}
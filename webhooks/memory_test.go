@@ -0,0 +1,17 @@
+package webhooks_test
+
+import (
+	"testing"
+
+	"github.com/tomasdembelli/course-manager/services"
+	"github.com/tomasdembelli/course-manager/webhooks"
+	"github.com/tomasdembelli/course-manager/webhooks/webhookstest"
+)
+
+var _ services.WebhookStore = (*webhooks.MemoryStore)(nil)
+
+func TestMemoryStore_conformance(t *testing.T) {
+	webhookstest.Run(t, func() services.WebhookStore {
+		return webhooks.NewMemoryStore()
+	})
+}
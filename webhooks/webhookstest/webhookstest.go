@@ -0,0 +1,209 @@
+// Package webhookstest provides a conformance test suite for the implementations of services.WebhookStore.
+// Every implementation should pass it, e.g.
+//
+//	func TestMemoryStore_conformance(t *testing.T) {
+//		webhookstest.Run(t, func() services.WebhookStore { return webhooks.NewMemoryStore() })
+//	}
+package webhookstest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tomasdembelli/course-manager/models"
+	"github.com/tomasdembelli/course-manager/services"
+	"github.com/tomasdembelli/course-manager/webhooks"
+)
+
+// Run checks that the WebhookStores returned by newStore honour the contract of services.WebhookStore,
+// and keep webhooks.MaxDeliveries finished deliveries per webhook.
+// newStore is called once per subtest, and must return an empty WebhookStore.
+func Run(t *testing.T, newStore func() services.WebhookStore) {
+	t.Run("webhooks", func(t *testing.T) {
+		testWebhooks(t, newStore())
+	})
+	t.Run("deliveries", func(t *testing.T) {
+		testDeliveries(t, newStore())
+	})
+	t.Run("prune", func(t *testing.T) {
+		testPrune(t, newStore())
+	})
+	t.Run("context cancellation", func(t *testing.T) {
+		testContextCancellation(t, newStore())
+	})
+}
+
+func testWebhooks(t *testing.T, store services.WebhookStore) {
+	ctx := context.TODO()
+	first, second := newWebhook(), newWebhook()
+	second.Owner = ""
+	for _, webhook := range []models.Webhook{first, second} {
+		if err := store.CreateWebhook(ctx, webhook); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	if err := store.CreateWebhook(ctx, first); err == nil {
+		t.Errorf("expected error creating a webhook twice, but none raised")
+	}
+
+	got, err := store.WebhookByID(ctx, first.ID)
+	if err != nil || !reflect.DeepEqual(*got, first) {
+		t.Errorf("WebhookByID() got = %v, %v, want %v", got, err, first)
+	}
+	got.EventTypes[0] = models.CourseDeletedEvent
+	if got, _ = store.WebhookByID(ctx, first.ID); !reflect.DeepEqual(*got, first) {
+		t.Errorf("the stored webhook has been modified through a returned one: %v", got)
+	}
+
+	disabledAt := first.CreatedAt.Add(time.Hour)
+	first.Enabled, first.Failures, first.DisabledAt = false, 3, &disabledAt
+	if err = store.UpdateWebhook(ctx, first); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	webhooks, err := store.ListWebhooks(ctx)
+	if err != nil || !reflect.DeepEqual(webhooks, []models.Webhook{first, second}) {
+		t.Errorf("ListWebhooks() got = %v, %v, want the webhooks in order of creation", webhooks, err)
+	}
+
+	if err = store.DeleteWebhook(ctx, first.ID); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err = store.DeleteWebhook(ctx, first.ID); err != nil {
+		t.Errorf("DeleteWebhook() of a deleted webhook error = %v, want nil", err)
+	}
+	wantErr := models.NewWebhookNotFoundErr(first.ID)
+	if _, err = store.WebhookByID(ctx, first.ID); !errors.Is(err, wantErr) {
+		t.Errorf("WebhookByID() of a deleted webhook error = %v, want %v", err, wantErr)
+	}
+	if err = store.UpdateWebhook(ctx, first); !errors.Is(err, wantErr) {
+		t.Errorf("UpdateWebhook() of a deleted webhook error = %v, want %v", err, wantErr)
+	}
+}
+
+func testDeliveries(t *testing.T, store services.WebhookStore) {
+	ctx := context.TODO()
+	webhook, other := newWebhook(), newWebhook()
+	for _, w := range []models.Webhook{webhook, other} {
+		if err := store.CreateWebhook(ctx, w); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	late := newDelivery(webhook.ID, now.Add(time.Minute))
+	early := newDelivery(webhook.ID, now.Add(-time.Minute))
+	current := newDelivery(other.ID, now)
+	duplicate := newDelivery(webhook.ID, now)
+	duplicate.Event = early.Event
+	if err := store.AddDeliveries(ctx, late, early, current, duplicate); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	due, err := store.DueDeliveries(ctx, now, 10)
+	if err != nil || !reflect.DeepEqual(due, []models.WebhookDelivery{early, current}) {
+		t.Errorf("DueDeliveries() got = %v, %v, want the due deliveries, earliest first", due, err)
+	}
+	if due, _ = store.DueDeliveries(ctx, now, 1); len(due) != 1 || due[0].ID != early.ID {
+		t.Errorf("DueDeliveries() got = %v, want the earliest delivery only", due)
+	}
+
+	early.Status, early.NextAttemptAt = models.DeliverySucceeded, nil
+	early.Attempts = append(early.Attempts, models.DeliveryAttempt{Time: now})
+	if err = store.UpdateDelivery(ctx, early); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err = store.UpdateDelivery(ctx, newDelivery(webhook.ID, now)); err != nil {
+		t.Errorf("UpdateDelivery() of an unknown delivery error = %v, want nil", err)
+	}
+	deliveries, err := store.Deliveries(ctx, webhook.ID)
+	if err != nil || !reflect.DeepEqual(deliveries, []models.WebhookDelivery{late, early}) {
+		t.Errorf("Deliveries() got = %v, %v, want the deliveries of the webhook in order", deliveries, err)
+	}
+
+	if err = store.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if deliveries, _ = store.Deliveries(ctx, webhook.ID); len(deliveries) != 0 {
+		t.Errorf("Deliveries() of a deleted webhook got = %v, want none", deliveries)
+	}
+	if due, _ = store.DueDeliveries(ctx, now, 10); !reflect.DeepEqual(due, []models.WebhookDelivery{current}) {
+		t.Errorf("DueDeliveries() got = %v, want the deliveries of the other webhook only", due)
+	}
+}
+
+func testPrune(t *testing.T, store services.WebhookStore) {
+	ctx := context.TODO()
+	webhook := newWebhook()
+	if err := store.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	now := time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)
+	pending := newDelivery(webhook.ID, now)
+	if err := store.AddDeliveries(ctx, pending); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var finished []models.WebhookDelivery
+	for i := 0; i < webhooks.MaxDeliveries+2; i++ {
+		delivery := newDelivery(webhook.ID, now)
+		delivery.Status, delivery.NextAttemptAt = models.DeliveryFailed, nil
+		finished = append(finished, delivery)
+		if err := store.AddDeliveries(ctx, delivery); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	deliveries, err := store.Deliveries(ctx, webhook.ID)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := append([]models.WebhookDelivery{pending}, finished[2:]...)
+	if !reflect.DeepEqual(deliveries, want) {
+		t.Errorf("Deliveries() got %d deliveries, want the pending one and the latest %d finished ones",
+			len(deliveries), webhooks.MaxDeliveries)
+	}
+}
+
+func testContextCancellation(t *testing.T, store services.WebhookStore) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	webhook := newWebhook()
+	if err := store.CreateWebhook(ctx, webhook); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateWebhook() error = %v, want %v", err, context.Canceled)
+	}
+	if err := store.AddDeliveries(ctx, newDelivery(webhook.ID, time.Now())); !errors.Is(err, context.Canceled) {
+		t.Errorf("AddDeliveries() error = %v, want %v", err, context.Canceled)
+	}
+	if webhooks, _ := store.ListWebhooks(context.TODO()); len(webhooks) != 0 {
+		t.Errorf("ListWebhooks() got = %v, want none", webhooks)
+	}
+}
+
+// newWebhook returns an enabled webhook which has not been created yet.
+func newWebhook() models.Webhook {
+	return models.Webhook{
+		WebhookMeta: models.WebhookMeta{
+			URL:        "https://example.com/hooks",
+			EventTypes: []models.EventType{models.StudentRegisteredEvent},
+			Enabled:    true,
+		},
+		ID:        uuid.New(),
+		Owner:     "alice",
+		Secret:    "s3cr3t",
+		CreatedAt: time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC),
+	}
+}
+
+// newDelivery returns a pending delivery of a new event to the given webhook, due at the given time.
+func newDelivery(webhookID uuid.UUID, at time.Time) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     webhookID,
+		Event:         models.Event{ID: uuid.New(), Type: models.StudentRegisteredEvent, CourseUUID: uuid.New()},
+		Status:        models.DeliveryPending,
+		Attempts:      []models.DeliveryAttempt{},
+		NextAttemptAt: &at,
+	}
+}